@@ -12,13 +12,12 @@ import (
 	"math"
 	"math/big"
 	"net"
-	"os"
 	"time"
 )
 
-// GenCert generates a certificate and private key of length lenPrivateKey.
-// Return certificate and key files or error.
-func GenCert(lenPrivateKey int) (certFile *os.File, privateKeyFile *os.File, err error) {
+// Generate generates a self-signed certificate for the loopback addresses and localhost
+// and its private key of length lenPrivateKey, both PEM encoded.
+func Generate(lenPrivateKey int) (certPEM []byte, privateKeyPEM []byte, err error) {
 	keyBytes := make([]byte, 8)
 	_, err = rand.Read(keyBytes)
 	if err != nil {
@@ -33,6 +32,7 @@ func GenCert(lenPrivateKey int) (certFile *os.File, privateKeyFile *os.File, err
 			Organization: []string{"Best Golang Developer)"},
 			Country:      []string{"RU"},
 		},
+		DNSNames:     []string{"localhost"},
 		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
 		NotBefore:    time.Now(),
 		NotAfter:     time.Now().AddDate(10, 0, 0),
@@ -51,39 +51,17 @@ func GenCert(lenPrivateKey int) (certFile *os.File, privateKeyFile *os.File, err
 		return nil, nil, err
 	}
 
-	var certPEM bytes.Buffer
-	pem.Encode(&certPEM, &pem.Block{
+	var certBuf bytes.Buffer
+	pem.Encode(&certBuf, &pem.Block{
 		Type:  "CERTIFICATE",
 		Bytes: certB,
 	})
 
-	var privateKeyPEM bytes.Buffer
-	pem.Encode(&privateKeyPEM, &pem.Block{
+	var privateKeyBuf bytes.Buffer
+	pem.Encode(&privateKeyBuf, &pem.Block{
 		Type:  "RSA PRIVATE KEY",
 		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
 	})
 
-	certFile, err = os.Create("cert.pem")
-	if err != nil {
-		return nil, nil, err
-	}
-	defer certFile.Close()
-
-	privateKeyFile, err = os.Create("priv_key.pem")
-	if err != nil {
-		return nil, nil, err
-	}
-	defer privateKeyFile.Close()
-
-	_, err = certFile.Write(certPEM.Bytes())
-	if err != nil {
-		return nil, nil, err
-	}
-
-	_, err = privateKeyFile.Write(privateKeyPEM.Bytes())
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return certFile, privateKeyFile, nil
+	return certBuf.Bytes(), privateKeyBuf.Bytes(), nil
 }
@@ -1,15 +1,21 @@
 package certgenerator
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestGenCert(t *testing.T) {
-	cFile, pFile, err := GenCert(1024)
-	if assert.NoError(t, err) {
-		assert.NotEmpty(t, cFile)
-		assert.NotEmpty(t, pFile)
-	}
+func TestGenerate(t *testing.T) {
+	certPEM, keyPEM, err := Generate(1024)
+	require.NoError(t, err)
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	assert.NoError(t, cert.VerifyHostname("localhost"))
+	assert.NoError(t, cert.VerifyHostname("127.0.0.1"))
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// filestoreUsage describes the arguments of the filestore subcommand.
+const filestoreUsage = "usage: shortener -f <file> [-file-compression none | gzip | zstd] filestore [verify | repair | compact]"
+
+// runFilestore runs the filestore subcommand: checks, repairs or compacts the JSON storage file.
+// verify only reads the file and fails if there are corrupt records,
+// repair moves them to the <file>.corrupt file, compact rewrites a file without corrupt records.
+// repair and compact refuse to run while the file is used by the service.
+func runFilestore(cfg config.Flags, args []string) error {
+	if cfg.FileName == "" {
+		return errors.New("storage file name is not set")
+	}
+	if len(args) != 1 {
+		return errors.New(filestoreUsage)
+	}
+
+	var check storage.FileCheck
+	var err error
+	switch args[0] {
+	case "verify":
+		check, err = storage.VerifyFile(cfg.FileName)
+	case "repair":
+		check, err = storage.RepairFile(cfg.FileName, cfg.FileCompression)
+	case "compact":
+		check, err = storage.CompactFile(cfg.FileName, cfg.FileCompression)
+	default:
+		return errors.New(filestoreUsage)
+	}
+	if errors.Is(err, storage.ErrFileLocked) {
+		return fmt.Errorf("%w, stop the service before %s", err, args[0])
+	}
+	printFileCheck(check)
+	if err != nil {
+		return err
+	}
+	if args[0] == "verify" && len(check.Corrupt) > 0 {
+		return fmt.Errorf("%d corrupt records, run repair to move them to %s.corrupt", len(check.Corrupt), cfg.FileName)
+	}
+	return nil
+}
+
+// printFileCheck prints the result of reading the storage file.
+func printFileCheck(check storage.FileCheck) {
+	fmt.Printf("format version: %d, compression: %s\n", check.Version, check.Compression)
+	fmt.Printf("records: %d, URLs: %d, corrupt: %d\n", check.Records, len(check.URLs), len(check.Corrupt))
+	for _, v := range check.Corrupt {
+		fmt.Printf("line %d: %s\n", v.Line, v.Error)
+	}
+	if check.TornTail {
+		fmt.Println("the end of the file is torn")
+	}
+}
@@ -3,25 +3,36 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/Julia-ivv/shortener-url/pkg/logger"
 
-	"github.com/Julia-ivv/shortener-url.git/cmd/certgenerator"
+	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
 	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/internal/deleter"
 	"github.com/Julia-ivv/shortener-url.git/internal/grpcserver"
+	"github.com/Julia-ivv/shortener-url.git/internal/health"
 	"github.com/Julia-ivv/shortener-url.git/internal/httpserver"
 	"github.com/Julia-ivv/shortener-url.git/internal/interceptors"
+	"github.com/Julia-ivv/shortener-url.git/internal/jobs"
+	"github.com/Julia-ivv/shortener-url.git/internal/metrics"
 	pb "github.com/Julia-ivv/shortener-url.git/internal/proto"
+	"github.com/Julia-ivv/shortener-url.git/internal/purger"
+	"github.com/Julia-ivv/shortener-url.git/internal/shutdown"
 	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+	"github.com/Julia-ivv/shortener-url.git/internal/tlsconfig"
+	"github.com/Julia-ivv/shortener-url.git/internal/tracing"
 )
 
 var (
@@ -35,64 +46,258 @@ func main() {
 	fmt.Println("Build date:", buildDate)
 	fmt.Println("Build commit:", buildCommit)
 
-	cfg := config.NewConfig()
+	// Errors of reading the configuration are written by the development logger.
+	bootLog := logger.NewLogger()
+	cfg := config.NewConfig(bootLog)
 
-	logger.ZapSugar = logger.NewLogger()
-	logger.ZapSugar.Infow("Starting http server", "addr", cfg.Host)
-	logger.ZapSugar.Infow("Starting gRPC server", "addr", cfg.GRPC)
-	logger.ZapSugar.Infow("flags",
+	log, level, err := logger.New(logger.Options{
+		Level:    cfg.LogLevel,
+		Format:   cfg.LogFormat,
+		Sampling: cfg.LogSampling,
+		File:     cfg.LogFile,
+	})
+	if err != nil {
+		bootLog.Fatal(err)
+	}
+	defer log.Sync()
+
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(*cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "migrate-data" {
+		if err := runMigrateData(*cfg, flag.Args()[1:], log); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "filestore" {
+		if err := runFilestore(*cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	log.Infow("Starting http server", "addr", cfg.Host)
+	log.Infow("Starting gRPC server", "addr", cfg.GRPC)
+	log.Infow("flags",
 		"base url", cfg.URL,
 		"filename", cfg.FileName,
 		"db dsn", cfg.DBDSN,
+		"kv filename", cfg.KVFileName,
 		"https enabled", cfg.EnableHTTPS,
+		"grpc tls enabled", cfg.GRPCEnableTLS,
+		"grpc client ca", cfg.GRPCClientCA,
 		"config file", cfg.ConfigFileName,
 	)
 
-	repo, err := storage.NewURLs(*cfg)
+	shutdownTracing, err := tracing.NewProvider(context.Background(), *cfg, buildVersion)
 	if err != nil {
-		logger.ZapSugar.Fatal(err)
+		log.Fatal(err)
+	}
+
+	repo, err := storage.NewURLs(*cfg, log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The schema and the pool are checked by the storage itself, the decorators below do not forward them.
+	schema, _ := repo.(storage.SchemaChecker)
+	statter, _ := repo.(storage.PoolStatter)
+	// Redirects are counted in memory and added to the storage with one write per flush.
+	var clicks *storage.ClickBuffer
+	if cfg.ClickFlushInterval.Duration > 0 {
+		clicks = storage.NewClickBuffer(repo)
+		repo = clicks
+	}
+	if cfg.MetricsAddr != "" {
+		if statter != nil {
+			if err = metrics.RegisterPool(statter); err != nil {
+				log.Fatal(err)
+			}
+		}
+		repo = metrics.NewInstrumentedURLs(repo, storage.BackendName(*cfg))
+	}
+	// Changes made by other instances are received only when there is a cache to invalidate.
+	var listener *storage.Listener
+	if cfg.CacheSize > 0 {
+		cache := storage.NewCachedURLs(repo, cfg.CacheSize, cfg.CacheTTL.Duration, cfg.CacheNegativeTTL.Duration)
+		if cfg.DBDSN != "" && !storage.IsSQLiteDSN(cfg.DBDSN) && cfg.DBNotifyChannel != "" {
+			listener = storage.NewListener(cfg.DBDSN, cfg.DBNotifyChannel)
+			listener.Subscribe(cache.HandleChange)
+		}
+		if cfg.MetricsAddr != "" {
+			if err = metrics.RegisterCache(cache); err != nil {
+				log.Fatal(err)
+			}
+		}
+		repo = cache
+	}
+	if cfg.TraceExporter != "" {
+		repo = tracing.NewTracedURLs(repo, storage.BackendName(*cfg))
+	}
+	checker := health.NewChecker(repo, schema)
+	var stopListen shutdown.StopFunc
+	if listener != nil {
+		stopListen = shutdown.Go(logger.NewContext(context.Background(), log), listener.Run)
+	}
+
+	var queue deleter.Queue = deleter.NewMemQueue()
+	if cfg.DeleteQueue != "" {
+		queue, err = deleter.NewFileQueue(cfg.DeleteQueue)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	tracker := jobs.NewTracker()
+	pool := deleter.NewPool(repo, tracker, queue, cfg.DeleteWorkers, log)
+	if err = pool.Start(); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.MetricsAddr != "" {
+		if err = metrics.RegisterQueue(pool.Len); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var stopClicks shutdown.StopFunc
+	if clicks != nil {
+		stopClicks = shutdown.Go(logger.NewContext(context.Background(), log), func(ctx context.Context) {
+			clicks.Run(ctx, cfg.ClickFlushInterval.Duration)
+		})
 	}
-	defer repo.Close()
 
-	httpWg := sync.WaitGroup{}
-	grpcWg := sync.WaitGroup{}
+	stopPurge := shutdown.Go(logger.NewContext(context.Background(), log), func(ctx context.Context) {
+		purger.Run(ctx, repo, cfg.PurgeAfter.Duration, cfg.PurgeInterval.Duration)
+	})
 
 	var srv = http.Server{
 		Addr:    cfg.Host,
-		Handler: httpserver.NewURLRouter(repo, *cfg, &httpWg),
+		Handler: httpserver.NewURLRouter(repo, *cfg, pool, tracker, log, level, checker),
+	}
+	grpcOpts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+	}
+	// srvACME answers the ACME HTTP-01 challenges, nil if they are not used.
+	var srvACME *http.Server
+	if cfg.EnableHTTPS || cfg.GRPCEnableTLS {
+		certs, err := tlsconfig.New(*cfg, log)
+		if err != nil {
+			log.Fatalw(err.Error(), "event", "load TLS certificate")
+		}
+		if cfg.EnableHTTPS {
+			srv.TLSConfig = certs.ServerConfig()
+		}
+		if cfg.GRPCEnableTLS {
+			grpcTLS, err := certs.GRPCConfig(*cfg)
+			if err != nil {
+				log.Fatalw(err.Error(), "event", "load gRPC client CA")
+			}
+			grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(grpcTLS)))
+		}
+		if handler := certs.HTTPHandler(); handler != nil && cfg.ACMEHTTPAddr != "" {
+			srvACME = &http.Server{Addr: cfg.ACMEHTTPAddr, Handler: handler}
+			log.Infow("Starting ACME challenge server", "addr", cfg.ACMEHTTPAddr, "domains", cfg.ACMEDomains)
+			go func() {
+				if err := srvACME.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Fatalw(err.Error(), "event", "start ACME challenge server")
+				}
+			}()
+		}
 	}
 
-	srvGRPC := grpc.NewServer(
+	identities, err := authorizer.ParseIdentities(cfg.GRPCClientIdentities)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(identities) > 0 && (!cfg.GRPCEnableTLS || cfg.GRPCClientCA == "") {
+		log.Fatal("gRPC client identities need TLS and a client CA")
+	}
+
+	srvGRPC := grpc.NewServer(append(grpcOpts,
+		grpc.ChainUnaryInterceptor(interceptors.WithLogger(log)),
+		grpc.ChainUnaryInterceptor(interceptors.HandlerWithRequestID),
+		grpc.ChainUnaryInterceptor(interceptors.HandlerWithMetrics),
+		grpc.ChainUnaryInterceptor(interceptors.HandlerWithTracing),
+		grpc.ChainUnaryInterceptor(interceptors.WithClientCert(identities)),
 		grpc.ChainUnaryInterceptor(interceptors.HandlerWithAuth),
-		grpc.ChainUnaryInterceptor(interceptors.HandlerWithLogging))
-	pb.RegisterShortUrlServer(srvGRPC, grpcserver.NewShortenerServer(repo, *cfg, &grpcWg))
+		grpc.ChainUnaryInterceptor(interceptors.HandlerWithLogging))...)
+	pb.RegisterShortUrlServer(srvGRPC, grpcserver.NewShortenerServer(repo, *cfg, pool, tracker, log))
+	checker.RegisterGRPC(srvGRPC)
+
+	var srvMetrics *http.Server
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		srvMetrics = &http.Server{Addr: cfg.MetricsAddr, Handler: mux}
+		log.Infow("Starting metrics server", "addr", cfg.MetricsAddr)
+		go func() {
+			if err := srvMetrics.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalw(err.Error(), "event", "start metrics server")
+			}
+		}()
+	}
+
+	// The parts are stopped in order: new requests go to other instances once the load balancer
+	// notices the service is not ready, the active ones are finished,
+	// then the background work that uses the storage is stopped and the counted redirects are added
+	// before the storage is closed.
+	// Unfinished deletions stay in the deletion queue until the next start.
+	stop := shutdown.NewSequence(log)
+	stop.Add("readiness", shutdown.Func(func() error {
+		checker.Drain()
+		return nil
+	}))
+	if delay := cfg.DrainDelay(); delay > 0 {
+		stop.Add("drain delay", shutdown.Delay(delay))
+	}
+	stop.Add("servers", shutdown.Parallel(shutdown.HTTP(&srv), shutdown.GRPC(srvGRPC)))
+	if srvACME != nil {
+		stop.Add("ACME challenge server", shutdown.HTTP(srvACME))
+	}
+	stop.Add("purger", stopPurge)
+	if stopListen != nil {
+		stop.Add("change listener", stopListen)
+	}
+	stop.Add("deletions", pool.Close)
+	stop.Add("deletion queue", shutdown.Func(queue.Close))
+	if clicks != nil {
+		stop.Add("clicks", func(ctx context.Context) error {
+			return errors.Join(stopClicks(ctx), clicks.Flush(ctx))
+		})
+	}
+	stop.Add("storage", shutdown.Func(repo.Close))
+	if srvMetrics != nil {
+		stop.Add("metrics server", shutdown.HTTP(srvMetrics))
+	}
+	stop.Add("tracing", shutdownTracing)
 
 	idleConnsClosed := make(chan struct{})
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 	go func() {
-		<-sigs
-		if err := srv.Shutdown(context.Background()); err != nil {
-			logger.ZapSugar.Infow("HTTP server Shutdown: %v", err)
+		sig := <-sigs
+		log.Infow("Shutting down", "signal", sig.String(), "timeout", cfg.ShutdownTimeout.Duration)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+		defer cancel()
+		if err := stop.Run(ctx); err != nil {
+			log.Errorw("graceful shutdown", "error", err)
 		}
-		srvGRPC.GracefulStop()
 		close(idleConnsClosed)
 	}()
 
 	go func() {
 		if cfg.EnableHTTPS {
-			certFile, privateKeyFile, err := certgenerator.GenCert(4096)
-			if err != nil {
-				logger.ZapSugar.Fatalw(err.Error(), "event", "create certificate or private key")
-			}
-			err = srv.ListenAndServeTLS(certFile.Name(), privateKeyFile.Name())
+			err := srv.ListenAndServeTLS("", "")
 			if err != nil && err != http.ErrServerClosed {
-				logger.ZapSugar.Fatalw(err.Error(), "event", "start server")
+				log.Fatalw(err.Error(), "event", "start server")
 			}
 		} else {
-			err = srv.ListenAndServe()
+			err := srv.ListenAndServe()
 			if err != nil && err != http.ErrServerClosed {
-				logger.ZapSugar.Fatalw(err.Error(), "event", "start server")
+				log.Fatalw(err.Error(), "event", "start server")
 			}
 		}
 	}()
@@ -100,14 +305,12 @@ func main() {
 	go func() {
 		listen, err := net.Listen("tcp", cfg.GRPC)
 		if err != nil {
-			logger.ZapSugar.Fatalw(err.Error(), "event", "listen port")
+			log.Fatalw(err.Error(), "event", "listen port")
 		}
 		if err = srvGRPC.Serve(listen); err != nil {
-			logger.ZapSugar.Fatalw(err.Error(), "event", "start gRPC server")
+			log.Fatalw(err.Error(), "event", "start gRPC server")
 		}
 	}()
 
-	grpcWg.Wait()
-	httpWg.Wait()
 	<-idleConnsClosed
 }
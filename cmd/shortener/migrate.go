@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// migrateUsage describes the arguments of the migrate subcommand.
+const migrateUsage = "usage: shortener -d <dsn | sqlite://file> migrate [up | down [N] | goto <version> | status]"
+
+// runMigrate runs the migrate subcommand: applies or reverts database schema migrations.
+func runMigrate(cfg config.Flags, args []string) error {
+	if cfg.DBDSN == "" {
+		return errors.New("database DSN is not set")
+	}
+
+	var db *sql.DB
+	var err error
+	newMigrator := storage.NewMigrator
+	if storage.IsSQLiteDSN(cfg.DBDSN) {
+		db, err = storage.OpenSQLite(cfg.DBDSN)
+		newMigrator = storage.NewSQLiteMigrator
+	} else {
+		db, err = sql.Open("pgx", cfg.DBDSN)
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	mig, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	switch {
+	case cmd == "up" && len(args) <= 1:
+		err = mig.Up(ctx)
+	case cmd == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+		err = mig.Down(ctx, steps)
+	case cmd == "goto" && len(args) == 2:
+		var version int
+		if version, err = strconv.Atoi(args[1]); err != nil || version < 0 {
+			return errors.New(migrateUsage)
+		}
+		err = mig.To(ctx, version)
+	case cmd == "status" && len(args) == 1:
+	default:
+		return errors.New(migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := mig.Version(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema version: %d, latest: %d\n", version, mig.Latest())
+	return nil
+}
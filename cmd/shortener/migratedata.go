@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+	"github.com/Julia-ivv/shortener-url.git/internal/transfer"
+)
+
+// migrateDataUsage describes the arguments of the migrate-data subcommand.
+const migrateDataUsage = "usage: shortener migrate-data --from <storage> --to <storage> [--dry-run] [--checkpoint <file>] [--batch <size>]\n" +
+	"storage: file:<path> | kv:<path> | sqlite://<path> | postgres://<dsn>"
+
+// Storage address prefixes of the migrate-data subcommand.
+const (
+	fileStoragePrefix = "file:"
+	kvStoragePrefix   = "kv:"
+)
+
+// runMigrateData runs the migrate-data subcommand: copies all URLs from one storage to another.
+// log receives the messages of the storages.
+func runMigrateData(cfg config.Flags, args []string, log *zap.SugaredLogger) error {
+	fs := flag.NewFlagSet("migrate-data", flag.ContinueOnError)
+	from := fs.String("from", "", "source storage")
+	to := fs.String("to", "", "target storage")
+	dryRun := fs.Bool("dry-run", false, "only count the URLs of the source")
+	checkpoint := fs.String("checkpoint", "", "file to save the progress and resume from")
+	batch := fs.Int("batch", 0, "number of URLs loaded at once")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 || *from == "" || *to == "" {
+		return errors.New(migrateDataUsage)
+	}
+
+	src, err := openBulkStorage(cfg, *from, log)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	defer src.Close()
+
+	var dst storage.Repositories
+	if *dryRun {
+		// The target is not opened, opening can create files or migrate the database schema.
+		_, err = storageFlags(cfg, *to)
+	} else {
+		dst, err = openBulkStorage(cfg, *to, log)
+	}
+	if err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	var target transfer.Target
+	if dst != nil {
+		defer dst.Close()
+		target = dst.(storage.BulkStorage)
+	}
+
+	p, err := transfer.Run(context.Background(), src.(storage.BulkStorage), target, transfer.Options{
+		ID:         *from + " -> " + *to,
+		BatchSize:  *batch,
+		Checkpoint: *checkpoint,
+		DryRun:     *dryRun,
+		Report: func(p transfer.Progress) {
+			fmt.Fprintf(os.Stderr, "read %d, loaded %d, last short URL %q\n", p.Read, p.Loaded, p.Last)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Printf("dry run: %d URLs to copy\n", p.Read)
+		return nil
+	}
+	fmt.Printf("copied: read %d URLs, loaded %d, skipped existing %d\n", p.Read, p.Loaded, p.Read-p.Loaded)
+	return nil
+}
+
+// storageFlags returns the settings selecting only the storage from the address.
+func storageFlags(cfg config.Flags, addr string) (config.Flags, error) {
+	cfg.FileName, cfg.KVFileName, cfg.DBDSN = "", "", ""
+	switch {
+	case strings.HasPrefix(addr, fileStoragePrefix):
+		cfg.FileName = strings.TrimPrefix(addr, fileStoragePrefix)
+	case strings.HasPrefix(addr, kvStoragePrefix):
+		cfg.KVFileName = strings.TrimPrefix(addr, kvStoragePrefix)
+	case storage.IsSQLiteDSN(addr), strings.HasPrefix(addr, "postgres://"), strings.HasPrefix(addr, "postgresql://"):
+		cfg.DBDSN = addr
+	default:
+		return cfg, fmt.Errorf("unknown storage %q\n%s", addr, migrateDataUsage)
+	}
+	if cfg.FileName == "" && cfg.KVFileName == "" && cfg.DBDSN == "" {
+		return cfg, fmt.Errorf("empty storage path %q", addr)
+	}
+	// The storage is used by the command only, it is compacted on closing.
+	cfg.FileCompactInterval = config.Duration{}
+	return cfg, nil
+}
+
+// openBulkStorage opens the storage by the address.
+func openBulkStorage(cfg config.Flags, addr string, log *zap.SugaredLogger) (storage.Repositories, error) {
+	flags, err := storageFlags(cfg, addr)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := storage.NewURLs(flags, log)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := repo.(storage.BulkStorage); !ok {
+		repo.Close()
+		return nil, fmt.Errorf("storage %q cannot copy URLs", addr)
+	}
+	return repo, nil
+}
@@ -0,0 +1,84 @@
+package authorizer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AdminContextKey - name of the key marking the caller as an admin in the context.
+const AdminContextKey key = "admin"
+
+// RoleAdmin - the role of the callers allowed to use the internal methods.
+const RoleAdmin = "admin"
+
+// Identity - the user or the role a client certificate is mapped to.
+type Identity struct {
+	// UserID - the user the calls are made for, 0 - the caller has no user.
+	UserID int
+	// Admin - the caller can use the internal methods, e.g. the statistics.
+	Admin bool
+}
+
+// Identities maps the names of client certificates to identities.
+type Identities map[string]Identity
+
+// ParseIdentities parses the comma-separated list of name=user:<ID> and name=admin pairs,
+// e.g. "billing.internal=user:42,ops@example.com=admin". The same name may be listed twice to get both.
+func ParseIdentities(list string) (Identities, error) {
+	ids := make(Identities)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("client identity %q: want name=user:<ID> or name=admin", pair)
+		}
+
+		id := ids[name]
+		switch {
+		case value == RoleAdmin:
+			id.Admin = true
+		case strings.HasPrefix(value, "user:"):
+			userID, err := strconv.Atoi(strings.TrimPrefix(value, "user:"))
+			if err != nil || userID <= 0 {
+				return nil, fmt.Errorf("client identity %q: bad user ID", pair)
+			}
+			id.UserID = userID
+		default:
+			return nil, fmt.Errorf("client identity %q: want name=user:<ID> or name=admin", pair)
+		}
+		ids[name] = id
+	}
+	return ids, nil
+}
+
+// Lookup returns the identity of the certificate and the name it was found by.
+// The URI, DNS and email names of the certificate are checked before its common name.
+func (ids Identities) Lookup(cert *x509.Certificate) (id Identity, name string, ok bool) {
+	names := make([]string, 0, len(cert.URIs)+len(cert.DNSNames)+len(cert.EmailAddresses)+1)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	names = append(names, cert.Subject.CommonName)
+
+	for _, name := range names {
+		if id, ok := ids[name]; ok && name != "" {
+			return id, name, true
+		}
+	}
+	return Identity{}, "", false
+}
+
+// IsAdmin reports whether the caller of the context is an admin.
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(AdminContextKey).(bool)
+	return admin
+}
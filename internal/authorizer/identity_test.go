@@ -0,0 +1,100 @@
+package authorizer
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIdentities(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    Identities
+		wantErr bool
+	}{
+		{name: "empty", list: "", want: Identities{}},
+		{
+			name: "users and admins",
+			list: "billing.internal=user:42, ops@example.com=admin,billing.internal=admin",
+			want: Identities{
+				"billing.internal": {UserID: 42, Admin: true},
+				"ops@example.com":  {Admin: true},
+			},
+		},
+		{name: "without role", list: "billing.internal", wantErr: true},
+		{name: "bad user ID", list: "billing.internal=user:abc", wantErr: true},
+		{name: "unknown role", list: "billing.internal=root", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseIdentities(test.list)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestIdentitiesLookup(t *testing.T) {
+	ids := Identities{
+		"spiffe://shortener/billing": {UserID: 42},
+		"ops.example.com":            {Admin: true},
+		"legacy-client":              {UserID: 7},
+	}
+	spiffe, err := url.Parse("spiffe://shortener/billing")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		want     Identity
+		wantName string
+		wantOK   bool
+	}{
+		{
+			name:     "uri before common name",
+			cert:     &x509.Certificate{URIs: []*url.URL{spiffe}, Subject: pkix.Name{CommonName: "legacy-client"}},
+			want:     Identity{UserID: 42},
+			wantName: "spiffe://shortener/billing",
+			wantOK:   true,
+		},
+		{
+			name:     "dns name",
+			cert:     &x509.Certificate{DNSNames: []string{"other.example.com", "ops.example.com"}},
+			want:     Identity{Admin: true},
+			wantName: "ops.example.com",
+			wantOK:   true,
+		},
+		{
+			name:     "common name",
+			cert:     &x509.Certificate{Subject: pkix.Name{CommonName: "legacy-client"}},
+			want:     Identity{UserID: 7},
+			wantName: "legacy-client",
+			wantOK:   true,
+		},
+		{name: "unknown", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "stranger"}}, wantOK: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, name, ok := ids.Lookup(test.cert)
+			assert.Equal(t, test.wantOK, ok)
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantName, name)
+		})
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	ctx := context.Background()
+	assert.False(t, IsAdmin(ctx))
+	assert.True(t, IsAdmin(context.WithValue(ctx, AdminContextKey, true)))
+}
@@ -1,15 +1,23 @@
 // Package config receives settings when the application starts.
+//
+// Each setting is taken from the first source that has it:
+//  1. an environment variable;
+//  2. a flag set in the command line;
+//  3. the configuration file set by the -c flag or the CONFIG variable;
+//  4. the default value.
+//
+// A setting present in the configuration file replaces the default even if it is zero, empty or false,
+// so the file can turn off a setting that is on by default.
 package config
 
 import (
-	"bufio"
 	"encoding/json"
 	"flag"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env"
-
-	"github.com/Julia-ivv/shortener-url/pkg/logger"
+	"go.uber.org/zap"
 )
 
 // Flags stores application launch settings.
@@ -20,16 +28,138 @@ type Flags struct {
 	URL string `env:"BASE_URL" json:"base_url"`
 	// FileName (flag -f) - full name of the JSON file to save data.
 	FileName string `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
-	// DBDSN (flag -d) - database connection address.
+	// FileCompactInterval (flag -file-compact-interval) - how often the JSON file log is compacted,
+	// 0 - only on start and shutdown.
+	FileCompactInterval Duration `env:"FILE_COMPACT_INTERVAL" json:"file_compact_interval"`
+	// FileCompression (flag -file-compression) - compression of the JSON file: none, gzip or zstd.
+	FileCompression string `env:"FILE_COMPRESSION" json:"file_compression"`
+	// DBDSN (flag -d) - database connection address, sqlite://<file> selects the SQLite database.
 	DBDSN string `env:"DATABASE_DSN" json:"database_dsn"`
+	// KVFileName (flag -kv) - full name of the embedded key-value database file,
+	// used instead of the JSON file if set.
+	KVFileName string `env:"KV_STORAGE_PATH" json:"kv_storage_path"`
 	// ConfigFileName (flag -c/-config) - the name of configuration file
 	ConfigFileName string `env:"CONFIG"`
 	// EnableHTTPS (flag -s) - if true, https enabled.
 	EnableHTTPS bool `env:"ENABLE_HTTPS" json:"enable_https"`
+	// TLSCertFile (flag -tls-cert) - full name of the PEM certificate file, the chain goes after the leaf,
+	// empty - a self-signed certificate is generated in DataDir. The file is reloaded when it changes.
+	TLSCertFile string `env:"TLS_CERT_FILE" json:"tls_cert_file"`
+	// TLSKeyFile (flag -tls-key) - full name of the PEM private key file of TLSCertFile.
+	TLSKeyFile string `env:"TLS_KEY_FILE" json:"tls_key_file"`
+	// TLSMinVersion (flag -tls-min-version) - the minimum TLS version: 1.2 or 1.3.
+	TLSMinVersion string `env:"TLS_MIN_VERSION" json:"tls_min_version"`
+	// TLSCiphers (flag -tls-ciphers) - comma-separated names of the TLS 1.2 cipher suites,
+	// e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, empty - the Go defaults. TLS 1.3 suites are not configurable.
+	TLSCiphers string `env:"TLS_CIPHERS" json:"tls_ciphers"`
+	// ACMEDomains (flag -acme-domains) - comma-separated domains the certificates are obtained for via ACME,
+	// empty - ACME is disabled. Other names, e.g. localhost, get the certificate from TLSCertFile or a self-signed one.
+	ACMEDomains string `env:"ACME_DOMAINS" json:"acme_domains"`
+	// ACMEDirectory (flag -acme-directory) - the directory URL of the ACME server, e.g. of a local Pebble.
+	ACMEDirectory string `env:"ACME_DIRECTORY" json:"acme_directory"`
+	// ACMEEmail (flag -acme-email) - the contact email of the ACME account, may be empty.
+	ACMEEmail string `env:"ACME_EMAIL" json:"acme_email"`
+	// ACMECAFile (flag -acme-ca) - full name of the PEM file with the root certificate of the ACME server,
+	// empty - the system roots are trusted.
+	ACMECAFile string `env:"ACME_CA_FILE" json:"acme_ca_file"`
+	// ACMEHTTPAddr (flag -acme-http-addr) - the address of the server answering HTTP-01 challenges and
+	// redirecting other requests to HTTPS, e.g. :80, empty - only TLS-ALPN-01 challenges are used.
+	ACMEHTTPAddr string `env:"ACME_HTTP_ADDRESS" json:"acme_http_address"`
+	// DataDir (flag -data-dir) - the directory the service keeps its state in,
+	// e.g. the generated certificate and the ACME certificate cache.
+	DataDir string `env:"DATA_DIR" json:"data_dir"`
 	// TrustedSubnet (flag -t) - CIDR.
 	TrustedSubnet string `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
 	// GRPC (flag -g) - port for gRPC, e.g. :3200.
 	GRPC string `env:"GRPC_PORT" json:"grpc"`
+	// GRPCEnableTLS (flag -grpc-tls) - if true, the gRPC server uses TLS with the certificate of the HTTPS server.
+	GRPCEnableTLS bool `env:"GRPC_ENABLE_TLS" json:"grpc_enable_tls"`
+	// GRPCClientCA (flag -grpc-client-ca) - full name of the PEM bundle of the CAs the gRPC client certificates
+	// are verified with, empty - client certificates are not requested.
+	GRPCClientCA string `env:"GRPC_CLIENT_CA_FILE" json:"grpc_client_ca_file"`
+	// GRPCClientCertRequired (flag -grpc-client-cert-required) - if true, gRPC clients without
+	// a valid certificate are rejected, otherwise they are authenticated by the token.
+	GRPCClientCertRequired bool `env:"GRPC_CLIENT_CERT_REQUIRED" json:"grpc_client_cert_required"`
+	// GRPCClientIdentities (flag -grpc-client-identities) - comma-separated name=user:<ID> and name=admin pairs
+	// mapping the names of client certificates (URI, DNS, email or common name) to users and admins.
+	GRPCClientIdentities string `env:"GRPC_CLIENT_IDENTITIES" json:"grpc_client_identities"`
+	// DeleteWorkers (flag -delete-workers) - the number of workers deleting URLs.
+	DeleteWorkers int `env:"DELETE_WORKERS" json:"delete_workers"`
+	// DeleteQueue (flag -delete-queue) - full name of the file to save the deletion queue,
+	// if empty (the default), the queue is kept in memory and unfinished deletions are lost on restart.
+	// Each instance needs its own file.
+	DeleteQueue string `env:"DELETE_QUEUE_PATH" json:"delete_queue_path"`
+	// RestorePeriod (flag -restore-period) - how long a deleted URL can be restored, e.g. 72h.
+	RestorePeriod Duration `env:"RESTORE_PERIOD" json:"restore_period"`
+	// PurgeAfter (flag -purge-after) - how long deleted URLs are kept before permanent removal,
+	// 0 disables the purge.
+	PurgeAfter Duration `env:"PURGE_AFTER" json:"purge_after"`
+	// PurgeInterval (flag -purge-interval) - how often the purge runs.
+	PurgeInterval Duration `env:"PURGE_INTERVAL" json:"purge_interval"`
+	// ClickFlushInterval (flag -click-flush-interval) - how often the redirects counted in memory
+	// are added to the storage, 0 (default) - every redirect is written to the storage.
+	// Counts that are not flushed yet are lost if the instance stops abnormally.
+	ClickFlushInterval Duration `env:"CLICK_FLUSH_INTERVAL" json:"click_flush_interval"`
+	// DBMaxConns (flag -db-max-conns) - the maximum size of the database connection pool,
+	// 0 - the pgx default.
+	DBMaxConns int `env:"DB_MAX_CONNS" json:"db_max_conns"`
+	// DBMinConns (flag -db-min-conns) - the minimum number of open database connections.
+	DBMinConns int `env:"DB_MIN_CONNS" json:"db_min_conns"`
+	// DBMaxConnLifetime (flag -db-max-conn-lifetime) - how long a connection is used before it is closed.
+	DBMaxConnLifetime Duration `env:"DB_MAX_CONN_LIFETIME" json:"db_max_conn_lifetime"`
+	// DBMaxConnIdleTime (flag -db-max-conn-idle-time) - how long an idle connection is kept open.
+	DBMaxConnIdleTime Duration `env:"DB_MAX_CONN_IDLE_TIME" json:"db_max_conn_idle_time"`
+	// DBHealthCheckPeriod (flag -db-health-check-period) - how often idle connections are checked.
+	DBHealthCheckPeriod Duration `env:"DB_HEALTH_CHECK_PERIOD" json:"db_health_check_period"`
+	// DBStatementCache (flag -db-statement-cache) - the number of prepared statements cached per connection.
+	DBStatementCache int `env:"DB_STATEMENT_CACHE" json:"db_statement_cache"`
+	// DBNotifyChannel (flag -db-notify-channel) - the PostgreSQL channel of URL changes
+	// shared by all instances, empty (default) - changes are not published.
+	// Set it on all instances that cache short URLs.
+	DBNotifyChannel string `env:"DB_NOTIFY_CHANNEL" json:"db_notify_channel"`
+	// ReadTimeout (flag -storage-read-timeout) - the time limit of reading from the storage, 0 - no limit.
+	ReadTimeout Duration `env:"STORAGE_READ_TIMEOUT" json:"storage_read_timeout"`
+	// WriteTimeout (flag -storage-write-timeout) - the time limit of adding and updating URLs, 0 - no limit.
+	WriteTimeout Duration `env:"STORAGE_WRITE_TIMEOUT" json:"storage_write_timeout"`
+	// DeleteTimeout (flag -storage-delete-timeout) - the time limit of deleting and purging URLs, 0 - no limit.
+	DeleteTimeout Duration `env:"STORAGE_DELETE_TIMEOUT" json:"storage_delete_timeout"`
+	// CacheSize (flag -cache-size) - the number of short URLs cached in memory for redirects, 0 - no cache.
+	CacheSize int `env:"CACHE_SIZE" json:"cache_size"`
+	// CacheTTL (flag -cache-ttl) - how long a found short URL is cached.
+	CacheTTL Duration `env:"CACHE_TTL" json:"cache_ttl"`
+	// CacheNegativeTTL (flag -cache-negative-ttl) - how long an unknown short URL is cached, 0 - not cached.
+	CacheNegativeTTL Duration `env:"CACHE_NEGATIVE_TTL" json:"cache_negative_ttl"`
+	// MetricsAddr (flag -metrics-addr) - the address of the Prometheus metrics server, e.g. :9100,
+	// empty - metrics are not served.
+	MetricsAddr string `env:"METRICS_ADDRESS" json:"metrics_address"`
+	// TraceExporter (flag -trace-exporter) - where spans are sent: otlp, stdout or file,
+	// empty - tracing is disabled.
+	TraceExporter string `env:"TRACE_EXPORTER" json:"trace_exporter"`
+	// TraceEndpoint (flag -trace-endpoint) - the OTLP gRPC collector address, e.g. localhost:4317.
+	TraceEndpoint string `env:"TRACE_ENDPOINT" json:"trace_endpoint"`
+	// TraceInsecure (flag -trace-insecure) - if true, spans are sent to the collector without TLS.
+	TraceInsecure bool `env:"TRACE_INSECURE" json:"trace_insecure"`
+	// TraceFile (flag -trace-file) - full name of the file the file exporter writes spans to as JSON.
+	TraceFile string `env:"TRACE_FILE" json:"trace_file"`
+	// TraceSampleRatio (flag -trace-sample-ratio) - the share of traces started by the service that are sampled,
+	// from 0 to 1, traces started by the caller follow the caller's decision.
+	TraceSampleRatio float64 `env:"TRACE_SAMPLE_RATIO" json:"trace_sample_ratio"`
+	// LogLevel (flag -log-level) - the minimum level of log records: debug, info, warn or error.
+	LogLevel string `env:"LOG_LEVEL" json:"log_level"`
+	// LogFormat (flag -log-format) - the encoding of log records: json or console.
+	LogFormat string `env:"LOG_FORMAT" json:"log_format"`
+	// LogSampling (flag -log-sampling) - the number of identical log records written per second,
+	// after that only every LogSampling-th of them is written, 0 - no sampling.
+	LogSampling int `env:"LOG_SAMPLING" json:"log_sampling"`
+	// LogFile (flag -log-file) - full name of the file log records are appended to, empty - stderr.
+	LogFile string `env:"LOG_FILE" json:"log_file"`
+	// ShutdownTimeout (flag -shutdown-timeout) - how long the graceful shutdown waits for requests
+	// and pending deletions, after that the servers and the deletions are stopped.
+	ShutdownTimeout Duration `env:"SHUTDOWN_TIMEOUT" json:"shutdown_timeout"`
+	// ShutdownDrainDelay (flag -shutdown-drain-delay) - how long the servers keep accepting requests
+	// after the service is marked as not ready, so the load balancer has time to stop sending them,
+	// it takes at most half of ShutdownTimeout, 0 - no delay.
+	ShutdownDrainDelay Duration `env:"SHUTDOWN_DRAIN_DELAY" json:"shutdown_drain_delay"`
 }
 
 // Default values for flags.
@@ -39,74 +169,159 @@ const (
 	defFileName string = "/tmp/short-url-db.json"
 	defHTTPS    bool   = false
 	defGRPC     string = ":3200"
+
+	defTLSMinVersion string = "1.2"
+	defDataDir       string = "/tmp/short-url-data"
+
+	defACMEDirectory string = "https://acme-v02.api.letsencrypt.org/directory"
+	defACMEHTTPAddr  string = ":80"
+
+	defDeleteWorkers int = 5
+
+	defRestorePeriod time.Duration = 72 * time.Hour
+	defPurgeAfter    time.Duration = 30 * 24 * time.Hour
+	defPurgeInterval time.Duration = time.Hour
+
+	defFileCompactInterval time.Duration = 10 * time.Minute
+
+	defReadTimeout   time.Duration = 3 * time.Second
+	defWriteTimeout  time.Duration = 3 * time.Second
+	defDeleteTimeout time.Duration = 30 * time.Second
+
+	defCacheSize        int           = 10000
+	defCacheTTL         time.Duration = time.Minute
+	defCacheNegativeTTL time.Duration = 5 * time.Second
+
+	defTraceEndpoint    string  = "localhost:4317"
+	defTraceFile        string  = "/tmp/short-url-traces.json"
+	defTraceSampleRatio float64 = 1
+
+	defLogLevel  string = "info"
+	defLogFormat string = "console"
+
+	defShutdownTimeout    time.Duration = 30 * time.Second
+	defShutdownDrainDelay time.Duration = 5 * time.Second
 )
 
-// readFromConf reads flag values from configuration file.
-func readFromConf(c *Flags) error {
-	f, err := os.Open(c.ConfigFileName)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+// DrainDelay returns the drain delay limited to half of the shutdown timeout,
+// the other half is left to finish the requests and stop the background work.
+func (c Flags) DrainDelay() time.Duration {
+	return min(c.ShutdownDrainDelay.Duration, c.ShutdownTimeout.Duration/2)
+}
 
-	scan := bufio.NewScanner(f)
-	allData := []byte{}
-	for scan.Scan() {
-		allData = append(allData, scan.Bytes()...)
-	}
-	if err = scan.Err(); err != nil {
+// readFromConf reads the settings from the configuration file.
+// The settings in the file replace the defaults, including zero and false values,
+// while the flags set in the command line and the environment variables keep priority over the file.
+// explicit holds the values of the flags of fs set in the command line by the flag names.
+func readFromConf(c *Flags, fs *flag.FlagSet, explicit map[string]string) error {
+	data, err := os.ReadFile(c.ConfigFileName)
+	if err != nil {
 		return err
 	}
 
-	var conf Flags
-	err = json.Unmarshal(allData, &conf)
-	if err != nil {
+	// The file is decoded over a copy, so a broken file changes nothing.
+	conf := *c
+	if err = json.Unmarshal(data, &conf); err != nil {
 		return err
 	}
+	conf.ConfigFileName = c.ConfigFileName
+	*c = conf
 
-	if c.Host == "" {
-		c.Host = conf.Host
-	}
-	if c.URL == "" {
-		c.URL = conf.URL
-	}
-	if c.FileName == "" {
-		c.FileName = conf.FileName
-	}
-	if c.DBDSN == "" {
-		c.DBDSN = conf.DBDSN
-	}
-	if c.TrustedSubnet == "" {
-		c.TrustedSubnet = conf.TrustedSubnet
-	}
-	if c.GRPC == "" {
-		c.GRPC = conf.GRPC
+	// The flags point to the fields of c, setting them again restores the command line values.
+	for name, value := range explicit {
+		if err = fs.Set(name, value); err != nil {
+			return err
+		}
 	}
-
-	return nil
+	return env.Parse(c)
 }
 
-// NewConfig creates an instance with settings from flags or environment variables.
-func NewConfig() *Flags {
+// NewConfig creates an instance with settings from environment variables, flags,
+// the configuration file and the defaults, in this order of priority.
+// log receives the errors of reading the configuration file.
+func NewConfig(log *zap.SugaredLogger) *Flags {
 	c := &Flags{}
 
 	flag.StringVar(&c.Host, "a", defHost, "HTTP server start address")
 	flag.StringVar(&c.URL, "b", defURL, "base address of the resulting URL")
 	flag.StringVar(&c.FileName, "f", defFileName, "full filename to save URLs")
-	flag.StringVar(&c.DBDSN, "d", "", "database connection address")
-	flag.StringVar(&c.ConfigFileName, "c", "", "the name of configuration file")
+	flag.StringVar(&c.DBDSN, "d", "", "database connection address, sqlite://<file> for SQLite")
+	c.FileCompactInterval = Duration{defFileCompactInterval}
+	flag.Var(&c.FileCompactInterval, "file-compact-interval", "how often the JSON file log is compacted")
+	flag.StringVar(&c.FileCompression, "file-compression", "", "compression of the JSON file: none, gzip or zstd")
+	flag.StringVar(&c.KVFileName, "kv", "", "full filename of the embedded key-value database")
+	flag.StringVar(&c.ConfigFileName, "c", "", "the name of configuration file, flags and environment variables override its settings")
 	flag.StringVar(&c.ConfigFileName, "config", "", "the name of configuration file")
 	flag.BoolVar(&c.EnableHTTPS, "s", defHTTPS, "https enabled")
+	flag.StringVar(&c.TLSCertFile, "tls-cert", "", "PEM certificate file, empty for a generated self-signed one")
+	flag.StringVar(&c.TLSKeyFile, "tls-key", "", "PEM private key file of the certificate")
+	flag.StringVar(&c.TLSMinVersion, "tls-min-version", defTLSMinVersion, "minimum TLS version: 1.2 or 1.3")
+	flag.StringVar(&c.TLSCiphers, "tls-ciphers", "", "comma-separated TLS 1.2 cipher suites, empty for the defaults")
+	flag.StringVar(&c.ACMEDomains, "acme-domains", "", "comma-separated domains of the ACME certificates, empty disables ACME")
+	flag.StringVar(&c.ACMEDirectory, "acme-directory", defACMEDirectory, "directory URL of the ACME server")
+	flag.StringVar(&c.ACMEEmail, "acme-email", "", "contact email of the ACME account")
+	flag.StringVar(&c.ACMECAFile, "acme-ca", "", "PEM root certificate of the ACME server, empty for the system roots")
+	flag.StringVar(&c.ACMEHTTPAddr, "acme-http-addr", defACMEHTTPAddr, "address of the HTTP-01 challenge server, empty for TLS-ALPN-01 only")
+	flag.StringVar(&c.DataDir, "data-dir", defDataDir, "directory of the service state, e.g. the generated certificate")
 	flag.StringVar(&c.TrustedSubnet, "t", "", "CIDR string")
 	flag.StringVar(&c.GRPC, "g", defGRPC, "gRPC port")
+	flag.BoolVar(&c.GRPCEnableTLS, "grpc-tls", false, "gRPC TLS enabled")
+	flag.StringVar(&c.GRPCClientCA, "grpc-client-ca", "", "PEM CA bundle of the gRPC client certificates, empty disables mTLS")
+	flag.BoolVar(&c.GRPCClientCertRequired, "grpc-client-cert-required", false, "reject gRPC clients without a valid certificate")
+	flag.StringVar(&c.GRPCClientIdentities, "grpc-client-identities", "", "comma-separated name=user:<ID> and name=admin pairs of client certificates")
+	flag.IntVar(&c.DeleteWorkers, "delete-workers", defDeleteWorkers, "number of workers deleting URLs")
+	flag.StringVar(&c.DeleteQueue, "delete-queue", "", "full filename to save the deletion queue, empty to keep it in memory")
+	c.RestorePeriod = Duration{defRestorePeriod}
+	flag.Var(&c.RestorePeriod, "restore-period", "how long a deleted URL can be restored")
+	c.PurgeAfter = Duration{defPurgeAfter}
+	flag.Var(&c.PurgeAfter, "purge-after", "how long deleted URLs are kept, 0 disables the purge")
+	c.PurgeInterval = Duration{defPurgeInterval}
+	flag.Var(&c.PurgeInterval, "purge-interval", "how often deleted URLs are purged")
+	flag.Var(&c.ClickFlushInterval, "click-flush-interval", "how often redirects counted in memory are written to the storage, 0 (default) writes every redirect")
+	flag.IntVar(&c.DBMaxConns, "db-max-conns", 0, "maximum size of the database connection pool")
+	flag.IntVar(&c.DBMinConns, "db-min-conns", 0, "minimum number of open database connections")
+	flag.Var(&c.DBMaxConnLifetime, "db-max-conn-lifetime", "maximum lifetime of a database connection")
+	flag.Var(&c.DBMaxConnIdleTime, "db-max-conn-idle-time", "maximum idle time of a database connection")
+	flag.Var(&c.DBHealthCheckPeriod, "db-health-check-period", "period of idle database connections checks")
+	flag.IntVar(&c.DBStatementCache, "db-statement-cache", 0, "number of prepared statements cached per connection")
+	flag.StringVar(&c.DBNotifyChannel, "db-notify-channel", "", "PostgreSQL channel of URL changes shared by the instances with the cache, empty disables notifications")
+	c.ReadTimeout = Duration{defReadTimeout}
+	flag.Var(&c.ReadTimeout, "storage-read-timeout", "time limit of reading from the storage")
+	c.WriteTimeout = Duration{defWriteTimeout}
+	flag.Var(&c.WriteTimeout, "storage-write-timeout", "time limit of adding and updating URLs")
+	c.DeleteTimeout = Duration{defDeleteTimeout}
+	flag.Var(&c.DeleteTimeout, "storage-delete-timeout", "time limit of deleting and purging URLs")
+	flag.IntVar(&c.CacheSize, "cache-size", defCacheSize, "number of short URLs cached for redirects, 0 disables the cache")
+	c.CacheTTL = Duration{defCacheTTL}
+	flag.Var(&c.CacheTTL, "cache-ttl", "how long a found short URL is cached")
+	c.CacheNegativeTTL = Duration{defCacheNegativeTTL}
+	flag.Var(&c.CacheNegativeTTL, "cache-negative-ttl", "how long an unknown short URL is cached")
+	flag.StringVar(&c.MetricsAddr, "metrics-addr", "", "Prometheus metrics server address, empty disables metrics")
+	flag.StringVar(&c.TraceExporter, "trace-exporter", "", "span exporter: otlp, stdout or file, empty disables tracing")
+	flag.StringVar(&c.TraceEndpoint, "trace-endpoint", defTraceEndpoint, "OTLP gRPC collector address")
+	flag.BoolVar(&c.TraceInsecure, "trace-insecure", false, "send spans to the collector without TLS")
+	flag.StringVar(&c.TraceFile, "trace-file", defTraceFile, "file the file span exporter writes to")
+	flag.Float64Var(&c.TraceSampleRatio, "trace-sample-ratio", defTraceSampleRatio, "share of sampled traces from 0 to 1")
+	flag.StringVar(&c.LogLevel, "log-level", defLogLevel, "minimum level of log records: debug, info, warn or error")
+	flag.StringVar(&c.LogFormat, "log-format", defLogFormat, "encoding of log records: json or console")
+	flag.IntVar(&c.LogSampling, "log-sampling", 0, "identical log records written per second before sampling, 0 disables sampling")
+	flag.StringVar(&c.LogFile, "log-file", "", "file log records are appended to, empty for stderr")
+	c.ShutdownTimeout = Duration{defShutdownTimeout}
+	flag.Var(&c.ShutdownTimeout, "shutdown-timeout", "how long the graceful shutdown waits before stopping")
+	c.ShutdownDrainDelay = Duration{defShutdownDrainDelay}
+	flag.Var(&c.ShutdownDrainDelay, "shutdown-drain-delay", "how long the servers accept requests after the service is marked as not ready")
 	flag.Parse()
+	explicit := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
 
 	env.Parse(c)
 
 	if c.ConfigFileName != "" {
-		err := readFromConf(c)
+		err := readFromConf(c, flag.CommandLine, explicit)
 		if err != nil {
-			logger.ZapSugar.Infow("reading configuration file", err)
+			log.Infow("reading configuration file", "file", c.ConfigFileName, "error", err)
 		}
 	}
 
@@ -1,30 +1,142 @@
 package config
 
 import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/caarlos0/env"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
 )
 
 func TestNewConfig(t *testing.T) {
-	flags := NewConfig()
+	flags := NewConfig(zaptest.NewLogger(t).Sugar())
 	if assert.NotEmpty(t, flags) {
 		assert.NotEmpty(t, flags.Host)
 		assert.NotEmpty(t, flags.URL)
 		assert.NotEmpty(t, flags.FileName)
+		assert.Zero(t, flags.ClickFlushInterval.Duration, "redirects are not buffered by default")
 	}
 }
 
 func TestReadFromConf(t *testing.T) {
-	c := Flags{
-		Host:           "",
-		URL:            "",
-		FileName:       "",
-		DBDSN:          "",
-		ConfigFileName: "for_tests.json",
-		EnableHTTPS:    false,
-		TrustedSubnet:  "",
+	tests := []struct {
+		name string
+		conf string
+		args []string
+		env  map[string]string
+		want Flags
+	}{
+		{
+			name: "file over defaults",
+			conf: `{"server_address":"localhost:9090","delete_workers":0,"log_level":"debug","enable_https":true}`,
+			want: Flags{Host: "localhost:9090", DeleteWorkers: 0, LogLevel: "debug", EnableHTTPS: true},
+		},
+		{
+			name: "missing keys keep defaults",
+			conf: `{"enable_https":true}`,
+			want: Flags{Host: "localhost:8080", DeleteWorkers: 4, LogLevel: "info", EnableHTTPS: true},
+		},
+		{
+			name: "grpc tls from file",
+			conf: `{"grpc_enable_tls":true,"grpc_client_cert_required":true}`,
+			want: Flags{Host: "localhost:8080", DeleteWorkers: 4, LogLevel: "info", GRPCEnableTLS: true, GRPCClientCertRequired: true},
+		},
+		{
+			name: "flags over file",
+			conf: `{"server_address":"localhost:9090","delete_workers":0,"log_level":"debug"}`,
+			args: []string{"-a", "localhost:8080", "-delete-workers", "4"},
+			want: Flags{Host: "localhost:8080", DeleteWorkers: 4, LogLevel: "debug"},
+		},
+		{
+			name: "environment over file",
+			conf: `{"delete_workers":0,"log_level":"debug","enable_https":true}`,
+			env:  map[string]string{"LOG_LEVEL": "warn", "ENABLE_HTTPS": "false"},
+			want: Flags{Host: "localhost:8080", DeleteWorkers: 0, LogLevel: "warn"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+			fileName := filepath.Join(t.TempDir(), "config.json")
+			assert.NoError(t, os.WriteFile(fileName, []byte(test.conf), 0o600))
+
+			c := Flags{ConfigFileName: fileName}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.StringVar(&c.Host, "a", "localhost:8080", "")
+			fs.IntVar(&c.DeleteWorkers, "delete-workers", 4, "")
+			fs.StringVar(&c.LogLevel, "log-level", "info", "")
+			fs.BoolVar(&c.EnableHTTPS, "s", false, "")
+			assert.NoError(t, fs.Parse(test.args))
+			explicit := make(map[string]string)
+			fs.Visit(func(f *flag.Flag) {
+				explicit[f.Name] = f.Value.String()
+			})
+
+			assert.NoError(t, readFromConf(&c, fs, explicit))
+			test.want.ConfigFileName = fileName
+			assert.Equal(t, test.want, c)
+		})
+	}
+
+	t.Run("broken file", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "config.json")
+		assert.NoError(t, os.WriteFile(fileName, []byte(`{"delete_workers":`), 0o600))
+		c := Flags{ConfigFileName: fileName, DeleteWorkers: 4}
+		assert.Error(t, readFromConf(&c, flag.NewFlagSet("test", flag.ContinueOnError), nil))
+		assert.Equal(t, 4, c.DeleteWorkers)
+	})
+
+	t.Run("test file", func(t *testing.T) {
+		c := Flags{ConfigFileName: "for_tests.json"}
+		assert.NoError(t, readFromConf(&c, flag.NewFlagSet("test", flag.ContinueOnError), nil))
+		assert.Equal(t, "localhost:9090", c.Host)
+		assert.True(t, c.EnableHTTPS)
+	})
+}
+
+func TestDuration(t *testing.T) {
+	t.Run("from json", func(t *testing.T) {
+		var c Flags
+		err := json.Unmarshal([]byte(`{"restore_period":"2h"}`), &c)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 2*time.Hour, c.RestorePeriod.Duration)
+		}
+	})
+	t.Run("from env", func(t *testing.T) {
+		t.Setenv("PURGE_AFTER", "30m")
+		var c Flags
+		if assert.NoError(t, env.Parse(&c)) {
+			assert.Equal(t, 30*time.Minute, c.PurgeAfter.Duration)
+		}
+	})
+	t.Run("bad value", func(t *testing.T) {
+		var d Duration
+		assert.Error(t, d.Set("abc"))
+	})
+}
+
+func TestDrainDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		delay   time.Duration
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{name: "within timeout", delay: 5 * time.Second, timeout: 30 * time.Second, want: 5 * time.Second},
+		{name: "half of timeout", delay: 20 * time.Second, timeout: 30 * time.Second, want: 15 * time.Second},
+		{name: "no delay", delay: 0, timeout: 30 * time.Second, want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Flags{ShutdownDrainDelay: Duration{test.delay}, ShutdownTimeout: Duration{test.timeout}}
+			assert.Equal(t, test.want, c.DrainDelay())
+		})
 	}
-	err := readFromConf(&c)
-	assert.NoError(t, err)
 }
@@ -0,0 +1,29 @@
+package config
+
+import "time"
+
+// Duration is a time.Duration that is set as a string like "72h"
+// in flags, environment variables and the configuration file.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses the duration from a string.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalText returns the duration as a string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Set parses the duration from a flag value.
+func (d *Duration) Set(s string) error {
+	return d.UnmarshalText([]byte(s))
+}
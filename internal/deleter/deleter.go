@@ -1,107 +1,282 @@
-// Package deleter uses channels to async delete a list of user's URLs.
+// Package deleter asynchronously deletes lists of user's URLs using a pool of workers.
 package deleter
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 
 	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/requestid"
+	"github.com/Julia-ivv/shortener-url.git/internal/tracing"
 )
 
-// NumWorkers - number of channels receiving data for deletion.
-const NumWorkers = 5
+// DefaultWorkers - the number of workers if the number is not set.
+const DefaultWorkers = 5
 
-// DelData contains the URL for deletion and its user ID.
-type DelData struct {
-	ShortURL string
-	UserID   int
+// batchSize - the number of URLs passed to the sink at once.
+const batchSize = 100
+
+// Retry settings of a failed batch.
+const (
+	// retryAttempts - how many times a batch is passed to the sink before the task is left
+	// in the queue until the next start.
+	retryAttempts = 5
+	// defRetryWait - the wait before the first retry, it doubles after each attempt.
+	defRetryWait = 100 * time.Millisecond
+)
+
+// ErrPoolClosed is returned when a task is added to a closed pool.
+var ErrPoolClosed = errors.New("deletion pool is closed")
+
+// Task contains a list of the user's URLs for deletion.
+type Task struct {
+	// ID - task ID, the same as the job ID.
+	ID string `json:"id"`
+	// UserID - ID of the user who owns the URLs.
+	UserID int `json:"user_id"`
+	// ShortURLs - short URLs for deletion.
+	ShortURLs []string `json:"short_urls"`
+	// Trace - the trace context of the request that added the task,
+	// the deletion span continues its trace.
+	Trace map[string]string `json:"trace,omitempty"`
+	// RequestID - the ID of the request that added the task,
+	// the log records of the deletion get it.
+	RequestID string `json:"request_id,omitempty"`
 }
 
-// Generator sends URLs to the channel for deletion.
-func Generator(doneCh chan struct{}, input []string, userID int) chan DelData {
-	inputCh := make(chan DelData)
+// Sink marks the user's URLs as deleted in the storage.
+type Sink interface {
+	// DeleteUserURLs marks the URLs as deleted and returns the URLs that were marked.
+	DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error)
+}
 
-	go func() {
-		defer close(inputCh)
-
-		for _, url := range input {
-			select {
-			case <-doneCh:
-				return
-			case inputCh <- DelData{ShortURL: url, UserID: userID}:
-			}
-		}
-	}()
+// Reporter receives the progress of tasks.
+type Reporter interface {
+	// Restore registers a task loaded from the queue after a restart.
+	Restore(id string, userID int, shortURLs []string)
+	// Start is called before the task is processed.
+	Start(id string)
+	// Report is called after each batch, offset - the position of the batch in the task.
+	Report(id string, offset int, batch []string, deleted []string, err error)
+	// Finish is called after all batches of the task are processed.
+	Finish(id string)
+}
 
-	return inputCh
+// Pool processes deletion tasks with a fixed number of workers.
+// Tasks are saved to the queue before processing and removed from it after,
+// so unfinished tasks are processed again after a restart.
+type Pool struct {
+	sink    Sink
+	rep     Reporter
+	queue   Queue
+	workers int
+	// retryWait - the wait before the first retry of a failed batch.
+	retryWait time.Duration
+	tasks     []Task
+	closed    bool
+	// adding - the number of tasks being saved to the queue, the workers wait for them on closing.
+	adding int
+	mu     sync.Mutex
+	cond   *sync.Cond
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-// DelResult contains the result of removing the URL.
-type DelResult struct {
-	Err  error
-	Rows int64
+// NewPool creates a pool of workers deleting URLs with sink, log receives the errors of the workers.
+func NewPool(sink Sink, rep Reporter, queue Queue, workers int, log *zap.SugaredLogger) *Pool {
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	p := &Pool{
+		sink:      sink,
+		rep:       rep,
+		queue:     queue,
+		workers:   workers,
+		retryWait: defRetryWait,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.ctx, p.cancel = context.WithCancel(logger.NewContext(context.Background(), log))
+	return p
 }
 
-// del receives the URL for deletion from the channel inputCh,
-// marks the URL as deleted in the storage,
-// places the result of the work in the channel delRes.
-func del(doneCh chan struct{}, inputCh chan DelData, stmt *sql.Stmt) chan DelResult {
-	delRes := make(chan DelResult)
+// Start restores unfinished tasks from the queue and starts the workers.
+func (p *Pool) Start() error {
+	pending, err := p.queue.Pending()
+	if err != nil {
+		return err
+	}
+	for _, task := range pending {
+		p.rep.Restore(task.ID, task.UserID, task.ShortURLs)
+	}
+
+	p.mu.Lock()
+	p.tasks = append(p.tasks, pending...)
+	p.mu.Unlock()
+
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return nil
+}
+
+// Add saves the task to the queue and schedules it for processing.
+// The task is saved without the lock, so a slow queue does not block the workers and other adds.
+func (p *Pool) Add(task Task) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.adding++
+	p.mu.Unlock()
+
+	err := p.queue.Push(task)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.adding--
+	if err == nil {
+		p.tasks = append(p.tasks, task)
+	}
+	// A closing pool waits for the added tasks, the workers recheck whether they can exit.
+	p.cond.Broadcast()
+	return err
+}
 
+// Close stops accepting tasks and waits for the workers to process the remaining ones.
+// When ctx is done, the current batches are cancelled,
+// the unfinished tasks stay in the queue until the next start.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	done := make(chan struct{})
 	go func() {
-		defer close(delRes)
-		for data := range inputCh {
-			result, errEx := stmt.Exec(data.UserID, data.ShortURL)
-			rows, err := result.RowsAffected()
-			if err != nil {
-				logger.ZapSugar.Infow("returns the number of rows", err)
-			}
-			select {
-			case <-doneCh:
-				return
-			case delRes <- DelResult{Rows: rows, Err: errEx}:
-			}
-		}
+		p.wg.Wait()
+		close(done)
 	}()
 
-	return delRes
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		p.mu.Lock()
+		p.cond.Broadcast()
+		p.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Len returns the number of tasks waiting for a worker.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.tasks)
 }
 
-// FanOut fills the channel with the deletion results.
-func FanOut(doneCh chan struct{}, inputCh chan DelData, stmt *sql.Stmt) []chan DelResult {
-	channels := make([]chan DelResult, NumWorkers)
-	for i := 0; i < NumWorkers; i++ {
-		delResutlCh := del(doneCh, inputCh, stmt)
-		channels[i] = delResutlCh
+// next waits for the next task, returns false when the pool is closed and there are no tasks
+// left or being added.
+func (p *Pool) next() (Task, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.tasks) == 0 && (!p.closed || p.adding > 0) && p.ctx.Err() == nil {
+		p.cond.Wait()
 	}
-	return channels
+	if len(p.tasks) == 0 || p.ctx.Err() != nil {
+		return Task{}, false
+	}
+	task := p.tasks[0]
+	p.tasks = p.tasks[1:]
+	return task, true
 }
 
-// FanIn collects URL removal results from several channels into one.
-func FanIn(stmt *sql.Stmt, doneCh chan struct{}, chans ...chan DelResult) chan DelResult {
-	finalCh := make(chan DelResult)
-	var wGroup sync.WaitGroup
-
-	for _, ch := range chans {
-		iterChan := ch
-		wGroup.Add(1)
-
-		go func() {
-			defer wGroup.Done()
-			for data := range iterChan {
-				select {
-				case <-doneCh:
-					return
-				case finalCh <- data:
-				}
-			}
-		}()
+// work processes tasks until the pool is closed.
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		task, ok := p.next()
+		if !ok {
+			return
+		}
+		p.process(task)
 	}
+}
 
-	go func() {
-		wGroup.Wait()
-		close(finalCh)
-	}()
+// process deletes the task's URLs in batches and removes the task from the queue.
+// A batch that still fails after the retries keeps the task in the queue,
+// so it is processed again after the next start.
+func (p *Pool) process(task Task) {
+	ctx, span := tracing.Tracer().Start(tracing.Extract(p.ctx, task.Trace), "deleter.process",
+		trace.WithAttributes(
+			attribute.String("job.id", task.ID),
+			attribute.Int("job.user_id", task.UserID),
+			attribute.Int("job.urls", len(task.ShortURLs)),
+		))
+	defer span.End()
+	ctx = logger.With(requestid.NewContext(ctx, task.RequestID), "request_id", task.RequestID, "user_id", task.UserID)
+	ctx = tracing.WithLogger(ctx)
+
+	p.rep.Start(task.ID)
+	failed := false
+	for offset := 0; offset < len(task.ShortURLs); offset += batchSize {
+		end := offset + batchSize
+		if end > len(task.ShortURLs) {
+			end = len(task.ShortURLs)
+		}
+		batch := task.ShortURLs[offset:end]
+		deleted, err := p.deleteBatch(ctx, batch, task.UserID)
+		if p.ctx.Err() != nil {
+			return
+		}
+		p.rep.Report(task.ID, offset, batch, deleted, err)
+		if err != nil {
+			failed = true
+		}
+	}
+	p.rep.Finish(task.ID)
+
+	if failed {
+		logger.FromContext(ctx).Errorw("deletion task failed, it stays in the deletion queue until the next start", "task", task.ID)
+		return
+	}
+	if err := p.queue.Done(task.ID); err != nil {
+		logger.FromContext(ctx).Errorw("remove task from deletion queue", "task", task.ID, "error", err)
+	}
+}
+
+// deleteBatch passes the batch to the sink, a failed batch is retried with a growing wait.
+// The retries stop when the pool is cancelled.
+func (p *Pool) deleteBatch(ctx context.Context, batch []string, userID int) (deleted []string, err error) {
+	wait := p.retryWait
+	for attempt := 1; ; attempt++ {
+		deleted, err = p.sink.DeleteUserURLs(ctx, batch, userID)
+		if err == nil || attempt == retryAttempts {
+			return deleted, err
+		}
+		logger.FromContext(ctx).Warnw("delete URLs, retrying", "attempt", attempt, "wait", wait, "error", err)
 
-	return finalCh
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-p.ctx.Done():
+			t.Stop()
+			return deleted, err
+		}
+		wait *= 2
+	}
 }
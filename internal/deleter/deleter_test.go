@@ -0,0 +1,309 @@
+package deleter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/tracing"
+)
+
+const testUserID = 123
+
+// testSink marks URLs as deleted in memory.
+// The first fails calls return err, all of them if fails is 0.
+type testSink struct {
+	urls    map[string]bool
+	batches int
+	err     error
+	fails   int
+	sync.Mutex
+}
+
+func (s *testSink) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.batches++
+	if s.err != nil && (s.fails == 0 || s.batches <= s.fails) {
+		return nil, s.err
+	}
+	for _, v := range delURLs {
+		if _, ok := s.urls[v]; ok {
+			s.urls[v] = true
+			deleted = append(deleted, v)
+		}
+	}
+	return deleted, nil
+}
+
+// testReporter saves the calls of the pool.
+type testReporter struct {
+	restored []string
+	finished []string
+	deleted  []string
+	errs     []error
+	sync.Mutex
+}
+
+func (r *testReporter) Restore(id string, userID int, shortURLs []string) {
+	r.Lock()
+	defer r.Unlock()
+	r.restored = append(r.restored, id)
+}
+
+func (r *testReporter) Start(id string) {}
+
+func (r *testReporter) Report(id string, offset int, batch []string, deleted []string, err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.deleted = append(r.deleted, deleted...)
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+}
+
+func (r *testReporter) Finish(id string) {
+	r.Lock()
+	defer r.Unlock()
+	r.finished = append(r.finished, id)
+}
+
+func TestPool(t *testing.T) {
+	urls := make([]string, batchSize+1)
+	for k := range urls {
+		urls[k] = "url" + strconv.Itoa(k)
+	}
+
+	tests := []struct {
+		name        string
+		sinkErr     error
+		sinkFails   int
+		wantBatches int
+		wantDeleted int
+		wantErrs    int
+		wantPending int
+	}{
+		{
+			name:        "delete in batches",
+			sinkErr:     nil,
+			wantBatches: 2,
+			wantDeleted: 2,
+			wantErrs:    0,
+			wantPending: 0,
+		},
+		{
+			name:        "sink error",
+			sinkErr:     errors.New("some error"),
+			wantBatches: 2 * retryAttempts,
+			wantDeleted: 0,
+			wantErrs:    2,
+			wantPending: 1,
+		},
+		{
+			name:        "retried sink error",
+			sinkErr:     errors.New("some error"),
+			sinkFails:   retryAttempts - 1,
+			wantBatches: retryAttempts + 1,
+			wantDeleted: 2,
+			wantErrs:    0,
+			wantPending: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queue, err := NewFileQueue(filepath.Join(t.TempDir(), "queue.json"))
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer queue.Close()
+
+			sink := &testSink{urls: map[string]bool{urls[0]: false, urls[batchSize]: false}, err: test.sinkErr, fails: test.sinkFails}
+			rep := &testReporter{}
+			pool := NewPool(sink, rep, queue, 2, zaptest.NewLogger(t).Sugar())
+			pool.retryWait = time.Millisecond
+			if !assert.NoError(t, pool.Start()) {
+				return
+			}
+			assert.NoError(t, pool.Add(Task{ID: "job1", UserID: testUserID, ShortURLs: urls}))
+			assert.NoError(t, pool.Close(context.Background()))
+
+			assert.Equal(t, test.wantBatches, sink.batches)
+			assert.Equal(t, test.wantDeleted, len(rep.deleted))
+			assert.Equal(t, test.wantErrs, len(rep.errs))
+			assert.Equal(t, []string{"job1"}, rep.finished)
+			assert.ErrorIs(t, pool.Add(Task{ID: "job2"}), ErrPoolClosed)
+
+			pending, err := queue.Pending()
+			assert.NoError(t, err)
+			assert.Len(t, pending, test.wantPending, "a failed task stays in the queue")
+		})
+	}
+}
+
+func TestPoolRestore(t *testing.T) {
+	fileName := t.TempDir() + "/queue.json"
+	queue, err := NewFileQueue(fileName)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, queue.Push(Task{ID: "job1", UserID: testUserID, ShortURLs: []string{"EwH"}}))
+	assert.NoError(t, queue.Close())
+
+	queue, err = NewFileQueue(fileName)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer queue.Close()
+
+	sink := &testSink{urls: map[string]bool{"EwH": false}}
+	rep := &testReporter{}
+	pool := NewPool(sink, rep, queue, 1, zaptest.NewLogger(t).Sugar())
+	t.Run("restore unfinished task", func(t *testing.T) {
+		assert.NoError(t, pool.Start())
+		assert.NoError(t, pool.Close(context.Background()))
+		assert.Equal(t, []string{"job1"}, rep.restored)
+		assert.True(t, slices.Equal([]string{"EwH"}, rep.deleted))
+		assert.True(t, sink.urls["EwH"])
+
+		pending, err := queue.Pending()
+		assert.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestPoolLen(t *testing.T) {
+	sink := &testSink{urls: map[string]bool{"EwH": false}}
+	pool := NewPool(sink, &testReporter{}, NewMemQueue(), 1, zaptest.NewLogger(t).Sugar())
+	t.Run("tasks wait until the pool starts", func(t *testing.T) {
+		assert.NoError(t, pool.Add(Task{ID: "job1", UserID: testUserID, ShortURLs: []string{"EwH"}}))
+		assert.NoError(t, pool.Add(Task{ID: "job2", UserID: testUserID, ShortURLs: []string{"EwH"}}))
+		assert.Equal(t, 2, pool.Len())
+
+		assert.NoError(t, pool.Start())
+		assert.NoError(t, pool.Close(context.Background()))
+		assert.Equal(t, 0, pool.Len())
+	})
+}
+
+// slowQueue saves tasks in memory once push is closed.
+type slowQueue struct {
+	*MemQueue
+	pushing chan struct{}
+	push    chan struct{}
+}
+
+func (q *slowQueue) Push(task Task) error {
+	q.pushing <- struct{}{}
+	<-q.push
+	return q.MemQueue.Push(task)
+}
+
+func TestPoolSlowQueue(t *testing.T) {
+	sink := &testSink{urls: map[string]bool{"EwH": false}}
+	rep := &testReporter{}
+	queue := &slowQueue{MemQueue: NewMemQueue(), pushing: make(chan struct{}), push: make(chan struct{})}
+	pool := NewPool(sink, rep, queue, 1, zaptest.NewLogger(t).Sugar())
+	assert.NoError(t, pool.Start())
+
+	added := make(chan error)
+	go func() {
+		added <- pool.Add(Task{ID: "job1", UserID: testUserID, ShortURLs: []string{"EwH"}})
+	}()
+	<-queue.pushing
+	assert.Equal(t, 0, pool.Len(), "the pool is not locked while the task is saved")
+
+	closed := make(chan error)
+	go func() {
+		closed <- pool.Close(context.Background())
+	}()
+	assert.Eventually(t, func() bool {
+		pool.mu.Lock()
+		defer pool.mu.Unlock()
+		return pool.closed
+	}, time.Second, time.Millisecond)
+	assert.ErrorIs(t, pool.Add(Task{ID: "job2"}), ErrPoolClosed)
+	select {
+	case <-closed:
+		t.Fatal("the pool is closed before the saved task is added")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(queue.push)
+	assert.NoError(t, <-added)
+	assert.NoError(t, <-closed)
+	assert.Equal(t, []string{"job1"}, rep.finished, "the task saved while closing is processed")
+	assert.True(t, sink.urls["EwH"])
+}
+
+// traceSink saves the trace IDs of the deletion contexts.
+type traceSink struct {
+	traceIDs []trace.TraceID
+	sync.Mutex
+}
+
+func (s *traceSink) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.traceIDs = append(s.traceIDs, trace.SpanContextFromContext(ctx).TraceID())
+	return delURLs, nil
+}
+
+func TestPoolTrace(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer tp.Shutdown(context.Background())
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "request")
+	span.End()
+
+	sink := &traceSink{}
+	pool := NewPool(sink, &testReporter{}, NewMemQueue(), 1, zaptest.NewLogger(t).Sugar())
+	t.Run("deletion continues the request trace", func(t *testing.T) {
+		assert.NoError(t, pool.Start())
+		assert.NoError(t, pool.Add(Task{ID: "job1", UserID: testUserID, ShortURLs: []string{"EwH"}, Trace: tracing.Inject(ctx)}))
+		assert.NoError(t, pool.Close(context.Background()))
+		assert.Equal(t, []trace.TraceID{span.SpanContext().TraceID()}, sink.traceIDs)
+	})
+}
+
+// logSink writes a log record for each batch.
+type logSink struct{}
+
+func (s logSink) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
+	logger.FromContext(ctx).Infow("delete batch", "urls", len(delURLs))
+	return delURLs, nil
+}
+
+func TestPoolRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	pool := NewPool(logSink{}, &testReporter{}, NewMemQueue(), 1, zap.New(core).Sugar())
+	assert.NoError(t, pool.Start())
+	assert.NoError(t, pool.Add(Task{ID: "job1", UserID: testUserID, ShortURLs: []string{"EwH"}, RequestID: "req-1"}))
+	assert.NoError(t, pool.Close(context.Background()))
+
+	entries := logs.FilterMessage("delete batch").All()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Equal(t, int64(testUserID), fields["user_id"])
+	}
+}
@@ -0,0 +1,222 @@
+package deleter
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Queue stores deletion tasks until they are processed.
+type Queue interface {
+	// Push saves the task.
+	Push(task Task) error
+	// Done removes the processed task.
+	Done(id string) error
+	// Pending returns unfinished tasks in the order they were added.
+	Pending() ([]Task, error)
+	// Close closes the queue.
+	Close() error
+}
+
+// MemQueue is a queue that does not save tasks, they are lost on restart.
+type MemQueue struct{}
+
+// NewMemQueue creates a queue without persistence.
+func NewMemQueue() *MemQueue {
+	return &MemQueue{}
+}
+
+// Push does nothing, the task is only kept by the pool.
+func (q *MemQueue) Push(task Task) error {
+	return nil
+}
+
+// Done does nothing.
+func (q *MemQueue) Done(id string) error {
+	return nil
+}
+
+// Pending returns no tasks.
+func (q *MemQueue) Pending() ([]Task, error) {
+	return nil, nil
+}
+
+// Close does nothing.
+func (q *MemQueue) Close() error {
+	return nil
+}
+
+// Operations of the queue journal.
+const (
+	opPush = "push"
+	opDone = "done"
+)
+
+// record is one line of the queue journal.
+type record struct {
+	Op   string `json:"op"`
+	Task *Task  `json:"task,omitempty"`
+	ID   string `json:"id,omitempty"`
+}
+
+// FileQueue is a queue that saves tasks to an append-only journal file.
+// The journal is compacted on opening and truncated when there are no pending tasks.
+type FileQueue struct {
+	file    *os.File
+	enc     *json.Encoder
+	order   []string
+	pending map[string]Task
+	sync.Mutex
+}
+
+// NewFileQueue opens the journal file and loads unfinished tasks.
+func NewFileQueue(fileName string) (*FileQueue, error) {
+	q := &FileQueue{pending: make(map[string]Task)}
+	if err := q.load(fileName); err != nil {
+		return nil, err
+	}
+	if err := q.compact(fileName); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	q.file = file
+	q.enc = json.NewEncoder(file)
+	return q, nil
+}
+
+// load reads the journal, a line that cannot be decoded
+// (e.g. the last line written during a crash) is skipped.
+func (q *FileQueue) load(fileName string) error {
+	file, err := os.Open(fileName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scan := bufio.NewScanner(file)
+	scan.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scan.Scan() {
+		var rec record
+		if err = json.Unmarshal(scan.Bytes(), &rec); err != nil {
+			continue
+		}
+		switch {
+		case rec.Op == opPush && rec.Task != nil:
+			if _, ok := q.pending[rec.Task.ID]; !ok {
+				q.order = append(q.order, rec.Task.ID)
+			}
+			q.pending[rec.Task.ID] = *rec.Task
+		case rec.Op == opDone:
+			q.remove(rec.ID)
+		}
+	}
+	return scan.Err()
+}
+
+// compact rewrites the journal with pending tasks only.
+func (q *FileQueue) compact(fileName string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	for _, id := range q.order {
+		task := q.pending[id]
+		if err = enc.Encode(record{Op: opPush, Task: &task}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
+
+// remove removes the task from pending tasks. Must be called under lock.
+func (q *FileQueue) remove(id string) {
+	if _, ok := q.pending[id]; !ok {
+		return
+	}
+	delete(q.pending, id)
+	for k, v := range q.order {
+		if v == id {
+			q.order = append(q.order[:k], q.order[k+1:]...)
+			break
+		}
+	}
+}
+
+// write appends the record to the journal and flushes it to disk.
+func (q *FileQueue) write(rec record) error {
+	if err := q.enc.Encode(rec); err != nil {
+		return err
+	}
+	return q.file.Sync()
+}
+
+// Push saves the task to the journal.
+func (q *FileQueue) Push(task Task) error {
+	q.Lock()
+	defer q.Unlock()
+
+	if err := q.write(record{Op: opPush, Task: &task}); err != nil {
+		return err
+	}
+	if _, ok := q.pending[task.ID]; !ok {
+		q.order = append(q.order, task.ID)
+	}
+	q.pending[task.ID] = task
+	return nil
+}
+
+// Done marks the task as processed in the journal.
+// The journal is truncated when no pending tasks are left.
+func (q *FileQueue) Done(id string) error {
+	q.Lock()
+	defer q.Unlock()
+
+	q.remove(id)
+	if len(q.pending) == 0 {
+		if err := q.file.Truncate(0); err != nil {
+			return err
+		}
+		return q.file.Sync()
+	}
+	return q.write(record{Op: opDone, ID: id})
+}
+
+// Pending returns unfinished tasks in the order they were added.
+func (q *FileQueue) Pending() ([]Task, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	tasks := make([]Task, 0, len(q.order))
+	for _, id := range q.order {
+		tasks = append(tasks, q.pending[id])
+	}
+	return tasks, nil
+}
+
+// Close closes the journal file.
+func (q *FileQueue) Close() error {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.file.Close()
+}
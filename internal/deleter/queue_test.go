@@ -0,0 +1,56 @@
+package deleter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileQueue(t *testing.T) {
+	fileName := t.TempDir() + "/queue.json"
+	queue, err := NewFileQueue(fileName)
+	require.NoError(t, err)
+
+	tasks := []Task{
+		{ID: "job1", UserID: testUserID, ShortURLs: []string{"EwH"}},
+		{ID: "job2", UserID: testUserID, ShortURLs: []string{"Ert"}},
+		{ID: "job3", UserID: testUserID, ShortURLs: []string{"Euu"}},
+	}
+	for _, v := range tasks {
+		require.NoError(t, queue.Push(v))
+	}
+	require.NoError(t, queue.Done("job2"))
+	require.NoError(t, queue.Close())
+
+	// simulate a crash during writing the last record
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND, 0666)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"done","id":"jo`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	t.Run("load pending tasks", func(t *testing.T) {
+		queue, err = NewFileQueue(fileName)
+		require.NoError(t, err)
+		defer queue.Close()
+
+		pending, err := queue.Pending()
+		assert.NoError(t, err)
+		assert.Equal(t, []Task{tasks[0], tasks[2]}, pending)
+	})
+
+	t.Run("truncate when empty", func(t *testing.T) {
+		queue, err = NewFileQueue(fileName)
+		require.NoError(t, err)
+		defer queue.Close()
+
+		assert.NoError(t, queue.Done("job1"))
+		assert.NoError(t, queue.Done("job3"))
+		info, err := os.Stat(fileName)
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(0), info.Size())
+		}
+	})
+}
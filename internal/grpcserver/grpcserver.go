@@ -5,19 +5,24 @@ import (
 	"context"
 	"errors"
 	"net"
-	"sync"
+	"time"
 
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
 	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
 	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/internal/deleter"
+	"github.com/Julia-ivv/shortener-url.git/internal/jobs"
+	"github.com/Julia-ivv/shortener-url.git/internal/metrics"
 	pb "github.com/Julia-ivv/shortener-url.git/internal/proto"
+	"github.com/Julia-ivv/shortener-url.git/internal/requestid"
 	"github.com/Julia-ivv/shortener-url.git/internal/storage"
-	"github.com/Julia-ivv/shortener-url.git/pkg/randomizer"
+	"github.com/Julia-ivv/shortener-url.git/internal/tracing"
 )
 
 // ShortenerServer stores the repository and settings of this application.
@@ -25,29 +30,40 @@ type ShortenerGRPCServer struct {
 	pb.UnimplementedShortUrlServer
 	stor storage.Repositories
 	cfg  config.Flags
-	wg   *sync.WaitGroup
+	pool *deleter.Pool
+	jobs *jobs.Tracker
+	log  *zap.SugaredLogger
 }
 
 // NewShortenerServer creates an instance with storage and settings for grpc methods.
-func NewShortenerServer(stor storage.Repositories, cfg config.Flags, wg *sync.WaitGroup) *ShortenerGRPCServer {
+// log is used for the calls whose context has no logger.
+func NewShortenerServer(stor storage.Repositories, cfg config.Flags, pool *deleter.Pool, tracker *jobs.Tracker, log *zap.SugaredLogger) *ShortenerGRPCServer {
 	h := &ShortenerGRPCServer{}
 	h.stor = stor
 	h.cfg = cfg
-	h.wg = wg
+	h.pool = pool
+	h.jobs = tracker
+	h.log = log
 	return h
 }
 
-// GetURL gets a long URL from the storage using shortURL.
-func (h *ShortenerGRPCServer) GetURL(ctx context.Context, in *pb.GetUrlRequest) (*pb.GetUrlResponse, error) {
+// GetUrl gets a long URL from the storage using shortURL.
+func (h *ShortenerGRPCServer) GetUrl(ctx context.Context, in *pb.GetUrlRequest) (*pb.GetUrlResponse, error) {
 	shortURL := in.ShortUrl
 
 	originURL, isDel, ok := h.stor.GetURL(ctx, shortURL)
 	if !ok {
+		metrics.Redirect(metrics.RedirectNotFound)
 		return nil, status.Error(codes.NotFound, "short URL not found")
 	}
 	if isDel {
+		metrics.Redirect(metrics.RedirectDeleted)
 		return nil, status.Error(codes.NotFound, "short URL has been removed")
 	}
+	metrics.Redirect(metrics.RedirectFound)
+	if err := h.stor.AddClick(ctx, shortURL); err != nil {
+		logger.FromContextOr(ctx, h.log).Infow("add click", "short URL", shortURL, "error", err)
+	}
 
 	return &pb.GetUrlResponse{
 		OriginalUrl: originURL,
@@ -73,36 +89,26 @@ func (h *ShortenerGRPCServer) PostBatch(ctx context.Context, in *pb.PostBatchReq
 		reqBatch[k].OriginalURL = v.OriginalUrl
 	}
 
-	resBatch := make([]storage.ResponseBatch, len(in.RequestBatchs))
-	for k, v := range reqBatch {
-		shortURL, err := randomizer.GenerateRandomString(randomizer.LengthShortURL)
-		if err != nil {
-			return nil, status.Error(codes.Internal, "randomizer error")
-		}
-		resBatch[k].CorrelationID = v.CorrelationID
-		resBatch[k].ShortURLFull = h.cfg.URL + "/" + shortURL
-		resBatch[k].ShortURL = shortURL
-	}
-
-	err := h.stor.AddBatch(ctx, resBatch, reqBatch, id)
+	results, err := storage.AddNewBatch(ctx, h.stor, reqBatch, id)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	res := make([]*pb.PostBatchResponse_ResponseBatch, 0, len(resBatch))
-	for _, v := range resBatch {
+	res := make([]*pb.PostBatchResponse_ResponseBatch, 0, len(reqBatch))
+	for k, v := range reqBatch {
 		res = append(res, &pb.PostBatchResponse_ResponseBatch{
 			CorrelationId: v.CorrelationID,
-			ShortUrl:      v.ShortURLFull,
+			ShortUrl:      h.cfg.URL + "/" + results[k].ShortURL,
+			Status:        results[k].Status,
 		})
 	}
 
 	return &pb.PostBatchResponse{ResponseBatchs: res}, nil
 }
 
-// PostURL gets a long URL from the request body.
+// PostUrl gets a long URL from the request body.
 // Adds it to storage, returns a short URL in the response body.
-func (h *ShortenerGRPCServer) PostURL(ctx context.Context, in *pb.PostUrlRequest) (*pb.PostUrlResponse, error) {
+func (h *ShortenerGRPCServer) PostUrl(ctx context.Context, in *pb.PostUrlRequest) (*pb.PostUrlResponse, error) {
 	v := ctx.Value(authorizer.UserContextKey)
 	if v == nil {
 		return nil, status.Error(codes.Unauthenticated, "missing user id")
@@ -113,15 +119,10 @@ func (h *ShortenerGRPCServer) PostURL(ctx context.Context, in *pb.PostUrlRequest
 		return nil, status.Error(codes.DataLoss, "empty request")
 	}
 
-	shortURL, err := randomizer.GenerateRandomString(randomizer.LengthShortURL)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "randomizer error")
-	}
-	findURL, err := h.stor.AddURL(ctx, shortURL, in.OriginalUrl, id)
+	shortURL, err := storage.AddNewURL(ctx, h.stor, in.OriginalUrl, id)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			return &pb.PostUrlResponse{ShortUrl: h.cfg.URL + "/" + findURL},
+		if errors.Is(err, storage.ErrConflict) {
+			return &pb.PostUrlResponse{ShortUrl: h.cfg.URL + "/" + shortURL},
 				status.Error(codes.AlreadyExists, "this URL already exists")
 		}
 		return nil, status.Error(codes.Internal, err.Error())
@@ -168,20 +169,82 @@ func (h *ShortenerGRPCServer) DeleteUserUrls(ctx context.Context, in *pb.DeleteU
 		return nil, status.Error(codes.DataLoss, "empty request")
 	}
 
-	h.wg.Add(1)
-	go func() {
-		h.stor.DeleteUserURLs(ctx, in.DelUrls, id)
-		h.wg.Done()
-	}()
+	job, err := h.jobs.Create(id, in.DelUrls)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	err = h.pool.Add(deleter.Task{ID: job.ID, UserID: id, ShortURLs: in.DelUrls, Trace: tracing.Inject(ctx),
+		RequestID: requestid.FromContext(ctx)})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 
-	return nil, nil
+	return &pb.DeleteUserUrlsResponse{JobId: job.ID}, nil
 }
 
-// GetStats gets the amount of all users and URLs in the service.
-// Available only for IP addresses from a trusted subnet.
-func (h *ShortenerGRPCServer) GetStats(ctx context.Context, in *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
+// RestoreUserUrls clears the removal flag for URLs from the request.
+// Only URLs deleted within the restore period can be restored.
+func (h *ShortenerGRPCServer) RestoreUserUrls(ctx context.Context, in *pb.RestoreUserUrlsRequest) (*pb.RestoreUserUrlsResponse, error) {
+	v := ctx.Value(authorizer.UserContextKey)
+	if v == nil {
+		return nil, status.Error(codes.Unauthenticated, "missing user id")
+	}
+	id := v.(int)
+
+	if len(in.Urls) == 0 {
+		return nil, status.Error(codes.DataLoss, "empty request")
+	}
+
+	restored, err := h.stor.RestoreUserURLs(ctx, in.Urls, id, time.Now().Add(-h.cfg.RestorePeriod.Duration))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.RestoreUserUrlsResponse{Restored: restored}, nil
+}
+
+// GetJob gets the state of the user's deletion job.
+func (h *ShortenerGRPCServer) GetJob(ctx context.Context, in *pb.GetJobRequest) (*pb.GetJobResponse, error) {
+	v := ctx.Value(authorizer.UserContextKey)
+	if v == nil {
+		return nil, status.Error(codes.Unauthenticated, "missing user id")
+	}
+	id := v.(int)
+
+	job, ok := h.jobs.Get(in.JobId, id)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "job not found")
+	}
+
+	res := make([]*pb.GetJobResponse_UrlResult, 0, len(job.Results))
+	for _, v := range job.Results {
+		res = append(res, &pb.GetJobResponse_UrlResult{
+			ShortUrl: v.ShortURL,
+			Outcome:  string(v.Outcome),
+			Error:    v.Error,
+		})
+	}
+
+	return &pb.GetJobResponse{
+		JobId:     job.ID,
+		Status:    string(job.Status),
+		Total:     int32(job.Total),
+		Processed: int32(job.Processed),
+		Deleted:   int32(job.Deleted),
+		Failed:    int32(job.Failed),
+		Results:   res,
+		Errors:    job.Errors,
+	}, nil
+}
+
+// checkTrusted checks that the caller is an admin by its client certificate
+// or the X-Real-IP address of the call is in the trusted subnet.
+func (h *ShortenerGRPCServer) checkTrusted(ctx context.Context) error {
+	if authorizer.IsAdmin(ctx) {
+		return nil
+	}
 	if h.cfg.TrustedSubnet == "" {
-		return nil, status.Error(codes.PermissionDenied, "empty trusted subnet")
+		return status.Error(codes.PermissionDenied, "empty trusted subnet")
 	}
 
 	var ipStr string
@@ -192,16 +255,25 @@ func (h *ShortenerGRPCServer) GetStats(ctx context.Context, in *pb.GetStatsReque
 		}
 	}
 	if len(ipStr) == 0 {
-		return nil, status.Error(codes.Internal, "missing IP")
+		return status.Error(codes.Internal, "missing IP")
 	}
 
 	ip := net.ParseIP(ipStr)
 	_, ipNet, err := net.ParseCIDR(h.cfg.TrustedSubnet)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return status.Error(codes.Internal, err.Error())
 	}
 	if !ipNet.Contains(ip) {
-		return nil, status.Error(codes.PermissionDenied, "not trusted IP")
+		return status.Error(codes.PermissionDenied, "not trusted IP")
+	}
+	return nil
+}
+
+// GetStats gets the amount of all users and URLs in the service.
+// Available only for admins and IP addresses from a trusted subnet.
+func (h *ShortenerGRPCServer) GetStats(ctx context.Context, in *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
+	if err := h.checkTrusted(ctx); err != nil {
+		return nil, err
 	}
 
 	stats, err := h.stor.GetStats(ctx)
@@ -209,10 +281,30 @@ func (h *ShortenerGRPCServer) GetStats(ctx context.Context, in *pb.GetStatsReque
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	return &pb.GetStatsResponse{
+	resp := &pb.GetStatsResponse{
 		Urls:  int32(stats.URLs),
 		Users: int32(stats.Users),
-	}, nil
+	}
+	if stats.Pool != nil {
+		resp.Pool = &pb.PoolStats{
+			MaxConns:             stats.Pool.MaxConns,
+			TotalConns:           stats.Pool.TotalConns,
+			IdleConns:            stats.Pool.IdleConns,
+			AcquiredConns:        stats.Pool.AcquiredConns,
+			AcquireCount:         stats.Pool.AcquireCount,
+			EmptyAcquireCount:    stats.Pool.EmptyAcquireCount,
+			CanceledAcquireCount: stats.Pool.CanceledAcquireCount,
+			AcquireDurationNs:    int64(stats.Pool.AcquireDuration),
+		}
+	}
+	if stats.Cache != nil {
+		resp.Cache = &pb.CacheStats{
+			Hits:    stats.Cache.Hits,
+			Misses:  stats.Cache.Misses,
+			Entries: int32(stats.Cache.Entries),
+		}
+	}
+	return resp, nil
 }
 
 // GetPingDB checks storage access.
@@ -4,16 +4,20 @@ import (
 	"context"
 	"errors"
 	"slices"
-	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
 	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/internal/deleter"
+	"github.com/Julia-ivv/shortener-url.git/internal/jobs"
 	pb "github.com/Julia-ivv/shortener-url.git/internal/proto"
 	"github.com/Julia-ivv/shortener-url.git/internal/storage"
 )
@@ -27,6 +31,7 @@ type testURL struct {
 	originURL   string
 	deletedFlag bool
 	userID      int
+	clicks      int64
 }
 
 type testURLs struct {
@@ -34,19 +39,37 @@ type testURLs struct {
 }
 
 func Init() {
-	cfg = *config.NewConfig()
+	cfg = *config.NewConfig(zap.NewNop().Sugar())
 }
 
-func (urls *testURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (err error) {
+func (urls *testURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
 	for _, delURL := range delURLs {
 		for k, curURL := range urls.originalURLs {
 			if (delURL == curURL.shortURL) && (userID == curURL.userID) {
 				urls.originalURLs[k].deletedFlag = true
+				deleted = append(deleted, delURL)
 				break
 			}
 		}
 	}
-	return nil
+	return deleted, nil
+}
+
+func (urls *testURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	for _, shortURL := range shortURLs {
+		for k, curURL := range urls.originalURLs {
+			if (shortURL == curURL.shortURL) && (userID == curURL.userID) && curURL.deletedFlag {
+				urls.originalURLs[k].deletedFlag = false
+				restored = append(restored, shortURL)
+				break
+			}
+		}
+	}
+	return restored, nil
+}
+
+func (urls *testURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	return 0, nil
 }
 
 func (urls *testURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
@@ -67,18 +90,25 @@ func (urls *testURLs) AddURL(ctx context.Context, shortURL string, originURL str
 	return "", nil
 }
 
-func (urls *testURLs) AddBatch(ctx context.Context, shortURLBatch []storage.ResponseBatch, originURLBatch []storage.RequestBatch, userID int) (err error) {
-	allUrls := make([]testURL, len(originURLBatch))
+func (urls *testURLs) AddBatch(ctx context.Context, shortURLBatch []storage.ResponseBatch, originURLBatch []storage.RequestBatch, userID int) (results []storage.BatchResult, err error) {
 	for k, v := range shortURLBatch {
-		allUrls = append(allUrls, testURL{
-			userID:    userID,
-			shortURL:  v.ShortURL,
-			originURL: originURLBatch[k].OriginalURL,
-		})
+		status := storage.BatchCreated
+		for _, u := range urls.originalURLs {
+			if u.userID == userID && u.originURL == originURLBatch[k].OriginalURL {
+				v.ShortURL, status = u.shortURL, storage.BatchExists
+				break
+			}
+		}
+		if status == storage.BatchCreated {
+			urls.originalURLs = append(urls.originalURLs, testURL{
+				userID:    userID,
+				shortURL:  v.ShortURL,
+				originURL: originURLBatch[k].OriginalURL,
+			})
+		}
+		results = append(results, storage.BatchResult{ShortURL: v.ShortURL, Status: status})
 	}
-
-	urls.originalURLs = append(urls.originalURLs, allUrls...)
-	return nil
+	return results, nil
 }
 
 func (urls *testURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []storage.UserURL, err error) {
@@ -95,6 +125,33 @@ func (urls *testURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID
 	return userURLs, nil
 }
 
+func (urls *testURLs) IterateUserURLs(ctx context.Context, userID int, fn func(url storage.URLInfo) error) (err error) {
+	for _, v := range urls.originalURLs {
+		if v.userID == userID {
+			err = fn(storage.URLInfo{
+				ShortURL:    v.shortURL,
+				OriginalURL: v.originURL,
+				UserID:      v.userID,
+				DeletedFlag: v.deletedFlag,
+				Clicks:      v.clicks,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (urls *testURLs) AddClick(ctx context.Context, shortURL string) (err error) {
+	for k, v := range urls.originalURLs {
+		if v.shortURL == shortURL {
+			urls.originalURLs[k].clicks++
+		}
+	}
+	return nil
+}
+
 func (urls *testURLs) PingStor(ctx context.Context) (err error) {
 	if urls == nil {
 		return errors.New("storage storage does not exist")
@@ -143,16 +200,22 @@ func createTestRepo() *testURLs {
 	})
 	return &testURLs{originalURLs: testR}
 }
+
+// newTestPool creates a deletion pool that is not started, tasks are kept in memory.
+func newTestPool(stor storage.Repositories) *deleter.Pool {
+	return deleter.NewPool(stor, jobs.NewTracker(), deleter.NewMemQueue(), 1, zap.NewNop().Sugar())
+}
+
 func TestNewShortenerServer(t *testing.T) {
 	t.Run("create new service", func(t *testing.T) {
-		res := NewShortenerServer(createTestRepo(), cfg, &sync.WaitGroup{})
+		res := NewShortenerServer(createTestRepo(), cfg, newTestPool(nil), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 		assert.NotEmpty(t, res)
 	})
 }
 
 func TestGetUrl(t *testing.T) {
 	testRepo := createTestRepo()
-	testServ := NewShortenerServer(testRepo, cfg, &sync.WaitGroup{})
+	testServ := NewShortenerServer(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	tests := []struct {
 		name      string
 		in        *pb.GetUrlRequest
@@ -199,7 +262,7 @@ func TestGetUrl(t *testing.T) {
 
 func TestPostBatch(t *testing.T) {
 	testRepo := createTestRepo()
-	testServ := NewShortenerServer(testRepo, cfg, &sync.WaitGroup{})
+	testServ := NewShortenerServer(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	tests := []struct {
 		name      string
 		in        *pb.PostBatchRequest
@@ -279,7 +342,7 @@ func TestPostBatch(t *testing.T) {
 
 func TestPostUrl(t *testing.T) {
 	testRepo := createTestRepo()
-	testServ := NewShortenerServer(testRepo, cfg, &sync.WaitGroup{})
+	testServ := NewShortenerServer(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	tests := []struct {
 		name      string
 		in        *pb.PostUrlRequest
@@ -330,7 +393,7 @@ func TestPostUrl(t *testing.T) {
 
 func TestGetUserUrls(t *testing.T) {
 	testRepo := createTestRepo()
-	testServ := NewShortenerServer(testRepo, cfg, &sync.WaitGroup{})
+	testServ := NewShortenerServer(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	tests := []struct {
 		name      string
 		in        *pb.GetUserUrlsRequest
@@ -392,7 +455,7 @@ func TestGetUserUrls(t *testing.T) {
 
 func TestDeleteUserUrls(t *testing.T) {
 	testRepo := createTestRepo()
-	testServ := NewShortenerServer(testRepo, cfg, &sync.WaitGroup{})
+	testServ := NewShortenerServer(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	tests := []struct {
 		name      string
 		in        *pb.DeleteUserUrlsRequest
@@ -442,9 +505,63 @@ func TestDeleteUserUrls(t *testing.T) {
 	}
 }
 
+func TestRestoreUserUrls(t *testing.T) {
+	testRepo := createTestRepo()
+	testRepo.originalURLs[0].deletedFlag = true
+	testServ := NewShortenerServer(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
+	tests := []struct {
+		name      string
+		in        *pb.RestoreUserUrlsRequest
+		res       *pb.RestoreUserUrlsResponse
+		ctx       context.Context
+		wantError bool
+		wantCode  codes.Code
+	}{
+		{
+			name: "ok test",
+			in: &pb.RestoreUserUrlsRequest{
+				Urls: []string{testRepo.originalURLs[0].shortURL},
+			},
+			res:       &pb.RestoreUserUrlsResponse{Restored: []string{testRepo.originalURLs[0].shortURL}},
+			ctx:       context.WithValue(context.Background(), authorizer.UserContextKey, testUserID),
+			wantError: false,
+			wantCode:  codes.OK,
+		},
+		{
+			name:      "missing id",
+			in:        &pb.RestoreUserUrlsRequest{},
+			res:       nil,
+			ctx:       context.Background(),
+			wantError: true,
+			wantCode:  codes.Unauthenticated,
+		},
+		{
+			name:      "empty request",
+			in:        &pb.RestoreUserUrlsRequest{},
+			res:       nil,
+			ctx:       context.WithValue(context.Background(), authorizer.UserContextKey, testUserID),
+			wantError: true,
+			wantCode:  codes.DataLoss,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := testServ.RestoreUserUrls(test.ctx, test.in)
+			if test.wantError {
+				assert.Error(t, err)
+				st, _ := status.FromError(err)
+				assert.Equal(t, test.wantCode, st.Code())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.res.Restored, res.Restored)
+			}
+		})
+	}
+}
+
 func TestGetStats(t *testing.T) {
 	testRepo := createTestRepo()
-	testServ := NewShortenerServer(testRepo, cfg, &sync.WaitGroup{})
+	testServ := NewShortenerServer(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ctxWithMd := metadata.NewIncomingContext(context.Background(),
 		metadata.New(map[string]string{"X-Real-IP": "192.168.0.1"}))
 	trSubn := "192.168.0.0/24"
@@ -496,6 +613,18 @@ func TestGetStats(t *testing.T) {
 			wantError:     true,
 			wantCode:      codes.PermissionDenied,
 		},
+		{
+			name: "admin certificate",
+			in:   &pb.GetStatsRequest{},
+			res: &pb.GetStatsResponse{
+				Urls:  3,
+				Users: 2,
+			},
+			ctx:           context.WithValue(context.Background(), authorizer.AdminContextKey, true),
+			trustedSubnet: "",
+			wantError:     false,
+			wantCode:      codes.OK,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -515,7 +644,7 @@ func TestGetStats(t *testing.T) {
 
 func TestGetPing(t *testing.T) {
 	testRepo := createTestRepo()
-	testServ := NewShortenerServer(testRepo, cfg, &sync.WaitGroup{})
+	testServ := NewShortenerServer(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 
 	t.Run("ok ping", func(t *testing.T) {
 		_, err := testServ.GetPing(context.Background(), nil)
@@ -523,7 +652,7 @@ func TestGetPing(t *testing.T) {
 	})
 
 	testRepo = nil
-	testServ = NewShortenerServer(testRepo, cfg, &sync.WaitGroup{})
+	testServ = NewShortenerServer(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	t.Run("error ping", func(t *testing.T) {
 		_, err := testServ.GetPing(context.Background(), nil)
 		assert.Error(t, err)
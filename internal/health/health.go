@@ -0,0 +1,173 @@
+// Package health reports whether the service is alive and ready to receive requests,
+// over HTTP for the /healthz and /readyz probes and with the standard gRPC health service.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// Results of the checks.
+const (
+	// StatusOK - the check passed.
+	StatusOK = "ok"
+	// StatusFail - the check failed.
+	StatusFail = "fail"
+)
+
+// Names of the readiness checks.
+const (
+	// CheckStorage - the storage is reachable.
+	CheckStorage = "storage"
+	// CheckMigrations - the schema migrations are applied.
+	CheckMigrations = "migrations"
+	// CheckShutdown - the server is not shutting down.
+	CheckShutdown = "shutdown"
+)
+
+// checkTimeout - how long to wait for the storage checks.
+const checkTimeout = 2 * time.Second
+
+// Pinger checks the storage access.
+type Pinger interface {
+	// PingStor returns an error if the storage is not reachable.
+	PingStor(ctx context.Context) error
+}
+
+// Result stores the result of one check.
+type Result struct {
+	// Status - StatusOK or StatusFail.
+	Status string `json:"status"`
+	// Error - why the check failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Report stores the results of the readiness checks.
+type Report struct {
+	// Status - StatusOK if all checks passed.
+	Status string `json:"status"`
+	// Checks - the results by check name.
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Checker runs the liveness and readiness checks.
+type Checker struct {
+	stor     Pinger
+	schema   storage.SchemaChecker
+	draining atomic.Bool
+	grpc     *grpchealth.Server
+}
+
+// NewChecker creates a checker of the storage, schema is nil for storages without migrations.
+func NewChecker(stor Pinger, schema storage.SchemaChecker) *Checker {
+	return &Checker{
+		stor:   stor,
+		schema: schema,
+		grpc:   grpchealth.NewServer(),
+	}
+}
+
+// Drain marks the service as not ready, it is called at the start of the graceful shutdown,
+// so the load balancer stops sending new requests while the current ones are finished.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+	c.grpc.Shutdown()
+}
+
+// Ready checks the storage access, the schema version and the shutdown state.
+func (c *Checker) Ready(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result)}
+	add := func(name string, err error) {
+		if err != nil {
+			report.Status = StatusFail
+			report.Checks[name] = Result{Status: StatusFail, Error: err.Error()}
+			return
+		}
+		report.Checks[name] = Result{Status: StatusOK}
+	}
+
+	if c.draining.Load() {
+		add(CheckShutdown, fmt.Errorf("server is shutting down"))
+	} else {
+		add(CheckShutdown, nil)
+	}
+	add(CheckStorage, c.stor.PingStor(ctx))
+	if c.schema != nil {
+		pending, err := c.schema.PendingMigrations(ctx)
+		if err == nil && pending > 0 {
+			err = fmt.Errorf("%d migrations are not applied", pending)
+		}
+		add(CheckMigrations, err)
+	}
+	return report
+}
+
+// Healthz reports that the process is alive, it does not check the dependencies.
+func (c *Checker) Healthz(res http.ResponseWriter, req *http.Request) {
+	writeReport(res, Report{Status: StatusOK})
+}
+
+// Readyz reports whether the service can receive requests with the results of the checks.
+// It responds with 503 if any check failed.
+func (c *Checker) Readyz(res http.ResponseWriter, req *http.Request) {
+	writeReport(res, c.Ready(req.Context()))
+}
+
+// writeReport writes the report as JSON, the status code is 503 if the report failed.
+func writeReport(res http.ResponseWriter, report Report) {
+	resp, err := json.Marshal(report)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Cache-Control", "no-store")
+	if report.Status != StatusOK {
+		res.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		res.WriteHeader(http.StatusOK)
+	}
+	res.Write(resp)
+}
+
+// RegisterGRPC registers the grpc.health.v1 service on the server.
+// It is called after the other services are registered, they are reported as serving
+// when the readiness checks pass. The empty service name reports the whole server.
+func (c *Checker) RegisterGRPC(srv *grpc.Server) {
+	for name := range srv.GetServiceInfo() {
+		c.grpc.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(srv, &grpcServer{Server: c.grpc, checker: c})
+}
+
+// grpcServer runs the readiness checks on each Check call,
+// Watch reports the serving status that changes on Drain.
+type grpcServer struct {
+	*grpchealth.Server
+	checker *Checker
+}
+
+// Check returns NOT_SERVING if the service is serving but a readiness check failed.
+func (s *grpcServer) Check(ctx context.Context, in *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	resp, err := s.Server.Check(ctx, in)
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return resp, err
+	}
+	if s.checker.Ready(ctx).Status != StatusOK {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return resp, nil
+}
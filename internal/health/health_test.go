@@ -0,0 +1,117 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testStorage returns the configured errors of the checks.
+type testStorage struct {
+	pingErr   error
+	pending   int
+	schemaErr error
+}
+
+func (s testStorage) PingStor(ctx context.Context) error {
+	return s.pingErr
+}
+
+func (s testStorage) PendingMigrations(ctx context.Context) (int, error) {
+	return s.pending, s.schemaErr
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		stor       testStorage
+		drain      bool
+		wantCode   int
+		wantFailed string
+	}{
+		{name: "ready", wantCode: http.StatusOK},
+		{name: "storage not reachable", stor: testStorage{pingErr: errors.New("connection refused")}, wantCode: http.StatusServiceUnavailable, wantFailed: CheckStorage},
+		{name: "migrations pending", stor: testStorage{pending: 2}, wantCode: http.StatusServiceUnavailable, wantFailed: CheckMigrations},
+		{name: "no version table", stor: testStorage{schemaErr: errors.New("relation does not exist")}, wantCode: http.StatusServiceUnavailable, wantFailed: CheckMigrations},
+		{name: "shutting down", drain: true, wantCode: http.StatusServiceUnavailable, wantFailed: CheckShutdown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checker := NewChecker(test.stor, test.stor)
+			if test.drain {
+				checker.Drain()
+			}
+
+			res := httptest.NewRecorder()
+			checker.Readyz(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, test.wantCode, res.Code)
+			assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+
+			var report Report
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), &report))
+			assert.Len(t, report.Checks, 3)
+			for name, result := range report.Checks {
+				if name == test.wantFailed {
+					assert.Equal(t, StatusFail, result.Status)
+					assert.NotEmpty(t, result.Error)
+				} else {
+					assert.Equal(t, StatusOK, result.Status, name)
+				}
+			}
+
+			res = httptest.NewRecorder()
+			checker.Healthz(res, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			assert.Equal(t, http.StatusOK, res.Code, "the process is alive")
+		})
+	}
+
+	t.Run("without migrations", func(t *testing.T) {
+		report := NewChecker(testStorage{}, nil).Ready(context.Background())
+		assert.Equal(t, StatusOK, report.Status)
+		assert.NotContains(t, report.Checks, CheckMigrations)
+	})
+}
+
+func TestGRPCHealth(t *testing.T) {
+	stor := &testStorage{}
+	checker := NewChecker(stor, nil)
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{ServiceName: "test.Shortener", HandlerType: (*interface{})(nil)}, struct{}{})
+	checker.RegisterGRPC(srv)
+	health := &grpcServer{Server: checker.grpc, checker: checker}
+
+	tests := []struct {
+		name    string
+		service string
+		pingErr error
+		drain   bool
+		want    healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{name: "server", want: healthpb.HealthCheckResponse_SERVING},
+		{name: "registered service", service: "test.Shortener", want: healthpb.HealthCheckResponse_SERVING},
+		{name: "storage not reachable", pingErr: errors.New("connection refused"), want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{name: "shutting down", drain: true, want: healthpb.HealthCheckResponse_NOT_SERVING},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stor.pingErr = test.pingErr
+			if test.drain {
+				checker.Drain()
+			}
+			resp, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: test.service})
+			require.NoError(t, err)
+			assert.Equal(t, test.want, resp.GetStatus())
+		})
+	}
+
+	_, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Error(t, err)
+}
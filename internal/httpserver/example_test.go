@@ -7,9 +7,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
-	"sync"
 
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/jobs"
 )
 
 func ExampleHandlers_PostURL() {
@@ -19,7 +21,7 @@ func ExampleHandlers_PostURL() {
 	const userContextKey key = "user"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Post("/", AddContext(hs.PostURL))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
@@ -50,7 +52,7 @@ func ExampleHandlers_PostJSON() {
 	const userContextKey key = "user"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Post(path, AddContext(hs.PostJSON))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
@@ -81,7 +83,7 @@ func ExampleHandlers_PostBatch() {
 	const userContextKey key = "user"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Post(path, AddContext(hs.PostBatch))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
@@ -135,7 +137,7 @@ func ExampleHandlers_GetURL() {
 	const userContextKey key = "user"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Get(path+"{shortURL}", AddContext(hs.GetURL))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
@@ -175,7 +177,7 @@ func ExampleHandlers_GetUserURLs() {
 	const userContextKey key = "user"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Get(path, AddContext(hs.GetUserURLs))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
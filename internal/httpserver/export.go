@@ -0,0 +1,222 @@
+package httpserver
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	mwPkg "github.com/Julia-ivv/shortener-url/pkg/middleware"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// Supported export formats.
+const (
+	exportFormatJSON   = "json"
+	exportFormatNDJSON = "ndjson"
+	exportFormatCSV    = "csv"
+)
+
+// ExportURL stores information about the user's URL for export.
+type ExportURL struct {
+	// ShortURL - short url with the base address.
+	ShortURL string `json:"short_url"`
+	// OriginalURL - original url.
+	OriginalURL string `json:"original_url"`
+	// DeletedFlag - true if the URL has been deleted.
+	DeletedFlag bool `json:"is_deleted"`
+	// CreatedAt - the time the URL was added.
+	CreatedAt time.Time `json:"created_at"`
+	// Clicks - the number of redirects by the short URL.
+	Clicks int64 `json:"clicks"`
+}
+
+// exportEncoder writes exported URLs to the response body.
+type exportEncoder interface {
+	// encode writes one URL.
+	encode(url ExportURL) error
+	// close finishes the output.
+	close() error
+}
+
+// jsonEncoder writes URLs as one JSON array.
+type jsonEncoder struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func (e *jsonEncoder) encode(url ExportURL) error {
+	sep := ","
+	if e.count == 0 {
+		sep = "["
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	return e.enc.Encode(url)
+}
+
+func (e *jsonEncoder) close() error {
+	if e.count == 0 {
+		_, err := io.WriteString(e.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// ndjsonEncoder writes URLs as JSON objects, one per line.
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) encode(url ExportURL) error {
+	return e.enc.Encode(url)
+}
+
+func (e *ndjsonEncoder) close() error {
+	return nil
+}
+
+// csvEncoder writes URLs as CSV rows with a header.
+type csvEncoder struct {
+	wr     *csv.Writer
+	header bool
+}
+
+func (e *csvEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	return e.wr.Write([]string{"short_url", "original_url", "is_deleted", "created_at", "clicks"})
+}
+
+func (e *csvEncoder) encode(url ExportURL) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	createdAt := ""
+	if !url.CreatedAt.IsZero() {
+		createdAt = url.CreatedAt.Format(time.RFC3339)
+	}
+	return e.wr.Write([]string{
+		url.ShortURL,
+		url.OriginalURL,
+		strconv.FormatBool(url.DeletedFlag),
+		createdAt,
+		strconv.FormatInt(url.Clicks, 10),
+	})
+}
+
+func (e *csvEncoder) close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.wr.Flush()
+	return e.wr.Error()
+}
+
+// exportFormat determines the export format
+// by the format query parameter or by the Accept header.
+func exportFormat(req *http.Request) string {
+	if format := req.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	for _, v := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(v))
+		switch mediaType {
+		case "text/csv":
+			return exportFormatCSV
+		case "application/x-ndjson":
+			return exportFormatNDJSON
+		}
+	}
+	return exportFormatJSON
+}
+
+// newExportEncoder creates the encoder of the format writing to w and returns it with its content type.
+func newExportEncoder(format string, w io.Writer) (enc exportEncoder, contentType string, ok bool) {
+	switch format {
+	case exportFormatJSON:
+		return &jsonEncoder{w: w, enc: json.NewEncoder(w)}, "application/json", true
+	case exportFormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, "application/x-ndjson", true
+	case exportFormatCSV:
+		return &csvEncoder{wr: csv.NewWriter(w)}, "text/csv", true
+	}
+	return nil, "", false
+}
+
+// acceptsGzip reports whether the client accepts the gzip compressed response.
+func acceptsGzip(req *http.Request) bool {
+	for _, v := range req.Header.Values("Accept-Encoding") {
+		if strings.Contains(v, "gzip") {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportUserURLs sends all the user's URLs with their metadata in CSV, JSON or NDJSON format.
+// Rows are read from the storage and written to the response one by one.
+// The response is compressed if the client accepts gzip.
+// If the storage fails after the response is started, the response is aborted with mwPkg.Abort,
+// so the client gets an incomplete response instead of a truncated export.
+func (h *Handlers) ExportUserURLs(res http.ResponseWriter, req *http.Request) {
+	value := req.Context().Value(authorizer.UserContextKey)
+	if value == nil {
+		http.Error(res, "500 internal server error", http.StatusInternalServerError)
+		return
+	}
+	id := value.(int)
+
+	format := exportFormat(req)
+	if _, _, ok := newExportEncoder(format, io.Discard); !ok {
+		http.Error(res, "unsupported export format", http.StatusBadRequest)
+		return
+	}
+
+	// The common compression middleware skips requests without a compressible Content-Type or Accept header,
+	// if it has compressed the response, Content-Encoding is already set.
+	var out io.Writer = res
+	if res.Header().Get("Content-Encoding") == "" && acceptsGzip(req) {
+		zw := gzip.NewWriter(res)
+		defer zw.Close()
+		res.Header().Set("Content-Encoding", "gzip")
+		out = zw
+	}
+	enc, contentType, _ := newExportEncoder(format, out)
+
+	res.Header().Add("Vary", "Accept-Encoding")
+	res.Header().Set("Content-Type", contentType)
+	res.Header().Set("Content-Disposition", `attachment; filename="urls.`+format+`"`)
+	res.WriteHeader(http.StatusOK)
+
+	err := h.stor.IterateUserURLs(req.Context(), id, func(url storage.URLInfo) error {
+		return enc.encode(ExportURL{
+			ShortURL:    h.cfg.URL + "/" + url.ShortURL,
+			OriginalURL: url.OriginalURL,
+			DeletedFlag: url.DeletedFlag,
+			CreatedAt:   url.CreatedAt,
+			Clicks:      url.Clicks,
+		})
+	})
+	if err != nil {
+		h.logger(req).Infow("export user URLs", "user ID", id, "error", err)
+		mwPkg.Abort(req, err)
+		return
+	}
+	if err = enc.close(); err != nil {
+		h.logger(req).Infow("export user URLs", "user ID", id, "error", err)
+	}
+}
@@ -0,0 +1,147 @@
+package httpserver
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	mwPkg "github.com/Julia-ivv/shortener-url/pkg/middleware"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/jobs"
+)
+
+func TestHandlerExportUserURLs(t *testing.T) {
+	testR := make([]testURL, 0)
+	testR = append(testR, testURL{
+		userID:    testUserID,
+		shortURL:  "EwH",
+		originURL: "https://practicum.yandex.ru/",
+		clicks:    3,
+	})
+	testR = append(testR, testURL{
+		userID:      testUserID,
+		shortURL:    "Eorp",
+		originURL:   "https://yandex.ru/",
+		deletedFlag: true,
+	})
+	testR = append(testR, testURL{
+		userID:    456,
+		shortURL:  "Ert",
+		originURL: "https://mail.ru/",
+	})
+	testRepo := &testURLs{originalURLs: testR}
+
+	// aborted receives the abort error seen by the middleware after the handler.
+	aborted := make(chan error, 1)
+	router := chi.NewRouter()
+	router.Use(mwPkg.HandlerWithAbort, func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			h.ServeHTTP(res, req)
+			if err := mwPkg.AbortError(req.Context()); err != nil {
+				aborted <- err
+			}
+		})
+	})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	t.Run("without context", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		hs.ExportUserURLs(w, httptest.NewRequest("GET", ts.URL+"/api/user/urls/export", nil))
+		res := w.Result()
+		defer res.Body.Close()
+		assert.Equal(t, 500, res.StatusCode)
+	})
+
+	router.Get("/api/user/urls/export", AddContext(hs.ExportUserURLs))
+	path := "/api/user/urls/export"
+
+	t.Run("json", func(t *testing.T) {
+		resp, body := testRequest(t, ts, "GET", path+"?format=json", nil, testUserID)
+		defer resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		var urls []ExportURL
+		require.NoError(t, json.Unmarshal([]byte(body), &urls))
+		if assert.Equal(t, 2, len(urls)) {
+			assert.Equal(t, int64(3), urls[0].Clicks)
+			assert.True(t, urls[1].DeletedFlag)
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		resp, body := testRequest(t, ts, "GET", path+"?format=ndjson", nil, testUserID)
+		defer resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, 2, len(strings.Split(strings.TrimSpace(body), "\n")))
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		resp, body := testRequest(t, ts, "GET", path+"?format=csv", nil, testUserID)
+		defer resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+		records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, 3, len(records))
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		resp, _ := testRequest(t, ts, "GET", path+"?format=xml", nil, testUserID)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("gzip csv", func(t *testing.T) {
+		req, err := http.NewRequest("GET", ts.URL+path+"?format=csv", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+		zr, err := gzip.NewReader(resp.Body)
+		require.NoError(t, err)
+		records, err := csv.NewReader(zr).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, 3, len(records))
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		testRepo.iterateErr = errors.New("storage is unavailable")
+		defer func() { testRepo.iterateErr = nil }()
+		req, err := http.NewRequest("GET", ts.URL+path+"?format=ndjson", nil)
+		require.NoError(t, err)
+		// The client retries a failed request on a reused connection, so a new one is used.
+		ts.Client().CloseIdleConnections()
+		// The connection is closed before or after the status is received, depending on buffering.
+		resp, err := ts.Client().Do(req)
+		if err == nil {
+			defer resp.Body.Close()
+			_, err = io.ReadAll(resp.Body)
+		}
+		assert.Error(t, err, "the response is aborted")
+		assert.ErrorIs(t, <-aborted, testRepo.iterateErr, "the middleware sees the error before the connection is closed")
+	})
+
+	testRepo.originalURLs = nil
+	t.Run("empty json", func(t *testing.T) {
+		resp, body := testRequest(t, ts, "GET", path, nil, testUserID)
+		defer resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.JSONEq(t, "[]", body)
+	})
+}
@@ -6,37 +6,52 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
 
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
 	mwPkg "github.com/Julia-ivv/shortener-url/pkg/middleware"
 
 	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
 	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/internal/deleter"
+	"github.com/Julia-ivv/shortener-url.git/internal/health"
+	"github.com/Julia-ivv/shortener-url.git/internal/jobs"
+	"github.com/Julia-ivv/shortener-url.git/internal/metrics"
 	mwInt "github.com/Julia-ivv/shortener-url.git/internal/middleware"
+	"github.com/Julia-ivv/shortener-url.git/internal/requestid"
 	"github.com/Julia-ivv/shortener-url.git/internal/storage"
-	"github.com/Julia-ivv/shortener-url.git/pkg/randomizer"
+	"github.com/Julia-ivv/shortener-url.git/internal/tracing"
 )
 
 // Handlers stores the repository and settings of this application.
 type Handlers struct {
 	stor storage.Repositories
 	cfg  config.Flags
-	wg   *sync.WaitGroup
+	pool *deleter.Pool
+	jobs *jobs.Tracker
+	log  *zap.SugaredLogger
 }
 
 // NewHandlers creates an instance with storage and settings for handlers.
-func NewHandlers(stor storage.Repositories, cfg config.Flags, wg *sync.WaitGroup) *Handlers {
+// log is used for the requests whose context has no logger.
+func NewHandlers(stor storage.Repositories, cfg config.Flags, pool *deleter.Pool, tracker *jobs.Tracker, log *zap.SugaredLogger) *Handlers {
 	h := &Handlers{}
 	h.stor = stor
 	h.cfg = cfg
-	h.wg = wg
+	h.pool = pool
+	h.jobs = tracker
+	h.log = log
 	return h
 }
 
+// logger returns the logger of the request.
+func (h *Handlers) logger(req *http.Request) *zap.SugaredLogger {
+	return logger.FromContextOr(req.Context(), h.log)
+}
+
 // PostURL gets a long URL from the request body.
 // Adds it to storage, returns a short URL in the response body.
 func (h *Handlers) PostURL(res http.ResponseWriter, req *http.Request) {
@@ -57,18 +72,12 @@ func (h *Handlers) PostURL(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	shortURL, err := randomizer.GenerateRandomString(randomizer.LengthShortURL)
-	if err != nil {
-		http.Error(res, "500 internal server error", http.StatusInternalServerError)
-		return
-	}
-	findURL, err := h.stor.AddURL(req.Context(), shortURL, string(postURL), id)
+	shortURL, err := storage.AddNewURL(req.Context(), h.stor, string(postURL), id)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		if errors.Is(err, storage.ErrConflict) {
 			res.Header().Set("Content-Type", "text/plain")
 			res.WriteHeader(http.StatusConflict)
-			_, err = res.Write([]byte(h.cfg.URL + "/" + findURL))
+			_, err = res.Write([]byte(h.cfg.URL + "/" + shortURL))
 			if err != nil {
 				http.Error(res, err.Error(), http.StatusBadRequest)
 				return
@@ -124,18 +133,11 @@ func (h *Handlers) PostJSON(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	shortURL, err := randomizer.GenerateRandomString(randomizer.LengthShortURL)
-	if err != nil {
-		http.Error(res, "500 internal server error", http.StatusInternalServerError)
-		return
-	}
-	findURL, err := h.stor.AddURL(req.Context(), shortURL, string(reqURL.URL), id)
+	shortURL, err := storage.AddNewURL(req.Context(), h.stor, string(reqURL.URL), id)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		if errors.Is(err, storage.ErrConflict) {
 			res.Header().Set("Content-Type", "application/json")
 			res.WriteHeader(http.StatusConflict)
-			shortURL = findURL
 		} else {
 			http.Error(res, err.Error(), http.StatusInternalServerError)
 			return
@@ -159,6 +161,7 @@ func (h *Handlers) PostJSON(res http.ResponseWriter, req *http.Request) {
 
 // PostBatch gets a slice of the original URLs from the request body.
 // Adds it to storage, returns a slice of the short URLs in the response body.
+// URLs already shortened by the user get the existing short URL and the "exists" status.
 func (h *Handlers) PostBatch(res http.ResponseWriter, req *http.Request) {
 	value := req.Context().Value(authorizer.UserContextKey)
 	if value == nil {
@@ -188,23 +191,18 @@ func (h *Handlers) PostBatch(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	resBatch := make([]storage.ResponseBatch, len(reqBatch))
-	for k, v := range reqBatch {
-		shortURL, err := randomizer.GenerateRandomString(randomizer.LengthShortURL)
-		if err != nil {
-			http.Error(res, "500 internal server error", http.StatusInternalServerError)
-			return
-		}
-		resBatch[k].CorrelationID = v.CorrelationID
-		resBatch[k].ShortURLFull = h.cfg.URL + "/" + shortURL
-		resBatch[k].ShortURL = shortURL
-	}
-
-	err = h.stor.AddBatch(req.Context(), resBatch, reqBatch, id)
+	results, err := storage.AddNewBatch(req.Context(), h.stor, reqBatch, id)
 	if err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	resBatch := make([]storage.ResponseBatch, len(reqBatch))
+	for k, v := range results {
+		resBatch[k].CorrelationID = reqBatch[k].CorrelationID
+		resBatch[k].ShortURLFull = h.cfg.URL + "/" + v.ShortURL
+		resBatch[k].ShortURL = v.ShortURL
+		resBatch[k].Status = v.Status
+	}
 
 	resp, err := json.Marshal(resBatch)
 	if err != nil {
@@ -227,13 +225,19 @@ func (h *Handlers) GetURL(res http.ResponseWriter, req *http.Request) {
 	shortURL := chi.URLParam(req, "shortURL")
 	originURL, isDel, ok := h.stor.GetURL(req.Context(), shortURL)
 	if !ok {
+		metrics.Redirect(metrics.RedirectNotFound)
 		http.Error(res, "URL not found", http.StatusBadRequest)
 		return
 	}
 	if isDel {
+		metrics.Redirect(metrics.RedirectDeleted)
 		res.WriteHeader(http.StatusGone)
 		return
 	}
+	metrics.Redirect(metrics.RedirectFound)
+	if err := h.stor.AddClick(req.Context(), shortURL); err != nil {
+		h.logger(req).Infow("add click", "short URL", shortURL, "error", err)
+	}
 	res.Header().Set("Location", originURL)
 	res.Header().Set("Content-Type", "text/plain")
 	res.WriteHeader(http.StatusTemporaryRedirect)
@@ -282,7 +286,13 @@ func (h *Handlers) GetUserURLs(res http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// ResponseJob stores the ID of the deletion job for the handler DeleteUserURLs.
+type ResponseJob struct {
+	JobID string `json:"job_id"`
+}
+
 // DeleteUserURLs adds a removal flag for URLs from the request body.
+// Deletion is performed asynchronously, the response contains the job ID to track it.
 func (h *Handlers) DeleteUserURLs(res http.ResponseWriter, req *http.Request) {
 	value := req.Context().Value(authorizer.UserContextKey)
 	if value == nil {
@@ -306,22 +316,123 @@ func (h *Handlers) DeleteUserURLs(res http.ResponseWriter, req *http.Request) {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	h.wg.Add(1)
-	go func() {
-		h.stor.DeleteUserURLs(req.Context(), reqShortURLs, id)
-		h.wg.Done()
-	}()
 
+	job, err := h.jobs.Create(id, reqShortURLs)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = h.pool.Add(deleter.Task{ID: job.ID, UserID: id, ShortURLs: reqShortURLs, Trace: tracing.Inject(req.Context()),
+		RequestID: requestid.FromContext(req.Context())})
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(ResponseJob{JobID: job.ID})
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Location", "/api/user/jobs/"+job.ID)
 	res.WriteHeader(http.StatusAccepted)
+	_, err = res.Write(resp)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
 
-// GetStats gets the amount of all users and URLs in the service.
-// Available only for IP addresses from a trusted subnet.
-func (h *Handlers) GetStats(res http.ResponseWriter, req *http.Request) {
+// ResponseRestore stores the restored short URLs for the handler RestoreUserURLs.
+type ResponseRestore struct {
+	Restored []string `json:"restored"`
+}
+
+// RestoreUserURLs clears the removal flag for URLs from the request body.
+// Only URLs deleted within the restore period can be restored.
+func (h *Handlers) RestoreUserURLs(res http.ResponseWriter, req *http.Request) {
+	value := req.Context().Value(authorizer.UserContextKey)
+	if value == nil {
+		http.Error(res, "500 internal server error", http.StatusInternalServerError)
+		return
+	}
+	id := value.(int)
+
+	reqJSON, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(reqJSON) == 0 {
+		http.Error(res, "request with empty body", http.StatusBadRequest)
+		return
+	}
+	var reqShortURLs []string
+	err = json.Unmarshal(reqJSON, &reqShortURLs)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	restored, err := h.stor.RestoreUserURLs(req.Context(), reqShortURLs, id, time.Now().Add(-h.cfg.RestorePeriod.Duration))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if restored == nil {
+		restored = []string{}
+	}
+
+	resp, err := json.Marshal(ResponseRestore{Restored: restored})
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	_, err = res.Write(resp)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetJob gets the state of the user's deletion job.
+func (h *Handlers) GetJob(res http.ResponseWriter, req *http.Request) {
+	value := req.Context().Value(authorizer.UserContextKey)
+	if value == nil {
+		http.Error(res, "500 internal server error", http.StatusInternalServerError)
+		return
+	}
+	id := value.(int)
+
+	job, ok := h.jobs.Get(chi.URLParam(req, "jobID"), id)
+	if !ok {
+		http.Error(res, "job not found", http.StatusNotFound)
+		return
+	}
+
+	resp, err := json.Marshal(job)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	_, err = res.Write(resp)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// checkTrusted checks that the X-Real-IP address of the request is in the trusted subnet.
+// If not, writes the error to the response and returns false.
+func (h *Handlers) checkTrusted(res http.ResponseWriter, req *http.Request) bool {
 	if h.cfg.TrustedSubnet == "" {
 		http.Error(res, "403 Forbidden, empty trusted subnet", http.StatusForbidden)
-		return
+		return false
 	}
 
 	ipStr := req.Header.Get("X-Real-IP")
@@ -329,10 +440,31 @@ func (h *Handlers) GetStats(res http.ResponseWriter, req *http.Request) {
 	_, ipNet, err := net.ParseCIDR(h.cfg.TrustedSubnet)
 	if err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
-		return
+		return false
 	}
 	if !ipNet.Contains(ip) {
 		http.Error(res, "403 Forbidden, not trusted IP", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// LogLevel returns the handler that gets and changes the log level at runtime:
+// GET returns {"level":"info"}, PUT with {"level":"debug"} sets the level.
+// Available only for IP addresses from a trusted subnet.
+func (h *Handlers) LogLevel(level http.Handler) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if !h.checkTrusted(res, req) {
+			return
+		}
+		level.ServeHTTP(res, req)
+	}
+}
+
+// GetStats gets the amount of all users and URLs in the service.
+// Available only for IP addresses from a trusted subnet.
+func (h *Handlers) GetStats(res http.ResponseWriter, req *http.Request) {
+	if !h.checkTrusted(res, req) {
 		return
 	}
 
@@ -358,20 +490,31 @@ func (h *Handlers) GetStats(res http.ResponseWriter, req *http.Request) {
 }
 
 // NewURLRouter creates a router instance.
-func NewURLRouter(repo storage.Repositories, cfg config.Flags, wg *sync.WaitGroup) chi.Router {
-	hs := NewHandlers(repo, cfg, wg)
+// log is passed to the handlers and to the middleware in the request context,
+// level gets and sets its level at /api/internal/log/level,
+// checker serves the /healthz and /readyz probes.
+func NewURLRouter(repo storage.Repositories, cfg config.Flags, pool *deleter.Pool, tracker *jobs.Tracker, log *zap.SugaredLogger, level http.Handler, checker *health.Checker) chi.Router {
+	hs := NewHandlers(repo, cfg, pool, tracker, log)
 	r := chi.NewRouter()
-	r.Use(mwPkg.HandlerWithLogging, mwPkg.HandlerWithGzipCompression)
+	r.Use(mwPkg.HandlerWithAbort, mwInt.HandlerWithLogger(log), mwInt.HandlerWithRequestID, mwInt.HandlerWithMetrics, mwInt.HandlerWithTracing, mwPkg.HandlerWithLogging, mwPkg.HandlerWithGzipCompression)
 	r.Group(func(r chi.Router) {
 		r.Use(mwInt.HandlerWithAuth)
 		r.Post("/", hs.PostURL)
 		r.Get("/{shortURL}", hs.GetURL)
 		r.Post("/api/shorten", hs.PostJSON)
 		r.Post("/api/shorten/batch", hs.PostBatch)
+		r.Post("/api/shorten/import", hs.PostImport)
 		r.Get("/api/user/urls", hs.GetUserURLs)
+		r.Get("/api/user/urls/export", hs.ExportUserURLs)
 		r.Delete("/api/user/urls", hs.DeleteUserURLs)
+		r.Post("/api/user/urls/restore", hs.RestoreUserURLs)
+		r.Get("/api/user/jobs/{jobID}", hs.GetJob)
 	})
 	r.Get("/ping", hs.GetPingDB)
+	r.Get("/healthz", checker.Healthz)
+	r.Get("/readyz", checker.Readyz)
 	r.Get("/api/internal/stats", hs.GetStats)
+	r.Get("/api/internal/log/level", hs.LogLevel(level))
+	r.Put("/api/internal/log/level", hs.LogLevel(level))
 	return r
 }
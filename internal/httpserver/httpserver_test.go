@@ -2,6 +2,7 @@ package httpserver
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -9,15 +10,21 @@ import (
 	"slices"
 	"strconv"
 	"strings"
-	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
 
 	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
 	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/internal/deleter"
+	"github.com/Julia-ivv/shortener-url.git/internal/health"
+	"github.com/Julia-ivv/shortener-url.git/internal/jobs"
 	"github.com/Julia-ivv/shortener-url.git/internal/storage"
 )
 
@@ -27,7 +34,7 @@ var inc int
 var cfg config.Flags
 
 func Init() {
-	cfg = *config.NewConfig()
+	cfg = *config.NewConfig(zap.NewNop().Sugar())
 }
 
 type testURL struct {
@@ -35,22 +42,45 @@ type testURL struct {
 	originURL   string
 	deletedFlag bool
 	userID      int
+	clicks      int64
 }
 
 type testURLs struct {
 	originalURLs []testURL
+	// iterateErr is returned by IterateUserURLs after the first URL.
+	iterateErr error
+	// batchErr is returned by AddBatch.
+	batchErr error
 }
 
-func (urls *testURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (err error) {
+func (urls *testURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
 	for _, delURL := range delURLs {
 		for k, curURL := range urls.originalURLs {
 			if (delURL == curURL.shortURL) && (userID == curURL.userID) {
 				urls.originalURLs[k].deletedFlag = true
+				deleted = append(deleted, delURL)
 				break
 			}
 		}
 	}
-	return nil
+	return deleted, nil
+}
+
+func (urls *testURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	for _, shortURL := range shortURLs {
+		for k, curURL := range urls.originalURLs {
+			if (shortURL == curURL.shortURL) && (userID == curURL.userID) && curURL.deletedFlag {
+				urls.originalURLs[k].deletedFlag = false
+				restored = append(restored, shortURL)
+				break
+			}
+		}
+	}
+	return restored, nil
+}
+
+func (urls *testURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	return 0, nil
 }
 
 func (urls *testURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
@@ -73,18 +103,28 @@ func (urls *testURLs) AddURL(ctx context.Context, shortURL string, originURL str
 	return "", nil
 }
 
-func (urls *testURLs) AddBatch(ctx context.Context, shortURLBatch []storage.ResponseBatch, originURLBatch []storage.RequestBatch, userID int) (err error) {
-	allUrls := make([]testURL, len(originURLBatch))
+func (urls *testURLs) AddBatch(ctx context.Context, shortURLBatch []storage.ResponseBatch, originURLBatch []storage.RequestBatch, userID int) (results []storage.BatchResult, err error) {
+	if urls.batchErr != nil {
+		return nil, urls.batchErr
+	}
 	for k, v := range shortURLBatch {
-		allUrls = append(allUrls, testURL{
-			userID:    userID,
-			shortURL:  v.ShortURL,
-			originURL: originURLBatch[k].OriginalURL,
-		})
+		status := storage.BatchCreated
+		for _, u := range urls.originalURLs {
+			if u.userID == userID && u.originURL == originURLBatch[k].OriginalURL {
+				v.ShortURL, status = u.shortURL, storage.BatchExists
+				break
+			}
+		}
+		if status == storage.BatchCreated {
+			urls.originalURLs = append(urls.originalURLs, testURL{
+				userID:    userID,
+				shortURL:  v.ShortURL,
+				originURL: originURLBatch[k].OriginalURL,
+			})
+		}
+		results = append(results, storage.BatchResult{ShortURL: v.ShortURL, Status: status})
 	}
-
-	urls.originalURLs = append(urls.originalURLs, allUrls...)
-	return nil
+	return results, nil
 }
 
 func (urls *testURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []storage.UserURL, err error) {
@@ -101,6 +141,36 @@ func (urls *testURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID
 	return userURLs, nil
 }
 
+func (urls *testURLs) IterateUserURLs(ctx context.Context, userID int, fn func(url storage.URLInfo) error) (err error) {
+	for _, v := range urls.originalURLs {
+		if v.userID == userID {
+			err = fn(storage.URLInfo{
+				ShortURL:    v.shortURL,
+				OriginalURL: v.originURL,
+				UserID:      v.userID,
+				DeletedFlag: v.deletedFlag,
+				Clicks:      v.clicks,
+			})
+			if err != nil {
+				return err
+			}
+			if urls.iterateErr != nil {
+				return urls.iterateErr
+			}
+		}
+	}
+	return nil
+}
+
+func (urls *testURLs) AddClick(ctx context.Context, shortURL string) (err error) {
+	for k, v := range urls.originalURLs {
+		if v.shortURL == shortURL {
+			urls.originalURLs[k].clicks++
+		}
+	}
+	return nil
+}
+
 func (urls *testURLs) PingStor(ctx context.Context) (err error) {
 	if urls == nil {
 		return errors.New("storage storage does not exist")
@@ -177,11 +247,16 @@ func benchRequest(ts *httptest.Server, method, path string, body io.Reader, user
 	return nil
 }
 
+// newTestPool creates a deletion pool that is not started, tasks are kept in memory.
+func newTestPool(stor storage.Repositories) *deleter.Pool {
+	return deleter.NewPool(stor, jobs.NewTracker(), deleter.NewMemQueue(), 1, zap.NewNop().Sugar())
+}
+
 func TestHandlerPostURL(t *testing.T) {
 	testRepo := &testURLs{originalURLs: make([]testURL, 0)}
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -255,7 +330,7 @@ func TestHandlerGetURL(t *testing.T) {
 	testRepo := &testURLs{originalURLs: testR}
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -319,7 +394,7 @@ func TestHandlerDeleteUserURLs(t *testing.T) {
 	testRepo := &testURLs{originalURLs: testR}
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -367,11 +442,120 @@ func TestHandlerDeleteUserURLs(t *testing.T) {
 	}
 }
 
+func TestHandlerRestoreUserURLs(t *testing.T) {
+	path := "/api/user/urls/restore"
+	testRepo := &testURLs{originalURLs: []testURL{
+		{
+			userID:      testUserID,
+			shortURL:    "EwH",
+			originURL:   "https://practicum.yandex.ru/",
+			deletedFlag: true,
+		},
+	}}
+
+	router := chi.NewRouter()
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
+	router.Post(path, AddContext(hs.RestoreUserURLs))
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "restore",
+			body:       `["EwH"]`,
+			wantStatus: 200,
+			wantBody:   `{"restored":["EwH"]}`,
+		},
+		{
+			name:       "nothing to restore",
+			body:       `["EwH"]`,
+			wantStatus: 200,
+			wantBody:   `{"restored":[]}`,
+		},
+		{
+			name:       "empty body",
+			body:       "",
+			wantStatus: 400,
+		},
+		{
+			name:       "bad body",
+			body:       "ghgh",
+			wantStatus: 400,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, body := testRequest(t, ts, "POST", path, strings.NewReader(test.body), testUserID)
+			defer resp.Body.Close()
+			assert.Equal(t, test.wantStatus, resp.StatusCode)
+			if test.wantBody != "" {
+				assert.JSONEq(t, test.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestHandlerGetJob(t *testing.T) {
+	tracker := jobs.NewTracker()
+	job, err := tracker.Create(testUserID, []string{"EwH"})
+	require.NoError(t, err)
+	otherJob, err := tracker.Create(88, []string{"Ert"})
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	hs := NewHandlers(&testURLs{}, cfg, newTestPool(nil), tracker, zaptest.NewLogger(t).Sugar())
+	router.Get("/api/user/jobs/{jobID}", AddContext(hs.GetJob))
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		jobID      string
+		wantStatus int
+	}{
+		{
+			name:       "job exists",
+			jobID:      job.ID,
+			wantStatus: 200,
+		},
+		{
+			name:       "job of other user",
+			jobID:      otherJob.ID,
+			wantStatus: 404,
+		},
+		{
+			name:       "unknown job",
+			jobID:      "unknown",
+			wantStatus: 404,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, body := testRequest(t, ts, "GET", "/api/user/jobs/"+test.jobID, nil, testUserID)
+			defer resp.Body.Close()
+			assert.Equal(t, test.wantStatus, resp.StatusCode)
+			if test.wantStatus == 200 {
+				var got jobs.Job
+				require.NoError(t, json.Unmarshal([]byte(body), &got))
+				assert.Equal(t, test.jobID, got.ID)
+				assert.Equal(t, 1, got.Total)
+			}
+		})
+	}
+}
+
 func TestHandlerPostJSON(t *testing.T) {
 	testRepo := &testURLs{originalURLs: make([]testURL, 0)}
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -432,7 +616,7 @@ func TestHandlerGetUserURLs(t *testing.T) {
 	testRepo := &testURLs{originalURLs: nil}
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -498,7 +682,7 @@ func TestHandlerPostBatch(t *testing.T) {
 	testRepo := &testURLs{originalURLs: make([]testURL, 0)}
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -591,12 +775,30 @@ func TestHandlerPostBatch(t *testing.T) {
 			assert.True(t, assert.NotEmpty(t, getBody))
 		})
 	}
+
+	t.Run("batch with existing URL", func(t *testing.T) {
+		body := `[
+			{"correlation_id": "ind3", "original_url": "https://pract.ru/url1"},
+			{"correlation_id": "ind4", "original_url": "https://pract.ru/url3"}
+		]`
+		resp, getBody := testRequest(t, ts, "POST", "/api/shorten/batch", strings.NewReader(body), testUserID)
+		defer resp.Body.Close()
+		assert.Equal(t, 201, resp.StatusCode)
+
+		var got []storage.ResponseBatch
+		require.NoError(t, json.Unmarshal([]byte(getBody), &got))
+		require.Len(t, got, 2)
+		assert.Equal(t, "ind3", got[0].CorrelationID)
+		assert.Equal(t, storage.BatchExists, got[0].Status)
+		assert.Equal(t, "ind4", got[1].CorrelationID)
+		assert.Equal(t, storage.BatchCreated, got[1].Status)
+	})
 }
 
 func TestPing(t *testing.T) {
 	testRepo := &testURLs{originalURLs: make([]testURL, 0)}
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -609,7 +811,7 @@ func TestPing(t *testing.T) {
 	})
 
 	testRepo = nil
-	hs = NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs = NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ts = httptest.NewServer(router)
 	defer ts.Close()
 	t.Run("no ping", func(t *testing.T) {
@@ -624,7 +826,7 @@ func TestPing(t *testing.T) {
 func TestNewURLRouter(t *testing.T) {
 	testRepo := &testURLs{originalURLs: make([]testURL, 0)}
 	t.Run("create router", func(t *testing.T) {
-		res := NewURLRouter(testRepo, cfg, &sync.WaitGroup{})
+		res := NewURLRouter(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar(), zap.NewAtomicLevel(), health.NewChecker(testRepo, nil))
 		assert.NotEmpty(t, res)
 	})
 }
@@ -646,7 +848,7 @@ func TestHandlerGetStats(t *testing.T) {
 	testRepo := &testURLs{originalURLs: testR}
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
@@ -704,12 +906,77 @@ func TestHandlerGetStats(t *testing.T) {
 	}
 }
 
+func TestHandlerLogLevel(t *testing.T) {
+	testRepo := &testURLs{originalURLs: make([]testURL, 0)}
+	level := zap.NewAtomicLevel()
+
+	router := chi.NewRouter()
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	path := "/api/internal/log/level"
+	router.Get(path, hs.LogLevel(level))
+	router.Put(path, hs.LogLevel(level))
+	tests := []struct {
+		name          string
+		method        string
+		body          string
+		trustedSubnet string
+		wantCode      int
+		wantLevel     zapcore.Level
+	}{
+		{
+			name:          "get level",
+			method:        http.MethodGet,
+			trustedSubnet: "192.168.0.0/24",
+			wantCode:      http.StatusOK,
+			wantLevel:     zapcore.InfoLevel,
+		},
+		{
+			name:          "set level",
+			method:        http.MethodPut,
+			body:          `{"level":"debug"}`,
+			trustedSubnet: "192.168.0.0/24",
+			wantCode:      http.StatusOK,
+			wantLevel:     zapcore.DebugLevel,
+		},
+		{
+			name:          "unknown level",
+			method:        http.MethodPut,
+			body:          `{"level":"loud"}`,
+			trustedSubnet: "192.168.0.0/24",
+			wantCode:      http.StatusBadRequest,
+			wantLevel:     zapcore.DebugLevel,
+		},
+		{
+			name:          "not trusted IP",
+			method:        http.MethodPut,
+			body:          `{"level":"error"}`,
+			trustedSubnet: "192.168.1.0/24",
+			wantCode:      http.StatusForbidden,
+			wantLevel:     zapcore.DebugLevel,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hs.cfg.TrustedSubnet = test.trustedSubnet
+			resp, body := testRequest(t, ts, test.method, path, strings.NewReader(test.body), testUserID)
+			assert.Equal(t, test.wantCode, resp.StatusCode)
+			assert.Equal(t, test.wantLevel, level.Level())
+			if test.wantCode == http.StatusOK {
+				assert.JSONEq(t, `{"level":"`+test.wantLevel.String()+`"}`, body)
+			}
+		})
+	}
+}
+
 func BenchmarkPostURL(b *testing.B) {
 	testRepo := &testURLs{originalURLs: make([]testURL, 0)}
 	path := "/"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Post(path, AddContext(hs.PostURL))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
@@ -727,7 +994,7 @@ func BenchmarkPostJSON(b *testing.B) {
 	path := "/api/shorten"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Post(path, AddContext(hs.PostJSON))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
@@ -745,7 +1012,7 @@ func BenchmarkPostBatch(b *testing.B) {
 	path := "/api/shorten/batch"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Post(path, AddContext(hs.PostBatch))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
@@ -787,7 +1054,7 @@ func BenchmarkGetURL(b *testing.B) {
 	path := "/"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Get(path+"{shortURL}", AddContext(hs.GetURL))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
@@ -811,7 +1078,7 @@ func BenchmarkGetUserURLs(b *testing.B) {
 	path := "/api/user/urls"
 
 	router := chi.NewRouter()
-	hs := NewHandlers(testRepo, cfg, &sync.WaitGroup{})
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zap.NewNop().Sugar())
 	router.Get(path, AddContext(hs.GetUserURLs))
 	ts := httptest.NewServer(router)
 	defer ts.Close()
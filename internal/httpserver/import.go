@@ -0,0 +1,283 @@
+package httpserver
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// Import settings.
+const (
+	// importChunkSize - the number of rows added to the storage at once.
+	importChunkSize = 500
+	// importMaxLineSize - the maximum length of one NDJSON line.
+	importMaxLineSize = 1 << 20
+)
+
+// Supported import formats.
+const (
+	importFormatNDJSON = "ndjson"
+	importFormatCSV    = "csv"
+)
+
+// Codes of the import row errors.
+const (
+	// importErrInvalidURL - the row cannot be decoded or has no original URL.
+	importErrInvalidURL = "invalid_url"
+	// importErrConflict - a short URL could not be assigned to the original URL.
+	importErrConflict = "conflict"
+	// importErrInternal - the storage or the request body failed.
+	importErrInternal = "internal"
+)
+
+// ImportResult stores the result of importing one row for the streaming response.
+type ImportResult struct {
+	// Line - the number of the row in the request body, starting with 1.
+	Line int `json:"line"`
+	// CorrelationID - URL ID for correlation with the request.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// ShortURL - short url, empty if the row was not imported.
+	ShortURL string `json:"short_url,omitempty"`
+	// Status - "created" or "exists" if the URL was already shortened by the user.
+	Status string `json:"status,omitempty"`
+	// Error - the code of the reason why the row was not imported: invalid_url, conflict or internal.
+	Error string `json:"error,omitempty"`
+}
+
+// rowError is a decoding error of a single row, the rest of the stream can still be read.
+type rowError struct {
+	err error
+}
+
+// Error returns the error text.
+func (e *rowError) Error() string {
+	return e.err.Error()
+}
+
+// importDecoder reads rows one by one from the request body.
+type importDecoder interface {
+	// next returns the next row and its line number, io.EOF at the end of the stream.
+	next() (row storage.RequestBatch, line int, err error)
+}
+
+// ndjsonDecoder reads rows in NDJSON format, one JSON object per line.
+type ndjsonDecoder struct {
+	scan *bufio.Scanner
+	line int
+}
+
+func newNDJSONDecoder(r io.Reader) *ndjsonDecoder {
+	scan := bufio.NewScanner(r)
+	scan.Buffer(make([]byte, 0, 64*1024), importMaxLineSize)
+	return &ndjsonDecoder{scan: scan}
+}
+
+func (d *ndjsonDecoder) next() (row storage.RequestBatch, line int, err error) {
+	for d.scan.Scan() {
+		d.line++
+		data := d.scan.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+		if err = json.Unmarshal(data, &row); err != nil {
+			return storage.RequestBatch{}, d.line, &rowError{err: err}
+		}
+		return row, d.line, nil
+	}
+	if err = d.scan.Err(); err != nil {
+		return storage.RequestBatch{}, d.line + 1, err
+	}
+	return storage.RequestBatch{}, d.line, io.EOF
+}
+
+// csvDecoder reads rows in CSV format: "correlation_id,original_url" or "original_url".
+// The header row is optional.
+type csvDecoder struct {
+	rd    *csv.Reader
+	line  int
+	first bool
+}
+
+func newCSVDecoder(r io.Reader) *csvDecoder {
+	rd := csv.NewReader(r)
+	rd.FieldsPerRecord = -1
+	rd.TrimLeadingSpace = true
+	return &csvDecoder{rd: rd, first: true}
+}
+
+func (d *csvDecoder) next() (row storage.RequestBatch, line int, err error) {
+	for {
+		record, errRead := d.rd.Read()
+		if errRead != nil {
+			var parseErr *csv.ParseError
+			if errors.As(errRead, &parseErr) {
+				return storage.RequestBatch{}, parseErr.StartLine, &rowError{err: errRead}
+			}
+			return storage.RequestBatch{}, d.line, errRead
+		}
+		d.line, _ = d.rd.FieldPos(0)
+		line = d.line
+
+		isFirst := d.first
+		d.first = false
+		switch len(record) {
+		case 1:
+			row.OriginalURL = record[0]
+		case 2:
+			row.CorrelationID, row.OriginalURL = record[0], record[1]
+		default:
+			return storage.RequestBatch{}, line, &rowError{err: errors.New("wrong number of fields")}
+		}
+		if isFirst && strings.EqualFold(row.OriginalURL, "original_url") {
+			row = storage.RequestBatch{}
+			continue
+		}
+		return row, line, nil
+	}
+}
+
+// importFormat determines the format of the request body
+// by the format query parameter or by the Content-Type header.
+func importFormat(req *http.Request) string {
+	if format := req.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return importFormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		return importFormatNDJSON
+	}
+	return ""
+}
+
+// importWriter sends import results to the client line by line.
+type importWriter struct {
+	res     http.ResponseWriter
+	enc     *json.Encoder
+	started bool
+}
+
+func (w *importWriter) write(results []ImportResult) error {
+	if !w.started {
+		w.res.Header().Set("Content-Type", "application/x-ndjson")
+		w.res.WriteHeader(http.StatusOK)
+		w.started = true
+	}
+	for _, v := range results {
+		if err := w.enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.res).Flush()
+}
+
+// importChunk adds the rows of one chunk to the storage and fills in the results.
+func (h *Handlers) importChunk(req *http.Request, results []ImportResult, rows []storage.RequestBatch, idx []int, id int) {
+	if len(rows) == 0 {
+		return
+	}
+
+	added, err := storage.AddNewBatch(req.Context(), h.stor, rows, id)
+	if err != nil {
+		h.logger(req).Infow("import URLs", "user ID", id, "error", err)
+		code := importErrInternal
+		if errors.Is(err, storage.ErrConflict) || errors.Is(err, storage.ErrShortURLExists) {
+			code = importErrConflict
+		}
+		for _, i := range idx {
+			results[i].Error = code
+		}
+		return
+	}
+	for k, i := range idx {
+		results[i].ShortURL = h.cfg.URL + "/" + added[k].ShortURL
+		results[i].Status = added[k].Status
+	}
+}
+
+// PostImport imports URLs from the request body in NDJSON or CSV format.
+// The body is read as a stream, rows are added to storage in chunks,
+// the result of each row is sent back as a separate NDJSON line.
+func (h *Handlers) PostImport(res http.ResponseWriter, req *http.Request) {
+	value := req.Context().Value(authorizer.UserContextKey)
+	if value == nil {
+		http.Error(res, "500 internal server error", http.StatusInternalServerError)
+		return
+	}
+	id := value.(int)
+
+	var dec importDecoder
+	switch importFormat(req) {
+	case importFormatNDJSON:
+		dec = newNDJSONDecoder(req.Body)
+	case importFormatCSV:
+		dec = newCSVDecoder(req.Body)
+	default:
+		http.Error(res, "unsupported import format", http.StatusBadRequest)
+		return
+	}
+
+	wr := &importWriter{res: res, enc: json.NewEncoder(res)}
+	results := make([]ImportResult, 0, importChunkSize)
+	rows := make([]storage.RequestBatch, 0, importChunkSize)
+	idx := make([]int, 0, importChunkSize)
+	flush := func() error {
+		h.importChunk(req, results, rows, idx, id)
+		err := wr.write(results)
+		results, rows, idx = results[:0], rows[:0], idx[:0]
+		return err
+	}
+
+	for {
+		row, line, err := dec.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *rowError
+		if err != nil && !errors.As(err, &rowErr) {
+			h.logger(req).Infow("read import body", "user ID", id, "line", line, "error", err)
+			code := importErrInternal
+			if errors.Is(err, bufio.ErrTooLong) {
+				code = importErrInvalidURL
+			}
+			results = append(results, ImportResult{Line: line, Error: code})
+			break
+		}
+
+		result := ImportResult{Line: line, CorrelationID: row.CorrelationID}
+		switch {
+		case err != nil, row.OriginalURL == "":
+			result.Error = importErrInvalidURL
+		default:
+			rows = append(rows, row)
+			idx = append(idx, len(results))
+		}
+		results = append(results, result)
+
+		if len(results) == importChunkSize {
+			if err = flush(); err != nil {
+				// The client is gone, there is no one to read the rest of the body for.
+				h.logger(req).Infow("write import results", "user ID", id, "error", err)
+				return
+			}
+		}
+	}
+
+	if !wr.started && len(results) == 0 {
+		http.Error(res, "request with empty body", http.StatusBadRequest)
+		return
+	}
+	if err := flush(); err != nil {
+		h.logger(req).Infow("write import results", "user ID", id, "error", err)
+	}
+}
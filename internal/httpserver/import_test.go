@@ -0,0 +1,143 @@
+package httpserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/jobs"
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+func TestHandlerPostImport(t *testing.T) {
+	testRepo := &testURLs{originalURLs: make([]testURL, 0)}
+
+	router := chi.NewRouter()
+	hs := NewHandlers(testRepo, cfg, newTestPool(testRepo), jobs.NewTracker(), zaptest.NewLogger(t).Sugar())
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	t.Run("without context", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		hs.PostImport(w, httptest.NewRequest("POST", ts.URL+"/api/shorten/import", nil))
+		res := w.Result()
+		defer res.Body.Close()
+		assert.Equal(t, 500, res.StatusCode)
+	})
+
+	router.Post("/api/shorten/import", AddContext(hs.PostImport))
+	type want struct {
+		statusCode int
+		lines      int
+		errors     []string
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        want
+	}{
+		{
+			name:        "ndjson with a bad row",
+			contentType: "application/x-ndjson",
+			body: `{"correlation_id":"1","original_url":"https://pract.ru/url1"}
+{"correlation_id":"2","original_url":""}
+
+{"correlation_id":"3","original_url":"https://pract.ru/url3"}
+not json
+`,
+			want: want{statusCode: 200, lines: 4, errors: []string{importErrInvalidURL, importErrInvalidURL}},
+		},
+		{
+			name:        "csv with header",
+			contentType: "text/csv",
+			body: `correlation_id,original_url
+1,https://pract.ru/url1
+2,https://pract.ru/url2
+https://pract.ru/url3
+1,2,3
+`,
+			want: want{statusCode: 200, lines: 4, errors: []string{importErrInvalidURL}},
+		},
+		{
+			name:        "empty body",
+			contentType: "text/csv",
+			body:        "",
+			want:        want{statusCode: 400},
+		},
+		{
+			name:        "unsupported format",
+			contentType: "text/plain",
+			body:        "https://pract.ru/url1",
+			want:        want{statusCode: 400},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/shorten/import", strings.NewReader(test.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", test.contentType)
+			resp, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.want.statusCode, resp.StatusCode)
+			if test.want.statusCode != http.StatusOK {
+				return
+			}
+			assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+			lines := 0
+			var errs []string
+			scan := bufio.NewScanner(resp.Body)
+			for scan.Scan() {
+				var r ImportResult
+				require.NoError(t, json.Unmarshal(scan.Bytes(), &r))
+				lines++
+				if r.Error != "" {
+					errs = append(errs, r.Error)
+					assert.Empty(t, r.ShortURL)
+				} else {
+					assert.NotEmpty(t, r.ShortURL)
+				}
+			}
+			assert.Equal(t, test.want.lines, lines)
+			assert.Equal(t, test.want.errors, errs)
+		})
+	}
+
+	for _, test := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "storage error", err: errors.New("connection refused"), want: importErrInternal},
+		{name: "short url collisions", err: storage.ErrShortURLExists, want: importErrConflict},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			testRepo.batchErr = test.err
+			defer func() { testRepo.batchErr = nil }()
+
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/shorten/import?format=csv",
+				strings.NewReader("https://pract.ru/url1\n"))
+			require.NoError(t, err)
+			resp, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var r ImportResult
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&r))
+			assert.Equal(t, test.want, r.Error)
+		})
+	}
+}
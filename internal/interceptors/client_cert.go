@@ -0,0 +1,44 @@
+package interceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
+)
+
+// WithClientCert returns the interceptor that authenticates the callers by their verified client certificates.
+// The user of the certificate identity is used instead of the token, the admin role gives access
+// to the internal methods. Callers without a known certificate are authenticated by the token.
+func WithClientCert(ids authorizer.Identities) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+		tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+		// Only the chains verified against the client CA are trusted.
+		if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+			return handler(ctx, req)
+		}
+		identity, name, ok := ids.Lookup(tlsInfo.State.VerifiedChains[0][0])
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		logger.AddFields(ctx, "client_cert", name)
+		if identity.Admin {
+			ctx = context.WithValue(ctx, authorizer.AdminContextKey, true)
+		}
+		if identity.UserID != 0 {
+			ctx = context.WithValue(ctx, authorizer.UserContextKey, identity.UserID)
+			logger.AddFields(ctx, "user_id", identity.UserID)
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,136 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
+)
+
+// testCA issues certificates signed by a new CA.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns a certificate with the common name signed by the CA.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestWithClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	cas := x509.NewCertPool()
+	cas.AddCert(ca.cert)
+	ids, err := authorizer.ParseIdentities("billing=user:42,ops=admin")
+	require.NoError(t, err)
+
+	// seen saves the identity the handler gets.
+	type seen struct {
+		userID interface{}
+		admin  bool
+	}
+	got := make(chan seen, 1)
+	srv := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{ca.issue(t, "server", x509.ExtKeyUsageServerAuth)},
+			ClientCAs:    cas,
+			ClientAuth:   tls.VerifyClientCertIfGiven,
+		})),
+		grpc.ChainUnaryInterceptor(WithClientCert(ids),
+			func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				got <- seen{userID: ctx.Value(authorizer.UserContextKey), admin: authorizer.IsAdmin(ctx)}
+				return handler(ctx, req)
+			}),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(listen)
+	defer srv.Stop()
+
+	tests := []struct {
+		name       string
+		commonName string
+		want       seen
+	}{
+		{name: "user certificate", commonName: "billing", want: seen{userID: 42}},
+		{name: "admin certificate", commonName: "ops", want: seen{admin: true}},
+		{name: "unknown certificate", commonName: "stranger", want: seen{}},
+		{name: "without certificate", want: seen{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientTLS := &tls.Config{RootCAs: cas}
+			if test.commonName != "" {
+				clientTLS.Certificates = []tls.Certificate{ca.issue(t, test.commonName, x509.ExtKeyUsageClientAuth)}
+			}
+			conn, err := grpc.Dial(listen.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, test.want, <-got)
+		})
+	}
+
+	t.Run("certificate of another CA", func(t *testing.T) {
+		other := newTestCA(t)
+		clientTLS := &tls.Config{RootCAs: cas, Certificates: []tls.Certificate{other.issue(t, "ops", x509.ExtKeyUsageClientAuth)}}
+		conn, err := grpc.Dial(listen.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.Error(t, err, "the handshake fails")
+	})
+}
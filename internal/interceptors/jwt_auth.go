@@ -3,17 +3,29 @@ package interceptors
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
 	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
 )
 
 // HandlerWithAuth adds user authentication to the handler.
+// The health service is called by probes without a token. Callers authenticated
+// by the client certificate need no token, admins without a user may call without it.
 func HandlerWithAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasPrefix(info.FullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+		return handler(ctx, req)
+	}
+	if _, ok := ctx.Value(authorizer.UserContextKey).(int); ok {
+		return handler(ctx, req)
+	}
 	var token string
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		values := md.Get(authorizer.AccessToken)
@@ -22,6 +34,9 @@ func HandlerWithAuth(ctx context.Context, req interface{}, info *grpc.UnaryServe
 		}
 	}
 	if len(token) == 0 {
+		if authorizer.IsAdmin(ctx) {
+			return handler(ctx, req)
+		}
 		return nil, status.Error(codes.Internal, "missing token")
 	}
 	userID, err := authorizer.GetUserIDFromToken(token)
@@ -36,6 +51,7 @@ func HandlerWithAuth(ctx context.Context, req interface{}, info *grpc.UnaryServe
 		}
 	}
 	ctx = context.WithValue(ctx, authorizer.UserContextKey, userID)
+	logger.AddFields(ctx, "user_id", userID)
 
 	return handler(ctx, req)
 }
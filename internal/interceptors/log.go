@@ -13,7 +13,7 @@ import (
 func HandlerWithLogging(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	start := time.Now()
 	h, err := handler(ctx, req)
-	logger.ZapSugar.Infoln(
+	logger.FromContext(ctx).Infoln(
 		"full method", info.FullMethod,
 		"duration", time.Since(start),
 	)
@@ -0,0 +1,18 @@
+package interceptors
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+)
+
+// WithLogger returns the interceptor that passes the logger to the gRPC methods in the context,
+// they get it with logger.FromContext.
+func WithLogger(log *zap.SugaredLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(logger.NewContext(ctx, log), req)
+	}
+}
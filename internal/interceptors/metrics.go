@@ -0,0 +1,20 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/metrics"
+)
+
+// HandlerWithMetrics counts the gRPC calls and measures their latencies.
+func HandlerWithMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	h, err := handler(ctx, req)
+	metrics.ObserveGRPC(info.FullMethod, status.Code(err).String(), time.Since(start))
+
+	return h, err
+}
@@ -0,0 +1,42 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/requestid"
+)
+
+// HandlerWithRequestID takes the request ID from the x-request-id metadata or generates a new one,
+// returns it in the response header and passes it to the gRPC methods in the context.
+// All log records of the call get the request ID, the client IP and the size of the request message,
+// the authentication adds the user ID.
+func HandlerWithRequestID(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	key := strings.ToLower(requestid.Header)
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	id = requestid.Get(id)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(key, id)); err != nil {
+		logger.FromContext(ctx).Infow("set request ID header", "error", err)
+	}
+
+	fields := []interface{}{"request_id", id}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, "client_ip", requestid.HostIP(p.Addr.String()))
+	}
+	if msg, ok := req.(proto.Message); ok {
+		fields = append(fields, "bytes_in", proto.Size(msg))
+	}
+	return handler(logger.NewFields(requestid.NewContext(ctx, id), fields...), req)
+}
@@ -0,0 +1,15 @@
+package interceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/tracing"
+)
+
+// HandlerWithTracing adds the trace ID of the call to the logs of the gRPC methods.
+// The span of the call is created by the otelgrpc stats handler of the server.
+func HandlerWithTracing(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(tracing.WithLogger(ctx), req)
+}
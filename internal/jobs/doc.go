@@ -0,0 +1,2 @@
+// Package jobs tracks the progress of asynchronous deletion of user URLs.
+package jobs
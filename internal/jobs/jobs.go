@@ -0,0 +1,217 @@
+package jobs
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Julia-ivv/shortener-url.git/pkg/randomizer"
+)
+
+// lengthJobID - the number of random bytes in the job ID.
+const lengthJobID = 12
+
+// jobTTL - how long a finished job is kept in the tracker.
+const jobTTL = time.Hour
+
+// Status - the state of the job.
+type Status string
+
+// Job states.
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Outcome - the result of deleting one URL.
+type Outcome string
+
+// Results of deleting a URL.
+const (
+	OutcomePending  Outcome = "pending"
+	OutcomeDeleted  Outcome = "deleted"
+	OutcomeNotFound Outcome = "not_found"
+	OutcomeFailed   Outcome = "failed"
+)
+
+// URLResult stores the result of deleting one URL.
+type URLResult struct {
+	// ShortURL - short url from the request.
+	ShortURL string `json:"short_url"`
+	// Outcome - the result of deleting.
+	Outcome Outcome `json:"outcome"`
+	// Error - error text if the URL was not deleted because of an error.
+	Error string `json:"error,omitempty"`
+}
+
+// Job stores the state of one request for deleting URLs.
+type Job struct {
+	// ID - job ID.
+	ID string `json:"id"`
+	// UserID - ID of the user who created the job.
+	UserID int `json:"-"`
+	// Status - the state of the job.
+	Status Status `json:"status"`
+	// Total - the number of URLs in the request.
+	Total int `json:"total"`
+	// Processed - the number of URLs already processed.
+	Processed int `json:"processed"`
+	// Deleted - the number of URLs marked as deleted.
+	Deleted int `json:"deleted"`
+	// Failed - the number of URLs not deleted because of an error.
+	Failed int `json:"failed"`
+	// Results - the result for each URL in the order of the request.
+	Results []URLResult `json:"results"`
+	// Errors - errors that occurred during deletion.
+	Errors []string `json:"errors,omitempty"`
+	// CreatedAt - the time the job was created.
+	CreatedAt time.Time `json:"created_at"`
+	// FinishedAt - the time the job was finished.
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// copyJob returns a copy of the job that can be used without a lock.
+func copyJob(j *Job) Job {
+	c := *j
+	c.Results = slices.Clone(j.Results)
+	c.Errors = slices.Clone(j.Errors)
+	return c
+}
+
+// Tracker stores deletion jobs in memory.
+type Tracker struct {
+	jobs map[string]*Job
+	sync.RWMutex
+}
+
+// NewTracker creates an instance for storing jobs.
+func NewTracker() *Tracker {
+	return &Tracker{
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Create creates a new pending job for deleting the user's URLs.
+func (t *Tracker) Create(userID int, shortURLs []string) (Job, error) {
+	id, err := randomizer.GenerateRandomString(lengthJobID)
+	if err != nil {
+		return Job{}, err
+	}
+
+	job := newJob(id, userID, shortURLs)
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.removeExpired()
+	t.jobs[id] = job
+	return copyJob(job), nil
+}
+
+// Restore adds a pending job with a known ID, e.g. loaded from the deletion queue after a restart.
+func (t *Tracker) Restore(id string, userID int, shortURLs []string) {
+	t.Lock()
+	defer t.Unlock()
+
+	if _, ok := t.jobs[id]; !ok {
+		t.jobs[id] = newJob(id, userID, shortURLs)
+	}
+}
+
+// newJob creates a pending job.
+func newJob(id string, userID int, shortURLs []string) *Job {
+	job := &Job{
+		ID:        id,
+		UserID:    userID,
+		Status:    StatusPending,
+		Total:     len(shortURLs),
+		Results:   make([]URLResult, len(shortURLs)),
+		CreatedAt: time.Now(),
+	}
+	for k, v := range shortURLs {
+		job.Results[k] = URLResult{ShortURL: v, Outcome: OutcomePending}
+	}
+	return job
+}
+
+// removeExpired removes finished jobs older than jobTTL. Must be called under lock.
+func (t *Tracker) removeExpired() {
+	for id, job := range t.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > jobTTL {
+			delete(t.jobs, id)
+		}
+	}
+}
+
+// Get gets the user's job by ID.
+func (t *Tracker) Get(id string, userID int) (job Job, ok bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	j, ok := t.jobs[id]
+	if !ok || j.UserID != userID {
+		return Job{}, false
+	}
+	return copyJob(j), true
+}
+
+// Start marks the job as running.
+func (t *Tracker) Start(id string) {
+	t.Lock()
+	defer t.Unlock()
+
+	if j, ok := t.jobs[id]; ok && j.Status == StatusPending {
+		j.Status = StatusRunning
+	}
+}
+
+// Report saves the result of deleting a part of the job's URLs,
+// offset - the position of the part in the request.
+// URLs from deleted are marked as deleted, the others as not found,
+// or all of them as failed if err is not nil.
+func (t *Tracker) Report(id string, offset int, batch []string, deleted []string, err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	j, ok := t.jobs[id]
+	if !ok || offset < 0 || offset+len(batch) > len(j.Results) {
+		return
+	}
+	if err != nil {
+		j.Errors = append(j.Errors, err.Error())
+	}
+	for k, v := range batch {
+		res := &j.Results[offset+k]
+		switch {
+		case err != nil:
+			res.Outcome = OutcomeFailed
+			res.Error = err.Error()
+			j.Failed++
+		case slices.Contains(deleted, v):
+			res.Outcome = OutcomeDeleted
+			j.Deleted++
+		default:
+			res.Outcome = OutcomeNotFound
+		}
+	}
+	j.Processed += len(batch)
+}
+
+// Finish marks the job as finished: done, or failed if there were errors.
+func (t *Tracker) Finish(id string) {
+	t.Lock()
+	defer t.Unlock()
+
+	j, ok := t.jobs[id]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	j.FinishedAt = &now
+	j.Status = StatusDone
+	if len(j.Errors) > 0 {
+		j.Status = StatusFailed
+	}
+}
@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testUserID = 123
+
+func TestCreate(t *testing.T) {
+	tracker := NewTracker()
+	t.Run("create job", func(t *testing.T) {
+		job, err := tracker.Create(testUserID, []string{"EwH", "Ert"})
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, job.ID)
+			assert.Equal(t, StatusPending, job.Status)
+			assert.Equal(t, 2, job.Total)
+			assert.Equal(t, OutcomePending, job.Results[0].Outcome)
+		}
+	})
+}
+
+func TestGet(t *testing.T) {
+	tracker := NewTracker()
+	job, err := tracker.Create(testUserID, []string{"EwH"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		userID int
+		wantOk bool
+	}{
+		{name: "job exists", id: job.ID, userID: testUserID, wantOk: true},
+		{name: "other user", id: job.ID, userID: 456, wantOk: false},
+		{name: "unknown job", id: "unknown", userID: testUserID, wantOk: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, ok := tracker.Get(test.id, test.userID)
+			assert.Equal(t, test.wantOk, ok)
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tracker := NewTracker()
+	t.Run("restore job", func(t *testing.T) {
+		tracker.Restore("restored", testUserID, []string{"EwH"})
+		job, ok := tracker.Get("restored", testUserID)
+		if assert.True(t, ok) {
+			assert.Equal(t, StatusPending, job.Status)
+			assert.Equal(t, 1, job.Total)
+		}
+	})
+}
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  Status
+		wantDeleted int
+		wantFailed  int
+		wantOutcome []Outcome
+	}{
+		{
+			name:        "deleted and not found",
+			err:         nil,
+			wantStatus:  StatusDone,
+			wantDeleted: 1,
+			wantFailed:  0,
+			wantOutcome: []Outcome{OutcomeDeleted, OutcomeNotFound},
+		},
+		{
+			name:        "batch failed",
+			err:         errors.New("some error"),
+			wantStatus:  StatusFailed,
+			wantDeleted: 0,
+			wantFailed:  2,
+			wantOutcome: []Outcome{OutcomeFailed, OutcomeFailed},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := NewTracker()
+			urls := []string{"EwH", "Ert"}
+			job, err := tracker.Create(testUserID, urls)
+			if !assert.NoError(t, err) {
+				return
+			}
+			tracker.Start(job.ID)
+			tracker.Report(job.ID, 0, urls, []string{"EwH"}, test.err)
+			tracker.Finish(job.ID)
+
+			job, ok := tracker.Get(job.ID, testUserID)
+			if assert.True(t, ok) {
+				assert.Equal(t, test.wantStatus, job.Status)
+				assert.Equal(t, len(urls), job.Processed)
+				assert.Equal(t, test.wantDeleted, job.Deleted)
+				assert.Equal(t, test.wantFailed, job.Failed)
+				for k, v := range test.wantOutcome {
+					assert.Equal(t, v, job.Results[k].Outcome)
+				}
+				assert.NotNil(t, job.FinishedAt)
+			}
+		})
+	}
+}
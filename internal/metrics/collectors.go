@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// poolCollector reads the state of the database connection pool on each scrape.
+type poolCollector struct {
+	stor                 storage.PoolStatter
+	maxConns             *prometheus.Desc
+	totalConns           *prometheus.Desc
+	idleConns            *prometheus.Desc
+	acquiredConns        *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+}
+
+func newPoolCollector(stor storage.PoolStatter) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		stor:                 stor,
+		maxConns:             desc("max_conns", "Maximum size of the pool."),
+		totalConns:           desc("total_conns", "Number of open connections."),
+		idleConns:            desc("idle_conns", "Number of idle connections."),
+		acquiredConns:        desc("acquired_conns", "Number of connections in use."),
+		acquireCount:         desc("acquires_total", "Number of successful acquires from the pool."),
+		emptyAcquireCount:    desc("empty_acquires_total", "Number of acquires that waited for a connection."),
+		canceledAcquireCount: desc("canceled_acquires_total", "Number of acquires cancelled by the context."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+	}
+}
+
+// Describe sends the descriptions of the pool metrics.
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+// Collect sends the current state of the pool.
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stor.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stats.MaxConns))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stats.AcquiredConns))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stats.AcquireCount))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stats.EmptyAcquireCount))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(stats.CanceledAcquireCount))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stats.AcquireDuration.Seconds())
+}
+
+// cacheCollector reads the counters of the URL cache on each scrape.
+type cacheCollector struct {
+	cache   *storage.CachedURLs
+	hits    *prometheus.Desc
+	misses  *prometheus.Desc
+	entries *prometheus.Desc
+}
+
+func newCacheCollector(cache *storage.CachedURLs) *cacheCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", name), help, nil, nil)
+	}
+	return &cacheCollector{
+		cache:   cache,
+		hits:    desc("hits_total", "Number of lookups answered from the URL cache."),
+		misses:  desc("misses_total", "Number of lookups passed to the storage."),
+		entries: desc("entries", "Number of cached short URLs."),
+	}
+}
+
+// Describe sends the descriptions of the cache metrics.
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+// Collect sends the current cache counters.
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.CacheStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(stats.Entries))
+}
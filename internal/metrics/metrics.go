@@ -0,0 +1,114 @@
+// Package metrics collects the service metrics and exposes them to Prometheus.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// namespace - the prefix of all metric names.
+const namespace = "shortener"
+
+// Results of redirects.
+const (
+	// RedirectFound - the client was redirected to the original URL.
+	RedirectFound = "found"
+	// RedirectDeleted - the short URL was deleted.
+	RedirectDeleted = "deleted"
+	// RedirectNotFound - the short URL is unknown.
+	RedirectNotFound = "not_found"
+)
+
+var (
+	// httpRequests counts HTTP requests by route, method and status.
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+	// httpDuration measures HTTP request latencies by route, method and status.
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latencies by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	// grpcRequests counts gRPC calls by method and status code.
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Number of gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+	// grpcDuration measures gRPC call latencies by method and status code.
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC call latencies by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	// redirects counts redirects by short URLs by result.
+	redirects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirects_total",
+		Help:      "Number of redirects by short URLs by result: found, deleted or not_found.",
+	}, []string{"result"})
+	// storageDuration measures storage operation latencies by backend, operation and result.
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Storage operation latencies by backend, operation and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"backend", "operation", "result"})
+)
+
+// Handler returns the handler of the metrics page.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterQueue adds the gauge of the number of deletion tasks waiting for a worker.
+func RegisterQueue(depth func() int) error {
+	return prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "delete_queue_depth",
+		Help:      "Number of deletion tasks waiting for a worker.",
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
+
+// RegisterPool adds the metrics of the database connection pool.
+func RegisterPool(stor storage.PoolStatter) error {
+	return prometheus.Register(newPoolCollector(stor))
+}
+
+// RegisterCache adds the counters of the URL cache.
+func RegisterCache(cache *storage.CachedURLs) error {
+	return prometheus.Register(newCacheCollector(cache))
+}
+
+// ObserveHTTP records the HTTP request by the route pattern, the method and the response status.
+func ObserveHTTP(route string, method string, status int, duration time.Duration) {
+	labels := []string{route, method, strconv.Itoa(status)}
+	httpRequests.WithLabelValues(labels...).Inc()
+	httpDuration.WithLabelValues(labels...).Observe(duration.Seconds())
+}
+
+// ObserveGRPC records the gRPC call by the full method name and the status code.
+func ObserveGRPC(method string, code string, duration time.Duration) {
+	grpcRequests.WithLabelValues(method, code).Inc()
+	grpcDuration.WithLabelValues(method, code).Observe(duration.Seconds())
+}
+
+// Redirect counts a redirect by the short URL with the result:
+// RedirectFound, RedirectDeleted or RedirectNotFound.
+func Redirect(result string) {
+	redirects.WithLabelValues(result).Inc()
+}
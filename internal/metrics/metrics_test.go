@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// sampleCount returns the number of observations of the storage operation.
+func sampleCount(t *testing.T, op, result string) uint64 {
+	m := &dto.Metric{}
+	h := storageDuration.WithLabelValues(storage.BackendKV, op, result).(prometheus.Histogram)
+	require.NoError(t, h.Write(m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentedURLs(t *testing.T) {
+	storageDuration.Reset()
+	kv, err := storage.NewKVURLs(t.TempDir() + "/urls.db")
+	require.NoError(t, err)
+	repo := NewInstrumentedURLs(kv, storage.BackendKV)
+	defer repo.Close()
+	ctx := context.Background()
+
+	_, err = repo.AddURL(ctx, "EwH", "https://practicum.yandex.ru/", 1)
+	require.NoError(t, err)
+	_, err = repo.AddURL(ctx, "EwH2", "https://practicum.yandex.ru/", 1)
+	require.ErrorIs(t, err, storage.ErrConflict)
+	_, _, ok := repo.GetURL(ctx, "EwH")
+	require.True(t, ok)
+	_, err = repo.RestoreUserURLs(ctx, []string{"EwH"}, 1, time.Time{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		op     string
+		result string
+		want   uint64
+	}{
+		{name: "conflict is not an error", op: "add_url", result: resultOK, want: 2},
+		{name: "no errors", op: "add_url", result: resultError, want: 0},
+		{name: "get url", op: "get_url", result: resultOK, want: 1},
+		{name: "restore", op: "restore_user_urls", result: resultOK, want: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, sampleCount(t, test.op, test.result))
+		})
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	redirects.Reset()
+	Redirect(RedirectFound)
+	Redirect(RedirectFound)
+	Redirect(RedirectNotFound)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(redirects.WithLabelValues(RedirectFound)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(redirects.WithLabelValues(RedirectNotFound)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(redirects.WithLabelValues(RedirectDeleted)))
+}
+
+func TestObserveGRPC(t *testing.T) {
+	grpcRequests.Reset()
+	ObserveGRPC("/shortener.ShortUrl/GetUrl", "NotFound", time.Millisecond)
+	assert.Equal(t, float64(1), testutil.ToFloat64(grpcRequests.WithLabelValues("/shortener.ShortUrl/GetUrl", "NotFound")))
+}
+
+func TestRegisterQueue(t *testing.T) {
+	err := RegisterQueue(func() int { return 3 })
+	if !errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		require.NoError(t, err)
+	}
+
+	expected := `
+# HELP shortener_delete_queue_depth Number of deletion tasks waiting for a worker.
+# TYPE shortener_delete_queue_depth gauge
+shortener_delete_queue_depth 3
+`
+	assert.NoError(t, testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "shortener_delete_queue_depth"))
+}
+
+// testPool returns fixed pool statistics.
+type testPool struct{}
+
+func (testPool) PoolStats() storage.PoolStats {
+	return storage.PoolStats{MaxConns: 10, TotalConns: 4, IdleConns: 3, AcquiredConns: 1, AcquireCount: 42, AcquireDuration: 2 * time.Second}
+}
+
+func TestPoolCollector(t *testing.T) {
+	expected := `
+# HELP shortener_db_pool_acquired_conns Number of connections in use.
+# TYPE shortener_db_pool_acquired_conns gauge
+shortener_db_pool_acquired_conns 1
+# HELP shortener_db_pool_acquires_total Number of successful acquires from the pool.
+# TYPE shortener_db_pool_acquires_total counter
+shortener_db_pool_acquires_total 42
+# HELP shortener_db_pool_acquire_duration_seconds_total Total time spent acquiring connections.
+# TYPE shortener_db_pool_acquire_duration_seconds_total counter
+shortener_db_pool_acquire_duration_seconds_total 2
+# HELP shortener_db_pool_max_conns Maximum size of the pool.
+# TYPE shortener_db_pool_max_conns gauge
+shortener_db_pool_max_conns 10
+`
+	c := newPoolCollector(testPool{})
+	assert.Equal(t, 8, testutil.CollectAndCount(c))
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"shortener_db_pool_acquired_conns", "shortener_db_pool_acquires_total",
+		"shortener_db_pool_acquire_duration_seconds_total", "shortener_db_pool_max_conns"))
+}
+
+func TestCacheCollector(t *testing.T) {
+	repo := storage.NewMapURLs()
+	_, err := repo.AddURL(context.Background(), "EwH", "https://practicum.yandex.ru/", 1)
+	require.NoError(t, err)
+	cache := storage.NewCachedURLs(repo, 10, time.Minute, 0)
+	cache.GetURL(context.Background(), "EwH")
+	cache.GetURL(context.Background(), "EwH")
+
+	expected := `
+# HELP shortener_cache_entries Number of cached short URLs.
+# TYPE shortener_cache_entries gauge
+shortener_cache_entries 1
+# HELP shortener_cache_hits_total Number of lookups answered from the URL cache.
+# TYPE shortener_cache_hits_total counter
+shortener_cache_hits_total 1
+# HELP shortener_cache_misses_total Number of lookups passed to the storage.
+# TYPE shortener_cache_misses_total counter
+shortener_cache_misses_total 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(newCacheCollector(cache), strings.NewReader(expected)))
+}
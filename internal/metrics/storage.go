@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// Results of storage operations.
+const (
+	resultOK    = "ok"
+	resultError = "error"
+)
+
+// InstrumentedURLs measures the latencies of the storage operations.
+type InstrumentedURLs struct {
+	storage.Repositories
+	backend string
+}
+
+// NewInstrumentedURLs wraps the storage, backend is the storage name in the metric labels.
+func NewInstrumentedURLs(repo storage.Repositories, backend string) *InstrumentedURLs {
+	return &InstrumentedURLs{Repositories: repo, backend: backend}
+}
+
+// observe records the duration of the operation since start.
+func (s *InstrumentedURLs) observe(op string, start time.Time, err error) {
+	result := resultOK
+	if err != nil {
+		result = resultError
+	}
+	storageDuration.WithLabelValues(s.backend, op, result).Observe(time.Since(start).Seconds())
+}
+
+// GetURL gets the original URL matching the short URL.
+func (s *InstrumentedURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
+	defer s.observe("get_url", time.Now(), nil)
+	return s.Repositories.GetURL(ctx, shortURL)
+}
+
+// AddURL adds a new short url.
+func (s *InstrumentedURLs) AddURL(ctx context.Context, shortURL string, originURL string, userID int) (findURL string, err error) {
+	defer func(start time.Time) {
+		// A conflict is a normal answer of the storage.
+		if errors.Is(err, storage.ErrConflict) {
+			s.observe("add_url", start, nil)
+			return
+		}
+		s.observe("add_url", start, err)
+	}(time.Now())
+	return s.Repositories.AddURL(ctx, shortURL, originURL, userID)
+}
+
+// AddBatch adds a batch of new short URLs.
+func (s *InstrumentedURLs) AddBatch(ctx context.Context, shortURLBatch []storage.ResponseBatch, originURLBatch []storage.RequestBatch, userID int) (results []storage.BatchResult, err error) {
+	defer func(start time.Time) { s.observe("add_batch", start, err) }(time.Now())
+	return s.Repositories.AddBatch(ctx, shortURLBatch, originURLBatch, userID)
+}
+
+// GetAllUserURLs gets all user's short url.
+func (s *InstrumentedURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []storage.UserURL, err error) {
+	defer func(start time.Time) { s.observe("get_user_urls", start, err) }(time.Now())
+	return s.Repositories.GetAllUserURLs(ctx, baseURL, userID)
+}
+
+// IterateUserURLs calls fn for each user's URL.
+func (s *InstrumentedURLs) IterateUserURLs(ctx context.Context, userID int, fn func(url storage.URLInfo) error) (err error) {
+	defer func(start time.Time) { s.observe("iterate_user_urls", start, err) }(time.Now())
+	return s.Repositories.IterateUserURLs(ctx, userID, fn)
+}
+
+// AddClick increases the number of redirects by the short URL.
+func (s *InstrumentedURLs) AddClick(ctx context.Context, shortURL string) (err error) {
+	defer func(start time.Time) { s.observe("add_click", start, err) }(time.Now())
+	return s.Repositories.AddClick(ctx, shortURL)
+}
+
+// DeleteUserURLs sets the deletion flag to the user URLs.
+func (s *InstrumentedURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
+	defer func(start time.Time) { s.observe("delete_user_urls", start, err) }(time.Now())
+	return s.Repositories.DeleteUserURLs(ctx, delURLs, userID)
+}
+
+// RestoreUserURLs clears the deletion flag of the user URLs.
+func (s *InstrumentedURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	defer func(start time.Time) { s.observe("restore_user_urls", start, err) }(time.Now())
+	return s.Repositories.RestoreUserURLs(ctx, shortURLs, userID, deletedAfter)
+}
+
+// PurgeDeletedURLs permanently removes URLs deleted before deletedBefore.
+func (s *InstrumentedURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	defer func(start time.Time) { s.observe("purge_deleted_urls", start, err) }(time.Now())
+	return s.Repositories.PurgeDeletedURLs(ctx, deletedBefore)
+}
+
+// GetStats gets the amount of all users and URLs in the service.
+func (s *InstrumentedURLs) GetStats(ctx context.Context) (stats storage.ServiceStats, err error) {
+	defer func(start time.Time) { s.observe("get_stats", start, err) }(time.Now())
+	return s.Repositories.GetStats(ctx)
+}
+
+// PingStor checking access to storage.
+func (s *InstrumentedURLs) PingStor(ctx context.Context) (err error) {
+	defer func(start time.Time) { s.observe("ping", start, err) }(time.Now())
+	return s.Repositories.PingStor(ctx)
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
 	"github.com/Julia-ivv/shortener-url.git/internal/authorizer"
 )
 
@@ -61,6 +63,7 @@ func HandlerWithAuth(h http.Handler) http.Handler {
 				}
 			}
 
+			logger.AddFields(req.Context(), "user_id", userID)
 			h.ServeHTTP(res, req.WithContext(newctx))
 		})
 }
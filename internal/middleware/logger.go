@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+)
+
+// HandlerWithLogger passes the logger to the next handlers in the request context,
+// they get it with logger.FromContext.
+func HandlerWithLogger(log *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(res http.ResponseWriter, req *http.Request) {
+				h.ServeHTTP(res, req.WithContext(logger.NewContext(req.Context(), log)))
+			})
+	}
+}
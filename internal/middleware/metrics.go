@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	mwPkg "github.com/Julia-ivv/shortener-url/pkg/middleware"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/metrics"
+)
+
+// statusWriter remembers the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader saves the status code and sends it.
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write sends the data, the status is 200 if WriteHeader was not called.
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// code returns the sent status code, 200 if nothing was sent.
+func (w *statusWriter) code() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// responseStatus returns the status code of the served request,
+// 500 if the started response was aborted with mwPkg.Abort.
+func responseStatus(sw *statusWriter, req *http.Request) int {
+	if mwPkg.AbortError(req.Context()) != nil {
+		return http.StatusInternalServerError
+	}
+	return sw.code()
+}
+
+// Unwrap returns the original writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// routePattern returns the chi route pattern of the served request, e.g. /{shortURL}.
+func routePattern(req *http.Request) string {
+	if rctx := chi.RouteContext(req.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return "unknown"
+}
+
+// HandlerWithMetrics counts the requests and measures their latencies.
+// The route is the chi route pattern, so every short URL does not create a new series.
+func HandlerWithMetrics(h http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(res http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: res}
+			h.ServeHTTP(sw, req)
+
+			metrics.ObserveHTTP(routePattern(req), req.Method, responseStatus(sw, req), time.Since(start))
+		})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	mwPkg "github.com/Julia-ivv/shortener-url/pkg/middleware"
+)
+
+func TestHandlerWithMetrics(t *testing.T) {
+	var gotRoute string
+	r := chi.NewRouter()
+	r.Use(HandlerWithMetrics)
+	r.Get("/{shortURL}", func(res http.ResponseWriter, req *http.Request) {
+		gotRoute = chi.RouteContext(req.Context()).RoutePattern()
+		res.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "short URL", path: "/EwH", wantStatus: http.StatusTemporaryRedirect},
+		{name: "unknown route", path: "/api/unknown", wantStatus: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, test.path, nil))
+			assert.Equal(t, test.wantStatus, res.Code)
+		})
+	}
+	assert.Equal(t, "/{shortURL}", gotRoute)
+}
+
+func TestStatusWriter(t *testing.T) {
+	res := httptest.NewRecorder()
+	sw := &statusWriter{ResponseWriter: res}
+	_, err := sw.Write([]byte("ok"))
+	assert.NoError(t, err)
+	sw.WriteHeader(http.StatusInternalServerError)
+	assert.Equal(t, http.StatusOK, sw.status)
+}
+
+func TestResponseStatus(t *testing.T) {
+	var status int
+	h := mwPkg.HandlerWithAbort(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		sw := &statusWriter{ResponseWriter: res}
+		sw.WriteHeader(http.StatusOK)
+		mwPkg.Abort(req, errors.New("storage is unavailable"))
+		status = responseStatus(sw, req)
+	}))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/user/urls/export", nil))
+	})
+	assert.Equal(t, http.StatusInternalServerError, status, "the aborted response is a server error")
+}
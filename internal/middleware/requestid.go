@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/requestid"
+)
+
+// HandlerWithRequestID takes the request ID from the X-Request-ID header or generates a new one,
+// returns it in the response header and passes it to the next handlers in the context.
+// All log records of the request get the request ID, the client IP and the size of the request body,
+// the authentication adds the user ID.
+func HandlerWithRequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(res http.ResponseWriter, req *http.Request) {
+			id := requestid.Get(req.Header.Get(requestid.Header))
+			res.Header().Set(requestid.Header, id)
+
+			ctx := requestid.NewContext(req.Context(), id)
+			fields := []interface{}{"request_id", id, "client_ip", requestid.ClientIP(req)}
+			if req.ContentLength >= 0 {
+				fields = append(fields, "bytes_in", req.ContentLength)
+			}
+			h.ServeHTTP(res, req.WithContext(logger.NewFields(ctx, fields...)))
+		})
+}
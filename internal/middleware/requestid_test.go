@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/requestid"
+)
+
+func TestHandlerWithRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	var gotID string
+	handler := HandlerWithRequestID(
+		// The access log is written by an outer handler after the user is authenticated.
+		http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			HandlerWithAuth(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				gotID = requestid.FromContext(req.Context())
+				logger.FromContext(req.Context()).Info("handler")
+			})).ServeHTTP(res, req)
+			logger.FromContext(req.Context()).Info("access")
+		}))
+	handler = HandlerWithLogger(zap.New(core).Sugar())(handler)
+
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "client request ID", header: "req-1", wantSame: true},
+		{name: "generated request ID", header: "", wantSame: false},
+		{name: "invalid request ID", header: "req 1", wantSame: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logs.TakeAll()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://practicum.yandex.ru/"))
+			req.Header.Set("X-Real-IP", "10.0.0.1")
+			if test.header != "" {
+				req.Header.Set(requestid.Header, test.header)
+			}
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			id := res.Header().Get(requestid.Header)
+			if test.wantSame {
+				assert.Equal(t, test.header, id)
+			} else {
+				assert.True(t, requestid.Valid(id))
+				assert.NotEqual(t, test.header, id)
+			}
+			assert.Equal(t, id, gotID)
+
+			entries := logs.TakeAll()
+			require.Len(t, entries, 2)
+			for _, entry := range entries {
+				fields := entry.ContextMap()
+				assert.Equal(t, id, fields["request_id"], entry.Message)
+				assert.Equal(t, "10.0.0.1", fields["client_ip"], entry.Message)
+				assert.Equal(t, int64(len("https://practicum.yandex.ru/")), fields["bytes_in"], entry.Message)
+				assert.Contains(t, fields, "user_id", entry.Message)
+			}
+		})
+	}
+}
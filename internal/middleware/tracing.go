@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/tracing"
+)
+
+// HandlerWithTracing creates a span for each request, continuing the trace from the traceparent header.
+// The span is named after the chi route pattern, the logger of the request context
+// adds the trace ID to the records.
+func HandlerWithTracing(h http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(res http.ResponseWriter, req *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := tracing.Tracer().Start(ctx, req.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPRequestMethodKey.String(req.Method), semconv.URLPath(req.URL.Path)),
+			)
+			defer span.End()
+
+			sw := &statusWriter{ResponseWriter: res}
+			h.ServeHTTP(sw, req.WithContext(tracing.WithLogger(ctx)))
+
+			route := routePattern(req)
+			status := responseStatus(sw, req)
+			span.SetName(req.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+}
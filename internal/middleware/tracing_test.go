@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+func TestHandlerWithTracing(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer tp.Shutdown(context.Background())
+
+	r := chi.NewRouter()
+	r.Use(HandlerWithTracing)
+	r.Get("/{shortURL}", func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusTemporaryRedirect)
+	})
+	r.Get("/ping", func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusInternalServerError)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantName    string
+		wantStatus  codes.Code
+	}{
+		{
+			name:        "continue trace",
+			path:        "/EwH",
+			traceparent: "00-" + traceID + "-00f067aa0ba902b7-01",
+			wantName:    "GET /{shortURL}",
+			wantStatus:  codes.Unset,
+		},
+		{
+			name:        "server error",
+			path:        "/ping",
+			traceparent: "",
+			wantName:    "GET /ping",
+			wantStatus:  codes.Error,
+		},
+	}
+	for k, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			if test.traceparent != "" {
+				req.Header.Set("traceparent", test.traceparent)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := sr.Ended()
+			require.Len(t, spans, k+1)
+			span := spans[k]
+			assert.Equal(t, test.wantName, span.Name())
+			assert.Equal(t, test.wantStatus, span.Status().Code)
+			if test.traceparent != "" {
+				assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+			}
+			assert.Contains(t, span.Attributes(), semconv.HTTPRoute(test.wantName[len("GET "):]))
+		})
+	}
+}
@@ -0,0 +1,6 @@
+// Package migrator applies versioned SQL migrations to the database.
+//
+// Migrations are files named like 0001_create_urls.up.sql and 0001_create_urls.down.sql.
+// Applied versions are stored in the schema_migrations table,
+// an advisory lock prevents several instances from migrating at the same time.
+package migrator
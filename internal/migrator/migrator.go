@@ -0,0 +1,275 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// lockKey - the key of the advisory lock taken while migrating.
+const lockKey = 72983401
+
+// ErrUnknownVersion is returned when the target version has no migration.
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// fileName matches migration file names: version, name, direction.
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Dialect contains the SQL that differs between databases.
+type Dialect struct {
+	// Lock - the query taking the migration lock with lockKey as an argument, empty - no lock.
+	Lock string
+	// Unlock - the query releasing the migration lock.
+	Unlock string
+	// VersionTable - the query creating the table of applied migrations.
+	VersionTable string
+}
+
+// Supported dialects.
+var (
+	// Postgres uses an advisory lock, so several instances can start at once.
+	Postgres = Dialect{
+		Lock:         "SELECT pg_advisory_lock($1)",
+		Unlock:       "SELECT pg_advisory_unlock($1)",
+		VersionTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, name text NOT NULL, applied_at timestamptz NOT NULL DEFAULT now())",
+	}
+	// SQLite takes no lock, the database file is locked by the migration transactions.
+	SQLite = Dialect{
+		VersionTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version integer PRIMARY KEY, name text NOT NULL, applied_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+	}
+)
+
+// Migration stores one version of the schema.
+type Migration struct {
+	// Version - migration number, migrations are applied in ascending order.
+	Version int
+	// Name - short description from the file name.
+	Name string
+	// Up - SQL applying the migration.
+	Up string
+	// Down - SQL reverting the migration.
+	Down string
+}
+
+// Load reads migrations from the root directory of fsys sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, v := range byVersion {
+		if v.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up step", v.Version)
+		}
+		migrations = append(migrations, *v)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator applies migrations to the database.
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// New creates a migrator with migrations from fsys.
+func New(db *sql.DB, fsys fs.FS, dialect Dialect) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Latest returns the version of the last known migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the current schema version, 0 if no migrations are applied.
+func (m *Migrator) Version(ctx context.Context) (version int, err error) {
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err = current(ctx, conn)
+		return err
+	})
+	return version, err
+}
+
+// Up applies all migrations that are not applied yet.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the given number of the last applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		target := 0
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if m.migrations[i].Version > version {
+				continue
+			}
+			if steps == 0 {
+				target = m.migrations[i].Version
+				break
+			}
+			steps--
+		}
+		return m.migrate(ctx, conn, version, target)
+	})
+}
+
+// To migrates the schema up or down to the given version, 0 reverts all migrations.
+func (m *Migrator) To(ctx context.Context, target int) error {
+	if target != 0 && m.find(target) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn, version, target)
+	})
+}
+
+// find returns the index of the migration with the version or -1.
+func (m *Migrator) find(version int) int {
+	for k, v := range m.migrations {
+		if v.Version == version {
+			return k
+		}
+	}
+	return -1
+}
+
+// migrate applies or reverts migrations between the current and the target versions.
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, version, target int) error {
+	if target >= version {
+		for _, mig := range m.migrations {
+			if mig.Version <= version || mig.Version > target {
+				continue
+			}
+			err := step(ctx, conn, mig.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d up: %w", mig.Version, err)
+			}
+		}
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version > version || mig.Version <= target {
+			continue
+		}
+		if mig.Down == "" {
+			return fmt.Errorf("migration %d has no down step", mig.Version)
+		}
+		err := step(ctx, conn, mig.Down,
+			"DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+		if err != nil {
+			return fmt.Errorf("migration %d down: %w", mig.Version, err)
+		}
+	}
+	return nil
+}
+
+// step runs the migration SQL and updates the version table in one transaction.
+func step(ctx context.Context, conn *sql.Conn, query string, versionQuery string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, versionQuery, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// current returns the last applied version.
+func current(ctx context.Context, conn *sql.Conn) (version int, err error) {
+	var v sql.NullInt64
+	row := conn.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations")
+	if err = row.Scan(&v); err != nil {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+// withLock runs fn on a dedicated connection holding the migration lock of the dialect.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect.Lock != "" {
+		if _, err = conn.ExecContext(ctx, m.dialect.Lock, lockKey); err != nil {
+			return err
+		}
+		defer func() {
+			_, errUnlock := conn.ExecContext(context.Background(), m.dialect.Unlock, lockKey)
+			err = errors.Join(err, errUnlock)
+		}()
+	}
+
+	if _, err = conn.ExecContext(ctx, m.dialect.VersionTable); err != nil {
+		return err
+	}
+	return fn(conn)
+}
@@ -0,0 +1,160 @@
+package migrator
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFS = fstest.MapFS{
+	"0001_create_urls.up.sql":     {Data: []byte("CREATE TABLE urls (short_url text)")},
+	"0001_create_urls.down.sql":   {Data: []byte("DROP TABLE urls")},
+	"0002_add_clicks.up.sql":      {Data: []byte("ALTER TABLE urls ADD COLUMN clicks bigint")},
+	"0002_add_clicks.down.sql":    {Data: []byte("ALTER TABLE urls DROP COLUMN clicks")},
+	"0003_index_short_url.up.sql": {Data: []byte("CREATE INDEX urls_short_url_idx ON urls (short_url)")},
+	"README.md":                   {Data: []byte("not a migration")},
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		fsys      fstest.MapFS
+		wantCount int
+		wantError bool
+	}{
+		{
+			name:      "sorted migrations",
+			fsys:      testFS,
+			wantCount: 3,
+			wantError: false,
+		},
+		{
+			name: "without up step",
+			fsys: fstest.MapFS{
+				"0001_create_urls.down.sql": {Data: []byte("DROP TABLE urls")},
+			},
+			wantError: true,
+		},
+		{
+			name: "different names",
+			fsys: fstest.MapFS{
+				"0001_create_urls.up.sql":  {Data: []byte("CREATE TABLE urls (short_url text)")},
+				"0001_create_url.down.sql": {Data: []byte("DROP TABLE urls")},
+			},
+			wantError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			migrations, err := Load(test.fsys)
+			if test.wantError {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.wantCount, len(migrations))
+				for k, v := range migrations {
+					assert.Equal(t, k+1, v.Version)
+				}
+			}
+		})
+	}
+}
+
+// expectLock sets expectations for taking the lock and reading the current version.
+func expectLock(mock sqlmock.Sqlmock, version int) {
+	mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"max"})
+	if version > 0 {
+		rows.AddRow(version)
+	} else {
+		rows.AddRow(nil)
+	}
+	mock.ExpectQuery("SELECT MAX\\(version\\) FROM schema_migrations").WillReturnRows(rows)
+}
+
+func TestUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mig, err := New(db, testFS, Postgres)
+	require.NoError(t, err)
+
+	expectLock(mock, 1)
+	for _, v := range []struct {
+		query   string
+		version int
+		name    string
+	}{
+		{query: "ALTER TABLE urls ADD COLUMN clicks", version: 2, name: "add_clicks"},
+		{query: "CREATE INDEX urls_short_url_idx", version: 3, name: "index_short_url"},
+	} {
+		mock.ExpectBegin()
+		mock.ExpectExec(v.query).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(v.version, v.name).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	t.Run("apply new migrations", func(t *testing.T) {
+		assert.NoError(t, mig.Up(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mig, err := New(db, testFS, Postgres)
+	require.NoError(t, err)
+
+	expectLock(mock, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE urls DROP COLUMN clicks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	t.Run("revert last migration", func(t *testing.T) {
+		assert.NoError(t, mig.Down(context.Background(), 1))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mig, err := New(db, testFS, Postgres)
+	require.NoError(t, err)
+
+	t.Run("unknown version", func(t *testing.T) {
+		assert.ErrorIs(t, mig.To(context.Background(), 10), ErrUnknownVersion)
+	})
+
+	t.Run("down step is missing", func(t *testing.T) {
+		expectLock(mock, 3)
+		mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.Error(t, mig.To(context.Background(), 1))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed migration is rolled back", func(t *testing.T) {
+		expectLock(mock, 0)
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TABLE urls").WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+		mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.ErrorIs(t, mig.To(context.Background(), 1), assert.AnError)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
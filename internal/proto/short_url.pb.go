@@ -439,6 +439,8 @@ type DeleteUserUrlsResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
 }
 
 func (x *DeleteUserUrlsResponse) Reset() {
@@ -473,6 +475,257 @@ func (*DeleteUserUrlsResponse) Descriptor() ([]byte, []int) {
 	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{9}
 }
 
+func (x *DeleteUserUrlsResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type RestoreUserUrlsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Urls []string `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+}
+
+func (x *RestoreUserUrlsRequest) Reset() {
+	*x = RestoreUserUrlsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_short_url_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RestoreUserUrlsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreUserUrlsRequest) ProtoMessage() {}
+
+func (x *RestoreUserUrlsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_short_url_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreUserUrlsRequest.ProtoReflect.Descriptor instead.
+func (*RestoreUserUrlsRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RestoreUserUrlsRequest) GetUrls() []string {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+type RestoreUserUrlsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Restored []string `protobuf:"bytes,1,rep,name=restored,proto3" json:"restored,omitempty"`
+}
+
+func (x *RestoreUserUrlsResponse) Reset() {
+	*x = RestoreUserUrlsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_short_url_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RestoreUserUrlsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreUserUrlsResponse) ProtoMessage() {}
+
+func (x *RestoreUserUrlsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_short_url_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreUserUrlsResponse.ProtoReflect.Descriptor instead.
+func (*RestoreUserUrlsResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RestoreUserUrlsResponse) GetRestored() []string {
+	if x != nil {
+		return x.Restored
+	}
+	return nil
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_short_url_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_short_url_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type GetJobResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	JobId     string                      `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status    string                      `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Total     int32                       `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	Processed int32                       `protobuf:"varint,4,opt,name=processed,proto3" json:"processed,omitempty"`
+	Deleted   int32                       `protobuf:"varint,5,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Failed    int32                       `protobuf:"varint,6,opt,name=failed,proto3" json:"failed,omitempty"`
+	Results   []*GetJobResponse_UrlResult `protobuf:"bytes,7,rep,name=results,proto3" json:"results,omitempty"`
+	Errors    []string                    `protobuf:"bytes,8,rep,name=errors,proto3" json:"errors,omitempty"`
+}
+
+func (x *GetJobResponse) Reset() {
+	*x = GetJobResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_short_url_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobResponse) ProtoMessage() {}
+
+func (x *GetJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_short_url_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobResponse.ProtoReflect.Descriptor instead.
+func (*GetJobResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetJobResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *GetJobResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *GetJobResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *GetJobResponse) GetProcessed() int32 {
+	if x != nil {
+		return x.Processed
+	}
+	return 0
+}
+
+func (x *GetJobResponse) GetDeleted() int32 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
+func (x *GetJobResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *GetJobResponse) GetResults() []*GetJobResponse_UrlResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *GetJobResponse) GetErrors() []string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
 type GetStatsRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -482,7 +735,7 @@ type GetStatsRequest struct {
 func (x *GetStatsRequest) Reset() {
 	*x = GetStatsRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_proto_short_url_proto_msgTypes[10]
+		mi := &file_internal_proto_short_url_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -495,7 +748,7 @@ func (x *GetStatsRequest) String() string {
 func (*GetStatsRequest) ProtoMessage() {}
 
 func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_short_url_proto_msgTypes[10]
+	mi := &file_internal_proto_short_url_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -508,7 +761,173 @@ func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
 func (*GetStatsRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{10}
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{14}
+}
+
+type PoolStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MaxConns             int32 `protobuf:"varint,1,opt,name=max_conns,json=maxConns,proto3" json:"max_conns,omitempty"`
+	TotalConns           int32 `protobuf:"varint,2,opt,name=total_conns,json=totalConns,proto3" json:"total_conns,omitempty"`
+	IdleConns            int32 `protobuf:"varint,3,opt,name=idle_conns,json=idleConns,proto3" json:"idle_conns,omitempty"`
+	AcquiredConns        int32 `protobuf:"varint,4,opt,name=acquired_conns,json=acquiredConns,proto3" json:"acquired_conns,omitempty"`
+	AcquireCount         int64 `protobuf:"varint,5,opt,name=acquire_count,json=acquireCount,proto3" json:"acquire_count,omitempty"`
+	EmptyAcquireCount    int64 `protobuf:"varint,6,opt,name=empty_acquire_count,json=emptyAcquireCount,proto3" json:"empty_acquire_count,omitempty"`
+	CanceledAcquireCount int64 `protobuf:"varint,7,opt,name=canceled_acquire_count,json=canceledAcquireCount,proto3" json:"canceled_acquire_count,omitempty"`
+	AcquireDurationNs    int64 `protobuf:"varint,8,opt,name=acquire_duration_ns,json=acquireDurationNs,proto3" json:"acquire_duration_ns,omitempty"`
+}
+
+func (x *PoolStats) Reset() {
+	*x = PoolStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_short_url_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PoolStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PoolStats) ProtoMessage() {}
+
+func (x *PoolStats) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_short_url_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PoolStats.ProtoReflect.Descriptor instead.
+func (*PoolStats) Descriptor() ([]byte, []int) {
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PoolStats) GetMaxConns() int32 {
+	if x != nil {
+		return x.MaxConns
+	}
+	return 0
+}
+
+func (x *PoolStats) GetTotalConns() int32 {
+	if x != nil {
+		return x.TotalConns
+	}
+	return 0
+}
+
+func (x *PoolStats) GetIdleConns() int32 {
+	if x != nil {
+		return x.IdleConns
+	}
+	return 0
+}
+
+func (x *PoolStats) GetAcquiredConns() int32 {
+	if x != nil {
+		return x.AcquiredConns
+	}
+	return 0
+}
+
+func (x *PoolStats) GetAcquireCount() int64 {
+	if x != nil {
+		return x.AcquireCount
+	}
+	return 0
+}
+
+func (x *PoolStats) GetEmptyAcquireCount() int64 {
+	if x != nil {
+		return x.EmptyAcquireCount
+	}
+	return 0
+}
+
+func (x *PoolStats) GetCanceledAcquireCount() int64 {
+	if x != nil {
+		return x.CanceledAcquireCount
+	}
+	return 0
+}
+
+func (x *PoolStats) GetAcquireDurationNs() int64 {
+	if x != nil {
+		return x.AcquireDurationNs
+	}
+	return 0
+}
+
+type CacheStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hits    int64 `protobuf:"varint,1,opt,name=hits,proto3" json:"hits,omitempty"`
+	Misses  int64 `protobuf:"varint,2,opt,name=misses,proto3" json:"misses,omitempty"`
+	Entries int32 `protobuf:"varint,3,opt,name=entries,proto3" json:"entries,omitempty"`
+}
+
+func (x *CacheStats) Reset() {
+	*x = CacheStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_short_url_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CacheStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheStats) ProtoMessage() {}
+
+func (x *CacheStats) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_short_url_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheStats.ProtoReflect.Descriptor instead.
+func (*CacheStats) Descriptor() ([]byte, []int) {
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CacheStats) GetHits() int64 {
+	if x != nil {
+		return x.Hits
+	}
+	return 0
+}
+
+func (x *CacheStats) GetMisses() int64 {
+	if x != nil {
+		return x.Misses
+	}
+	return 0
+}
+
+func (x *CacheStats) GetEntries() int32 {
+	if x != nil {
+		return x.Entries
+	}
+	return 0
 }
 
 type GetStatsResponse struct {
@@ -516,14 +935,16 @@ type GetStatsResponse struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Urls  int32 `protobuf:"varint,1,opt,name=urls,proto3" json:"urls,omitempty"`
-	Users int32 `protobuf:"varint,2,opt,name=users,proto3" json:"users,omitempty"`
+	Urls  int32       `protobuf:"varint,1,opt,name=urls,proto3" json:"urls,omitempty"`
+	Users int32       `protobuf:"varint,2,opt,name=users,proto3" json:"users,omitempty"`
+	Pool  *PoolStats  `protobuf:"bytes,3,opt,name=pool,proto3" json:"pool,omitempty"`
+	Cache *CacheStats `protobuf:"bytes,4,opt,name=cache,proto3" json:"cache,omitempty"`
 }
 
 func (x *GetStatsResponse) Reset() {
 	*x = GetStatsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_proto_short_url_proto_msgTypes[11]
+		mi := &file_internal_proto_short_url_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -536,7 +957,7 @@ func (x *GetStatsResponse) String() string {
 func (*GetStatsResponse) ProtoMessage() {}
 
 func (x *GetStatsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_short_url_proto_msgTypes[11]
+	mi := &file_internal_proto_short_url_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -549,7 +970,7 @@ func (x *GetStatsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetStatsResponse.ProtoReflect.Descriptor instead.
 func (*GetStatsResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{11}
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *GetStatsResponse) GetUrls() int32 {
@@ -566,6 +987,20 @@ func (x *GetStatsResponse) GetUsers() int32 {
 	return 0
 }
 
+func (x *GetStatsResponse) GetPool() *PoolStats {
+	if x != nil {
+		return x.Pool
+	}
+	return nil
+}
+
+func (x *GetStatsResponse) GetCache() *CacheStats {
+	if x != nil {
+		return x.Cache
+	}
+	return nil
+}
+
 type GetPingRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -575,7 +1010,7 @@ type GetPingRequest struct {
 func (x *GetPingRequest) Reset() {
 	*x = GetPingRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_proto_short_url_proto_msgTypes[12]
+		mi := &file_internal_proto_short_url_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -588,7 +1023,7 @@ func (x *GetPingRequest) String() string {
 func (*GetPingRequest) ProtoMessage() {}
 
 func (x *GetPingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_short_url_proto_msgTypes[12]
+	mi := &file_internal_proto_short_url_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -601,7 +1036,7 @@ func (x *GetPingRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetPingRequest.ProtoReflect.Descriptor instead.
 func (*GetPingRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{12}
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{18}
 }
 
 type GetPingResponse struct {
@@ -613,7 +1048,7 @@ type GetPingResponse struct {
 func (x *GetPingResponse) Reset() {
 	*x = GetPingResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_proto_short_url_proto_msgTypes[13]
+		mi := &file_internal_proto_short_url_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -626,7 +1061,7 @@ func (x *GetPingResponse) String() string {
 func (*GetPingResponse) ProtoMessage() {}
 
 func (x *GetPingResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_short_url_proto_msgTypes[13]
+	mi := &file_internal_proto_short_url_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -639,7 +1074,7 @@ func (x *GetPingResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetPingResponse.ProtoReflect.Descriptor instead.
 func (*GetPingResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{13}
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{19}
 }
 
 type PostBatchRequest_RequestBatch struct {
@@ -654,7 +1089,7 @@ type PostBatchRequest_RequestBatch struct {
 func (x *PostBatchRequest_RequestBatch) Reset() {
 	*x = PostBatchRequest_RequestBatch{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_proto_short_url_proto_msgTypes[14]
+		mi := &file_internal_proto_short_url_proto_msgTypes[20]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -667,7 +1102,7 @@ func (x *PostBatchRequest_RequestBatch) String() string {
 func (*PostBatchRequest_RequestBatch) ProtoMessage() {}
 
 func (x *PostBatchRequest_RequestBatch) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_short_url_proto_msgTypes[14]
+	mi := &file_internal_proto_short_url_proto_msgTypes[20]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -704,12 +1139,13 @@ type PostBatchResponse_ResponseBatch struct {
 
 	CorrelationId string `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
 	ShortUrl      string `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	Status        string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
 }
 
 func (x *PostBatchResponse_ResponseBatch) Reset() {
 	*x = PostBatchResponse_ResponseBatch{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_proto_short_url_proto_msgTypes[15]
+		mi := &file_internal_proto_short_url_proto_msgTypes[21]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -722,7 +1158,7 @@ func (x *PostBatchResponse_ResponseBatch) String() string {
 func (*PostBatchResponse_ResponseBatch) ProtoMessage() {}
 
 func (x *PostBatchResponse_ResponseBatch) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_short_url_proto_msgTypes[15]
+	mi := &file_internal_proto_short_url_proto_msgTypes[21]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -752,6 +1188,13 @@ func (x *PostBatchResponse_ResponseBatch) GetShortUrl() string {
 	return ""
 }
 
+func (x *PostBatchResponse_ResponseBatch) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
 type GetUserUrlsResponse_UserUrl struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -764,7 +1207,7 @@ type GetUserUrlsResponse_UserUrl struct {
 func (x *GetUserUrlsResponse_UserUrl) Reset() {
 	*x = GetUserUrlsResponse_UserUrl{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_proto_short_url_proto_msgTypes[16]
+		mi := &file_internal_proto_short_url_proto_msgTypes[22]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -777,7 +1220,7 @@ func (x *GetUserUrlsResponse_UserUrl) String() string {
 func (*GetUserUrlsResponse_UserUrl) ProtoMessage() {}
 
 func (x *GetUserUrlsResponse_UserUrl) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_short_url_proto_msgTypes[16]
+	mi := &file_internal_proto_short_url_proto_msgTypes[22]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -807,6 +1250,69 @@ func (x *GetUserUrlsResponse_UserUrl) GetOriginalUrl() string {
 	return ""
 }
 
+type GetJobResponse_UrlResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ShortUrl string `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	Outcome  string `protobuf:"bytes,2,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	Error    string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *GetJobResponse_UrlResult) Reset() {
+	*x = GetJobResponse_UrlResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_short_url_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetJobResponse_UrlResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobResponse_UrlResult) ProtoMessage() {}
+
+func (x *GetJobResponse_UrlResult) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_short_url_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobResponse_UrlResult.ProtoReflect.Descriptor instead.
+func (*GetJobResponse_UrlResult) Descriptor() ([]byte, []int) {
+	return file_internal_proto_short_url_proto_rawDescGZIP(), []int{13, 0}
+}
+
+func (x *GetJobResponse_UrlResult) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *GetJobResponse_UrlResult) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+func (x *GetJobResponse_UrlResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 var File_internal_proto_short_url_proto protoreflect.FileDescriptor
 
 var file_internal_proto_short_url_proto_rawDesc = []byte{
@@ -836,75 +1342,148 @@ var file_internal_proto_short_url_proto_rawDesc = []byte{
 	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x63, 0x6f, 0x72, 0x72, 0x65,
 	0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67,
 	0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
-	0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x72, 0x6c, 0x22, 0xb9, 0x01, 0x0a, 0x11,
+	0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x72, 0x6c, 0x22, 0xd1, 0x01, 0x0a, 0x11,
 	0x50, 0x6f, 0x73, 0x74, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
 	0x65, 0x12, 0x4f, 0x0a, 0x0f, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x5f, 0x62, 0x61,
 	0x74, 0x63, 0x68, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x73, 0x74, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70,
 	0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x61, 0x74,
 	0x63, 0x68, 0x52, 0x0e, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x61, 0x74, 0x63,
-	0x68, 0x73, 0x1a, 0x53, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x61,
+	0x68, 0x73, 0x1a, 0x6b, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x61,
 	0x74, 0x63, 0x68, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69,
 	0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x63, 0x6f, 0x72,
 	0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x68,
 	0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73,
-	0x68, 0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x22, 0x14, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x55, 0x73,
-	0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xa1, 0x01,
-	0x0a, 0x13, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3f, 0x0a, 0x09, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x75, 0x72,
-	0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x2e, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x52, 0x08, 0x75, 0x73,
-	0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x1a, 0x49, 0x0a, 0x07, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72,
-	0x6c, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x21,
-	0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x72,
-	0x6c, 0x22, 0x32, 0x0a, 0x15, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55,
-	0x72, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x64, 0x65,
-	0x6c, 0x5f, 0x75, 0x72, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x64, 0x65,
-	0x6c, 0x55, 0x72, 0x6c, 0x73, 0x22, 0x18, 0x0a, 0x16, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55,
-	0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
-	0x11, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x22, 0x3c, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x75, 0x73,
-	0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73,
-	0x22, 0x10, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x22, 0x11, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0xc7, 0x03, 0x0a, 0x08, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x55,
-	0x72, 0x6c, 0x12, 0x35, 0x0a, 0x06, 0x47, 0x65, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x14, 0x2e, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x55, 0x72, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x55, 0x72,
-	0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x38, 0x0a, 0x07, 0x50, 0x6f, 0x73,
-	0x74, 0x55, 0x72, 0x6c, 0x12, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x73,
-	0x74, 0x55, 0x72, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x73, 0x74, 0x55, 0x72, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x3e, 0x0a, 0x09, 0x50, 0x6f, 0x73, 0x74, 0x42, 0x61, 0x74, 0x63, 0x68,
-	0x12, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x73, 0x74, 0x42, 0x61, 0x74,
-	0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x2e, 0x50, 0x6f, 0x73, 0x74, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x44, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72,
-	0x6c, 0x73, 0x12, 0x19, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x55, 0x73,
-	0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c,
-	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4d, 0x0a, 0x0e, 0x44, 0x65, 0x6c,
-	0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x12, 0x1c, 0x2e, 0x70, 0x72,
+	0x68, 0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x22,
+	0x14, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xa1, 0x01, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65,
+	0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3f, 0x0a,
+	0x09, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x75, 0x72, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x22, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72,
+	0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x55, 0x73, 0x65,
+	0x72, 0x55, 0x72, 0x6c, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x1a, 0x49,
+	0x0a, 0x07, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x68, 0x6f,
+	0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x68,
+	0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e,
+	0x61, 0x6c, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x72,
+	0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x55, 0x72, 0x6c, 0x22, 0x32, 0x0a, 0x15, 0x44, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x64, 0x65, 0x6c, 0x5f, 0x75, 0x72, 0x6c, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x64, 0x65, 0x6c, 0x55, 0x72, 0x6c, 0x73, 0x22, 0x2f, 0x0a,
+	0x16, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6a, 0x6f, 0x62, 0x49, 0x64, 0x22, 0x2c,
+	0x0a, 0x16, 0x52, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x22, 0x35, 0x0a, 0x17,
+	0x52, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x74, 0x6f,
+	0x72, 0x65, 0x64, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x73, 0x74, 0x6f,
+	0x72, 0x65, 0x64, 0x22, 0x26, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x15, 0x0a, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6a, 0x6f, 0x62, 0x49, 0x64, 0x22, 0xd2, 0x02, 0x0a, 0x0e,
+	0x47, 0x65, 0x74, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x15,
+	0x0a, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x6a, 0x6f, 0x62, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a,
+	0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x74, 0x6f,
+	0x74, 0x61, 0x6c, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x65, 0x64,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x65,
+	0x64, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x66,
+	0x61, 0x69, 0x6c, 0x65, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x66, 0x61, 0x69,
+	0x6c, 0x65, 0x64, 0x12, 0x39, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x07,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74,
+	0x4a, 0x6f, 0x62, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x55, 0x72, 0x6c, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x16,
+	0x0a, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x1a, 0x58, 0x0a, 0x09, 0x55, 0x72, 0x6c, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x55, 0x72, 0x6c,
+	0x12, 0x18, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x63, 0x6f, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x6f, 0x75, 0x74, 0x63, 0x6f, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x22, 0x11, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x22, 0xca, 0x02, 0x0a, 0x09, 0x50, 0x6f, 0x6f, 0x6c, 0x53, 0x74, 0x61, 0x74,
+	0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x61, 0x78, 0x5f, 0x63, 0x6f, 0x6e, 0x6e, 0x73, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x6d, 0x61, 0x78, 0x43, 0x6f, 0x6e, 0x6e, 0x73, 0x12, 0x1f,
+	0x0a, 0x0b, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x6e, 0x73, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x43, 0x6f, 0x6e, 0x6e, 0x73, 0x12,
+	0x1d, 0x0a, 0x0a, 0x69, 0x64, 0x6c, 0x65, 0x5f, 0x63, 0x6f, 0x6e, 0x6e, 0x73, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x09, 0x69, 0x64, 0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x6e, 0x73, 0x12, 0x25,
+	0x0a, 0x0e, 0x61, 0x63, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64, 0x5f, 0x63, 0x6f, 0x6e, 0x6e, 0x73,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x61, 0x63, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64,
+	0x43, 0x6f, 0x6e, 0x6e, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x61, 0x63, 0x71, 0x75, 0x69, 0x72, 0x65,
+	0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x61, 0x63,
+	0x71, 0x75, 0x69, 0x72, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x2e, 0x0a, 0x13, 0x65, 0x6d,
+	0x70, 0x74, 0x79, 0x5f, 0x61, 0x63, 0x71, 0x75, 0x69, 0x72, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x41, 0x63,
+	0x71, 0x75, 0x69, 0x72, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x34, 0x0a, 0x16, 0x63, 0x61,
+	0x6e, 0x63, 0x65, 0x6c, 0x65, 0x64, 0x5f, 0x61, 0x63, 0x71, 0x75, 0x69, 0x72, 0x65, 0x5f, 0x63,
+	0x6f, 0x75, 0x6e, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x14, 0x63, 0x61, 0x6e, 0x63,
+	0x65, 0x6c, 0x65, 0x64, 0x41, 0x63, 0x71, 0x75, 0x69, 0x72, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74,
+	0x12, 0x2e, 0x0a, 0x13, 0x61, 0x63, 0x71, 0x75, 0x69, 0x72, 0x65, 0x5f, 0x64, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6e, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x61,
+	0x63, 0x71, 0x75, 0x69, 0x72, 0x65, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4e, 0x73,
+	0x22, 0x52, 0x0a, 0x0a, 0x43, 0x61, 0x63, 0x68, 0x65, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x12,
+	0x0a, 0x04, 0x68, 0x69, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x68, 0x69,
+	0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x69, 0x73, 0x73, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x06, 0x6d, 0x69, 0x73, 0x73, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e,
+	0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x65, 0x6e, 0x74,
+	0x72, 0x69, 0x65, 0x73, 0x22, 0x8b, 0x01, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x12, 0x14, 0x0a,
+	0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x75, 0x73,
+	0x65, 0x72, 0x73, 0x12, 0x24, 0x0a, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x6f, 0x6c, 0x53, 0x74,
+	0x61, 0x74, 0x73, 0x52, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x27, 0x0a, 0x05, 0x63, 0x61, 0x63,
+	0x68, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x43, 0x61, 0x63, 0x68, 0x65, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x05, 0x63, 0x61, 0x63,
+	0x68, 0x65, 0x22, 0x10, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x22, 0x11, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x50, 0x69, 0x6e, 0x67, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0xd0, 0x04, 0x0a, 0x08, 0x53, 0x68, 0x6f, 0x72,
+	0x74, 0x55, 0x72, 0x6c, 0x12, 0x35, 0x0a, 0x06, 0x47, 0x65, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x14,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x55, 0x72, 0x6c, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74,
+	0x55, 0x72, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x38, 0x0a, 0x07, 0x50,
+	0x6f, 0x73, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50,
+	0x6f, 0x73, 0x74, 0x55, 0x72, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x73, 0x74, 0x55, 0x72, 0x6c, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3e, 0x0a, 0x09, 0x50, 0x6f, 0x73, 0x74, 0x42, 0x61, 0x74,
+	0x63, 0x68, 0x12, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x73, 0x74, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x50, 0x6f, 0x73, 0x74, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x44, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72,
+	0x55, 0x72, 0x6c, 0x73, 0x12, 0x19, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74,
+	0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x55,
+	0x72, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4d, 0x0a, 0x0e, 0x44,
+	0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x12, 0x1c, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72,
+	0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x70, 0x72,
 	0x6f, 0x74, 0x6f, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72,
-	0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x53,
-	0x74, 0x61, 0x74, 0x73, 0x12, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74,
-	0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x38, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x50, 0x69, 0x6e, 0x67,
-	0x12, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x69, 0x6e, 0x67,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
-	0x47, 0x65, 0x74, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42,
-	0x37, 0x5a, 0x35, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x4a, 0x75,
-	0x6c, 0x69, 0x61, 0x2d, 0x69, 0x76, 0x76, 0x2f, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65,
-	0x72, 0x2d, 0x75, 0x72, 0x6c, 0x2e, 0x67, 0x69, 0x74, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e,
-	0x61, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x50, 0x0a, 0x0f, 0x52, 0x65,
+	0x73, 0x74, 0x6f, 0x72, 0x65, 0x55, 0x73, 0x65, 0x72, 0x55, 0x72, 0x6c, 0x73, 0x12, 0x1d, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x55, 0x73, 0x65,
+	0x72, 0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x55, 0x73, 0x65, 0x72,
+	0x55, 0x72, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x06,
+	0x47, 0x65, 0x74, 0x4a, 0x6f, 0x62, 0x12, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47,
+	0x65, 0x74, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12,
+	0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x38, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x15, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x69,
+	0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x37, 0x5a, 0x35, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x4a, 0x75, 0x6c, 0x69, 0x61, 0x2d, 0x69,
+	0x76, 0x76, 0x2f, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x2d, 0x75, 0x72, 0x6c,
+	0x2e, 0x67, 0x69, 0x74, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -919,7 +1498,7 @@ func file_internal_proto_short_url_proto_rawDescGZIP() []byte {
 	return file_internal_proto_short_url_proto_rawDescData
 }
 
-var file_internal_proto_short_url_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_internal_proto_short_url_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
 var file_internal_proto_short_url_proto_goTypes = []interface{}{
 	(*GetUrlRequest)(nil),                   // 0: proto.GetUrlRequest
 	(*GetUrlResponse)(nil),                  // 1: proto.GetUrlResponse
@@ -931,37 +1510,51 @@ var file_internal_proto_short_url_proto_goTypes = []interface{}{
 	(*GetUserUrlsResponse)(nil),             // 7: proto.GetUserUrlsResponse
 	(*DeleteUserUrlsRequest)(nil),           // 8: proto.DeleteUserUrlsRequest
 	(*DeleteUserUrlsResponse)(nil),          // 9: proto.DeleteUserUrlsResponse
-	(*GetStatsRequest)(nil),                 // 10: proto.GetStatsRequest
-	(*GetStatsResponse)(nil),                // 11: proto.GetStatsResponse
-	(*GetPingRequest)(nil),                  // 12: proto.GetPingRequest
-	(*GetPingResponse)(nil),                 // 13: proto.GetPingResponse
-	(*PostBatchRequest_RequestBatch)(nil),   // 14: proto.PostBatchRequest.RequestBatch
-	(*PostBatchResponse_ResponseBatch)(nil), // 15: proto.PostBatchResponse.ResponseBatch
-	(*GetUserUrlsResponse_UserUrl)(nil),     // 16: proto.GetUserUrlsResponse.UserUrl
+	(*RestoreUserUrlsRequest)(nil),          // 10: proto.RestoreUserUrlsRequest
+	(*RestoreUserUrlsResponse)(nil),         // 11: proto.RestoreUserUrlsResponse
+	(*GetJobRequest)(nil),                   // 12: proto.GetJobRequest
+	(*GetJobResponse)(nil),                  // 13: proto.GetJobResponse
+	(*GetStatsRequest)(nil),                 // 14: proto.GetStatsRequest
+	(*PoolStats)(nil),                       // 15: proto.PoolStats
+	(*CacheStats)(nil),                      // 16: proto.CacheStats
+	(*GetStatsResponse)(nil),                // 17: proto.GetStatsResponse
+	(*GetPingRequest)(nil),                  // 18: proto.GetPingRequest
+	(*GetPingResponse)(nil),                 // 19: proto.GetPingResponse
+	(*PostBatchRequest_RequestBatch)(nil),   // 20: proto.PostBatchRequest.RequestBatch
+	(*PostBatchResponse_ResponseBatch)(nil), // 21: proto.PostBatchResponse.ResponseBatch
+	(*GetUserUrlsResponse_UserUrl)(nil),     // 22: proto.GetUserUrlsResponse.UserUrl
+	(*GetJobResponse_UrlResult)(nil),        // 23: proto.GetJobResponse.UrlResult
 }
 var file_internal_proto_short_url_proto_depIdxs = []int32{
-	14, // 0: proto.PostBatchRequest.request_batchs:type_name -> proto.PostBatchRequest.RequestBatch
-	15, // 1: proto.PostBatchResponse.response_batchs:type_name -> proto.PostBatchResponse.ResponseBatch
-	16, // 2: proto.GetUserUrlsResponse.user_urls:type_name -> proto.GetUserUrlsResponse.UserUrl
-	0,  // 3: proto.ShortUrl.GetUrl:input_type -> proto.GetUrlRequest
-	2,  // 4: proto.ShortUrl.PostUrl:input_type -> proto.PostUrlRequest
-	4,  // 5: proto.ShortUrl.PostBatch:input_type -> proto.PostBatchRequest
-	6,  // 6: proto.ShortUrl.GetUserUrls:input_type -> proto.GetUserUrlsRequest
-	8,  // 7: proto.ShortUrl.DeleteUserUrls:input_type -> proto.DeleteUserUrlsRequest
-	10, // 8: proto.ShortUrl.GetStats:input_type -> proto.GetStatsRequest
-	12, // 9: proto.ShortUrl.GetPing:input_type -> proto.GetPingRequest
-	1,  // 10: proto.ShortUrl.GetUrl:output_type -> proto.GetUrlResponse
-	3,  // 11: proto.ShortUrl.PostUrl:output_type -> proto.PostUrlResponse
-	5,  // 12: proto.ShortUrl.PostBatch:output_type -> proto.PostBatchResponse
-	7,  // 13: proto.ShortUrl.GetUserUrls:output_type -> proto.GetUserUrlsResponse
-	9,  // 14: proto.ShortUrl.DeleteUserUrls:output_type -> proto.DeleteUserUrlsResponse
-	11, // 15: proto.ShortUrl.GetStats:output_type -> proto.GetStatsResponse
-	13, // 16: proto.ShortUrl.GetPing:output_type -> proto.GetPingResponse
-	10, // [10:17] is the sub-list for method output_type
-	3,  // [3:10] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	20, // 0: proto.PostBatchRequest.request_batchs:type_name -> proto.PostBatchRequest.RequestBatch
+	21, // 1: proto.PostBatchResponse.response_batchs:type_name -> proto.PostBatchResponse.ResponseBatch
+	22, // 2: proto.GetUserUrlsResponse.user_urls:type_name -> proto.GetUserUrlsResponse.UserUrl
+	23, // 3: proto.GetJobResponse.results:type_name -> proto.GetJobResponse.UrlResult
+	15, // 4: proto.GetStatsResponse.pool:type_name -> proto.PoolStats
+	16, // 5: proto.GetStatsResponse.cache:type_name -> proto.CacheStats
+	0,  // 6: proto.ShortUrl.GetUrl:input_type -> proto.GetUrlRequest
+	2,  // 7: proto.ShortUrl.PostUrl:input_type -> proto.PostUrlRequest
+	4,  // 8: proto.ShortUrl.PostBatch:input_type -> proto.PostBatchRequest
+	6,  // 9: proto.ShortUrl.GetUserUrls:input_type -> proto.GetUserUrlsRequest
+	8,  // 10: proto.ShortUrl.DeleteUserUrls:input_type -> proto.DeleteUserUrlsRequest
+	10, // 11: proto.ShortUrl.RestoreUserUrls:input_type -> proto.RestoreUserUrlsRequest
+	12, // 12: proto.ShortUrl.GetJob:input_type -> proto.GetJobRequest
+	14, // 13: proto.ShortUrl.GetStats:input_type -> proto.GetStatsRequest
+	18, // 14: proto.ShortUrl.GetPing:input_type -> proto.GetPingRequest
+	1,  // 15: proto.ShortUrl.GetUrl:output_type -> proto.GetUrlResponse
+	3,  // 16: proto.ShortUrl.PostUrl:output_type -> proto.PostUrlResponse
+	5,  // 17: proto.ShortUrl.PostBatch:output_type -> proto.PostBatchResponse
+	7,  // 18: proto.ShortUrl.GetUserUrls:output_type -> proto.GetUserUrlsResponse
+	9,  // 19: proto.ShortUrl.DeleteUserUrls:output_type -> proto.DeleteUserUrlsResponse
+	11, // 20: proto.ShortUrl.RestoreUserUrls:output_type -> proto.RestoreUserUrlsResponse
+	13, // 21: proto.ShortUrl.GetJob:output_type -> proto.GetJobResponse
+	17, // 22: proto.ShortUrl.GetStats:output_type -> proto.GetStatsResponse
+	19, // 23: proto.ShortUrl.GetPing:output_type -> proto.GetPingResponse
+	15, // [15:24] is the sub-list for method output_type
+	6,  // [6:15] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_internal_proto_short_url_proto_init() }
@@ -1091,7 +1684,7 @@ func file_internal_proto_short_url_proto_init() {
 			}
 		}
 		file_internal_proto_short_url_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetStatsRequest); i {
+			switch v := v.(*RestoreUserUrlsRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1103,7 +1696,7 @@ func file_internal_proto_short_url_proto_init() {
 			}
 		}
 		file_internal_proto_short_url_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetStatsResponse); i {
+			switch v := v.(*RestoreUserUrlsResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1115,7 +1708,7 @@ func file_internal_proto_short_url_proto_init() {
 			}
 		}
 		file_internal_proto_short_url_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetPingRequest); i {
+			switch v := v.(*GetJobRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1127,7 +1720,7 @@ func file_internal_proto_short_url_proto_init() {
 			}
 		}
 		file_internal_proto_short_url_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetPingResponse); i {
+			switch v := v.(*GetJobResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1139,7 +1732,7 @@ func file_internal_proto_short_url_proto_init() {
 			}
 		}
 		file_internal_proto_short_url_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PostBatchRequest_RequestBatch); i {
+			switch v := v.(*GetStatsRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1151,7 +1744,7 @@ func file_internal_proto_short_url_proto_init() {
 			}
 		}
 		file_internal_proto_short_url_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PostBatchResponse_ResponseBatch); i {
+			switch v := v.(*PoolStats); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1163,6 +1756,78 @@ func file_internal_proto_short_url_proto_init() {
 			}
 		}
 		file_internal_proto_short_url_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CacheStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_short_url_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetStatsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_short_url_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPingRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_short_url_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPingResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_short_url_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PostBatchRequest_RequestBatch); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_short_url_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PostBatchResponse_ResponseBatch); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_short_url_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*GetUserUrlsResponse_UserUrl); i {
 			case 0:
 				return &v.state
@@ -1174,6 +1839,18 @@ func file_internal_proto_short_url_proto_init() {
 				return nil
 			}
 		}
+		file_internal_proto_short_url_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetJobResponse_UrlResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1181,7 +1858,7 @@ func file_internal_proto_short_url_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_internal_proto_short_url_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   17,
+			NumMessages:   24,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,13 +20,15 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	ShortUrl_GetUrl_FullMethodName         = "/proto.ShortUrl/GetUrl"
-	ShortUrl_PostUrl_FullMethodName        = "/proto.ShortUrl/PostUrl"
-	ShortUrl_PostBatch_FullMethodName      = "/proto.ShortUrl/PostBatch"
-	ShortUrl_GetUserUrls_FullMethodName    = "/proto.ShortUrl/GetUserUrls"
-	ShortUrl_DeleteUserUrls_FullMethodName = "/proto.ShortUrl/DeleteUserUrls"
-	ShortUrl_GetStats_FullMethodName       = "/proto.ShortUrl/GetStats"
-	ShortUrl_GetPing_FullMethodName        = "/proto.ShortUrl/GetPing"
+	ShortUrl_GetUrl_FullMethodName          = "/proto.ShortUrl/GetUrl"
+	ShortUrl_PostUrl_FullMethodName         = "/proto.ShortUrl/PostUrl"
+	ShortUrl_PostBatch_FullMethodName       = "/proto.ShortUrl/PostBatch"
+	ShortUrl_GetUserUrls_FullMethodName     = "/proto.ShortUrl/GetUserUrls"
+	ShortUrl_DeleteUserUrls_FullMethodName  = "/proto.ShortUrl/DeleteUserUrls"
+	ShortUrl_RestoreUserUrls_FullMethodName = "/proto.ShortUrl/RestoreUserUrls"
+	ShortUrl_GetJob_FullMethodName          = "/proto.ShortUrl/GetJob"
+	ShortUrl_GetStats_FullMethodName        = "/proto.ShortUrl/GetStats"
+	ShortUrl_GetPing_FullMethodName         = "/proto.ShortUrl/GetPing"
 )
 
 // ShortUrlClient is the client API for ShortUrl service.
@@ -38,6 +40,8 @@ type ShortUrlClient interface {
 	PostBatch(ctx context.Context, in *PostBatchRequest, opts ...grpc.CallOption) (*PostBatchResponse, error)
 	GetUserUrls(ctx context.Context, in *GetUserUrlsRequest, opts ...grpc.CallOption) (*GetUserUrlsResponse, error)
 	DeleteUserUrls(ctx context.Context, in *DeleteUserUrlsRequest, opts ...grpc.CallOption) (*DeleteUserUrlsResponse, error)
+	RestoreUserUrls(ctx context.Context, in *RestoreUserUrlsRequest, opts ...grpc.CallOption) (*RestoreUserUrlsResponse, error)
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*GetJobResponse, error)
 	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
 	GetPing(ctx context.Context, in *GetPingRequest, opts ...grpc.CallOption) (*GetPingResponse, error)
 }
@@ -95,6 +99,24 @@ func (c *shortUrlClient) DeleteUserUrls(ctx context.Context, in *DeleteUserUrlsR
 	return out, nil
 }
 
+func (c *shortUrlClient) RestoreUserUrls(ctx context.Context, in *RestoreUserUrlsRequest, opts ...grpc.CallOption) (*RestoreUserUrlsResponse, error) {
+	out := new(RestoreUserUrlsResponse)
+	err := c.cc.Invoke(ctx, ShortUrl_RestoreUserUrls_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortUrlClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*GetJobResponse, error) {
+	out := new(GetJobResponse)
+	err := c.cc.Invoke(ctx, ShortUrl_GetJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *shortUrlClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
 	out := new(GetStatsResponse)
 	err := c.cc.Invoke(ctx, ShortUrl_GetStats_FullMethodName, in, out, opts...)
@@ -122,6 +144,8 @@ type ShortUrlServer interface {
 	PostBatch(context.Context, *PostBatchRequest) (*PostBatchResponse, error)
 	GetUserUrls(context.Context, *GetUserUrlsRequest) (*GetUserUrlsResponse, error)
 	DeleteUserUrls(context.Context, *DeleteUserUrlsRequest) (*DeleteUserUrlsResponse, error)
+	RestoreUserUrls(context.Context, *RestoreUserUrlsRequest) (*RestoreUserUrlsResponse, error)
+	GetJob(context.Context, *GetJobRequest) (*GetJobResponse, error)
 	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
 	GetPing(context.Context, *GetPingRequest) (*GetPingResponse, error)
 	mustEmbedUnimplementedShortUrlServer()
@@ -146,6 +170,12 @@ func (UnimplementedShortUrlServer) GetUserUrls(context.Context, *GetUserUrlsRequ
 func (UnimplementedShortUrlServer) DeleteUserUrls(context.Context, *DeleteUserUrlsRequest) (*DeleteUserUrlsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserUrls not implemented")
 }
+func (UnimplementedShortUrlServer) RestoreUserUrls(context.Context, *RestoreUserUrlsRequest) (*RestoreUserUrlsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreUserUrls not implemented")
+}
+func (UnimplementedShortUrlServer) GetJob(context.Context, *GetJobRequest) (*GetJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
 func (UnimplementedShortUrlServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
 }
@@ -255,6 +285,42 @@ func _ShortUrl_DeleteUserUrls_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShortUrl_RestoreUserUrls_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreUserUrlsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortUrlServer).RestoreUserUrls(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShortUrl_RestoreUserUrls_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortUrlServer).RestoreUserUrls(ctx, req.(*RestoreUserUrlsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortUrl_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortUrlServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShortUrl_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortUrlServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ShortUrl_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetStatsRequest)
 	if err := dec(in); err != nil {
@@ -318,6 +384,14 @@ var ShortUrl_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteUserUrls",
 			Handler:    _ShortUrl_DeleteUserUrls_Handler,
 		},
+		{
+			MethodName: "RestoreUserUrls",
+			Handler:    _ShortUrl_RestoreUserUrls_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _ShortUrl_GetJob_Handler,
+		},
 		{
 			MethodName: "GetStats",
 			Handler:    _ShortUrl_GetStats_Handler,
@@ -0,0 +1,48 @@
+// Package purger periodically removes URLs that were deleted long ago.
+package purger
+
+import (
+	"context"
+	"time"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+)
+
+// Storage permanently removes deleted URLs.
+type Storage interface {
+	// PurgeDeletedURLs removes URLs deleted before deletedBefore and returns their number.
+	PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error)
+}
+
+// Run removes URLs deleted more than retention ago, first at start and then every interval,
+// until ctx is done. The results are written to the logger of ctx. Does nothing if retention or interval is not positive.
+func Run(ctx context.Context, stor Storage, retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purge(ctx, stor, retention)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge removes URLs deleted more than retention ago once.
+func purge(ctx context.Context, stor Storage, retention time.Duration) {
+	purged, err := stor.PurgeDeletedURLs(ctx, time.Now().Add(-retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.FromContext(ctx).Infow("purge deleted URLs", "error", err)
+		}
+		return
+	}
+	if purged > 0 {
+		logger.FromContext(ctx).Infow("purge deleted URLs", "purged", purged)
+	}
+}
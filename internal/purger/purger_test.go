@@ -0,0 +1,60 @@
+package purger
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testStorage counts purge calls.
+type testStorage struct {
+	calls  int
+	before time.Time
+	sync.Mutex
+}
+
+func (s *testStorage) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.calls++
+	s.before = deletedBefore
+	return 0, nil
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention time.Duration
+		wantCalls bool
+	}{
+		{
+			name:      "purge enabled",
+			retention: time.Hour,
+			wantCalls: true,
+		},
+		{
+			name:      "purge disabled",
+			retention: 0,
+			wantCalls: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stor := &testStorage{}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			Run(ctx, stor, test.retention, 10*time.Millisecond)
+
+			stor.Lock()
+			defer stor.Unlock()
+			assert.Equal(t, test.wantCalls, stor.calls > 1)
+			if test.wantCalls {
+				assert.WithinDuration(t, time.Now().Add(-test.retention), stor.before, time.Second)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Package requestid identifies requests, so the log records of a request can be found by its ID.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Header - the HTTP header of the request ID, the same key in lower case is used in gRPC metadata.
+const Header = "X-Request-ID"
+
+// maxLen - the maximum length of a request ID accepted from a client.
+const maxLen = 128
+
+// ctxKey - the context key of the request ID.
+type ctxKey struct{}
+
+// New generates a random request ID.
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether the request ID received from a client can be used:
+// it is not empty, not longer than 128 characters and consists of printable ASCII characters.
+func Valid(id string) bool {
+	if len(id) == 0 || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the request ID received from the client or a new one if it is missing or not valid.
+func Get(id string) string {
+	if Valid(id) {
+		return id
+	}
+	return New()
+}
+
+// NewContext returns a copy of ctx with the request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID of the context, empty if the context has none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// ClientIP returns the IP address of the client:
+// the X-Real-IP header, the first address of the X-Forwarded-For header or the remote address.
+func ClientIP(req *http.Request) string {
+	if ip := strings.TrimSpace(req.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	if fwd := req.Header.Get("X-Forwarded-For"); fwd != "" {
+		ip, _, _ := strings.Cut(fwd, ",")
+		return strings.TrimSpace(ip)
+	}
+	return HostIP(req.RemoteAddr)
+}
+
+// HostIP returns the host part of the address, the address itself if it has no port.
+func HostIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
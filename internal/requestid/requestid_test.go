@@ -0,0 +1,68 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "uuid", id: "3f2504e0-4f89-11d3-9a0c-0305e82c3301", want: true},
+		{name: "empty", id: "", want: false},
+		{name: "too long", id: strings.Repeat("a", maxLen+1), want: false},
+		{name: "space", id: "req 1", want: false},
+		{name: "new line", id: "req1\nlevel=error", want: false},
+		{name: "not ascii", id: "запрос", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, Valid(test.id))
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	assert.Equal(t, "req-1", Get("req-1"))
+	id := Get("bad id")
+	assert.Len(t, id, 32)
+	assert.True(t, Valid(id))
+	assert.NotEqual(t, id, Get(""))
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, FromContext(ctx))
+	assert.Equal(t, "req-1", FromContext(NewContext(ctx, "req-1")))
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   string
+	}{
+		{name: "remote address", want: "192.0.2.1"},
+		{name: "real ip", header: map[string]string{"X-Real-IP": "10.0.0.1", "X-Forwarded-For": "10.0.0.2"}, want: "10.0.0.1"},
+		{name: "forwarded for", header: map[string]string{"X-Forwarded-For": "10.0.0.2, 10.0.0.3"}, want: "10.0.0.2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range test.header {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, test.want, ClientIP(req))
+		})
+	}
+	assert.Equal(t, "::1", HostIP("[::1]:8080"))
+	assert.Equal(t, "pipe", HostIP("pipe"))
+}
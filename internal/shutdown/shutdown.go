@@ -0,0 +1,171 @@
+// Package shutdown stops the parts of the application in order within a common deadline.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// forceWait - how long a step is waited for after the deadline, it is enough to close connections.
+const forceWait = time.Second
+
+// StopFunc stops a part of the application. When ctx is done, it should stop immediately.
+type StopFunc func(ctx context.Context) error
+
+// step - a named part of the application.
+type step struct {
+	name string
+	stop StopFunc
+}
+
+// Sequence stops the added parts in the order they were added.
+type Sequence struct {
+	steps []step
+	log   *zap.SugaredLogger
+}
+
+// NewSequence creates an empty sequence, log receives the progress and errors of the steps.
+func NewSequence(log *zap.SugaredLogger) *Sequence {
+	return &Sequence{log: log}
+}
+
+// Add adds a step to the end of the sequence.
+func (s *Sequence) Add(name string, stop StopFunc) {
+	s.steps = append(s.steps, step{name: name, stop: stop})
+}
+
+// Run runs the steps one by one and returns their errors.
+// A step that fails does not stop the sequence. When ctx is done, the steps are expected to
+// force-stop; if a step still does not return, Run does not wait for it and skips the remaining steps.
+func (s *Sequence) Run(ctx context.Context) error {
+	var errs []error
+	for k, st := range s.steps {
+		start := time.Now()
+		done := make(chan error, 1)
+		go func() {
+			done <- st.stop(ctx)
+		}()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			select {
+			case err = <-done:
+			case <-time.After(forceWait):
+				skipped := make([]string, 0, len(s.steps)-k-1)
+				for _, rest := range s.steps[k+1:] {
+					skipped = append(skipped, rest.name)
+				}
+				s.log.Errorw("shutdown deadline exceeded", "step", st.name, "skipped", skipped)
+				return errors.Join(append(errs, fmt.Errorf("%s: %w", st.name, ctx.Err()))...)
+			}
+		}
+
+		if err != nil {
+			s.log.Errorw("shutdown step failed", "step", st.name, "duration", time.Since(start), "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", st.name, err))
+			continue
+		}
+		s.log.Infow("shutdown step done", "step", st.name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// HTTP stops the server accepting connections and waits for the active requests,
+// the remaining connections are closed when ctx is done.
+func HTTP(srv *http.Server) StopFunc {
+	return func(ctx context.Context) error {
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			return errors.Join(err, srv.Close())
+		}
+		return nil
+	}
+}
+
+// GRPC stops the server accepting connections and waits for the active calls,
+// the remaining calls are cancelled when ctx is done.
+func GRPC(srv *grpc.Server) StopFunc {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			srv.Stop()
+			<-done
+			return ctx.Err()
+		}
+	}
+}
+
+// Parallel runs the stop functions at the same time, e.g. of the servers that drain requests,
+// and returns their errors.
+func Parallel(stops ...StopFunc) StopFunc {
+	return func(ctx context.Context) error {
+		errs := make([]error, len(stops))
+		var wg sync.WaitGroup
+		for k, stop := range stops {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[k] = stop(ctx)
+			}()
+		}
+		wg.Wait()
+		return errors.Join(errs...)
+	}
+}
+
+// Go runs fn in a goroutine with a copy of ctx and returns the function that cancels
+// the copy and waits for fn to return.
+func Go(ctx context.Context, fn func(ctx context.Context)) StopFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+	return func(stopCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return stopCtx.Err()
+		}
+	}
+}
+
+// Delay waits for d, e.g. for the load balancer to notice the service is not ready,
+// the wait ends early when ctx is done.
+func Delay(d time.Duration) StopFunc {
+	return func(ctx context.Context) error {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+}
+
+// Func adapts a function without a context, e.g. Close, to StopFunc.
+func Func(fn func() error) StopFunc {
+	return func(ctx context.Context) error {
+		return fn()
+	}
+}
@@ -0,0 +1,150 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+)
+
+func TestSequenceRun(t *testing.T) {
+	errStep := errors.New("close error")
+	var order []string
+	record := func(name string, err error) StopFunc {
+		return func(ctx context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+
+	t.Run("all steps run in order", func(t *testing.T) {
+		order = nil
+		s := NewSequence(zaptest.NewLogger(t).Sugar())
+		s.Add("servers", record("servers", nil))
+		s.Add("storage", record("storage", errStep))
+		s.Add("tracing", record("tracing", nil))
+
+		err := s.Run(context.Background())
+		assert.ErrorIs(t, err, errStep, "the error of a step is returned")
+		assert.Equal(t, []string{"servers", "storage", "tracing"}, order, "a failed step does not stop the sequence")
+	})
+
+	t.Run("hung step after the deadline", func(t *testing.T) {
+		order = nil
+		s := NewSequence(zaptest.NewLogger(t).Sugar())
+		s.Add("servers", record("servers", nil))
+		s.Add("deletions", func(ctx context.Context) error {
+			<-ctx.Done()
+			order = append(order, "deletions")
+			return ctx.Err()
+		})
+		s.Add("hung", func(ctx context.Context) error {
+			select {}
+		})
+		s.Add("storage", record("storage", nil))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := s.Run(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 10*time.Millisecond+2*forceWait)
+		assert.Equal(t, []string{"servers", "deletions"}, order, "the steps after the hung one are skipped")
+	})
+}
+
+func TestHTTP(t *testing.T) {
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-req.Context().Done()
+	})}
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(listen)
+	go http.Get("http://" + listen.Addr().String())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = HTTP(srv)(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "the active request is not finished before the deadline")
+
+	_, err = http.Get("http://" + listen.Addr().String())
+	assert.Error(t, err, "the server is closed")
+}
+
+func TestParallel(t *testing.T) {
+	release := make(chan struct{})
+	wait := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+	done := make(chan error)
+	go func() {
+		done <- Parallel(wait, wait, func(ctx context.Context) error {
+			close(release)
+			return errors.New("stop error")
+		})(context.Background())
+	}()
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the stop functions are not run at the same time")
+	}
+}
+
+func TestGo(t *testing.T) {
+	exited := false
+	stop := Go(context.Background(), func(ctx context.Context) {
+		<-ctx.Done()
+		exited = true
+	})
+	assert.NoError(t, stop(context.Background()))
+	assert.True(t, exited, "stop waits for the goroutine")
+
+	stop = Go(context.Background(), func(ctx context.Context) {
+		select {}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, stop(ctx), context.DeadlineExceeded)
+}
+
+func TestDelay(t *testing.T) {
+	start := time.Now()
+	assert.NoError(t, Delay(20*time.Millisecond)(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	assert.NoError(t, Delay(time.Minute)(ctx), "the wait ends with ctx")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestGRPC(t *testing.T) {
+	srv := grpc.NewServer()
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan struct{})
+	go func() {
+		srv.Serve(listen)
+		close(served)
+	}()
+
+	assert.NoError(t, GRPC(srv)(context.Background()))
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("the server is still serving")
+	}
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/migrator"
+)
+
+// migrationFiles contains the Postgres migrations and the SQLite versions of them
+// in the sqlite directory, both sets have the same versions and names.
+//
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// NewMigrator creates a migrator with the Postgres schema migrations.
+func NewMigrator(db *sql.DB) (*migrator.Migrator, error) {
+	fsys, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrator.New(db, fsys, migrator.Postgres)
+}
+
+// NewSQLiteMigrator creates a migrator with the SQLite schema migrations.
+func NewSQLiteMigrator(db *sql.DB) (*migrator.Migrator, error) {
+	fsys, err := fs.Sub(migrationFiles, "migrations/sqlite")
+	if err != nil {
+		return nil, err
+	}
+	return migrator.New(db, fsys, migrator.SQLite)
+}
+
+// pendingMigrations returns the number of migrations in dir newer than the applied version.
+// The version is read without the migration lock, so the schema can be checked while another instance migrates.
+func pendingMigrations(dir string, version int) (int, error) {
+	fsys, err := fs.Sub(migrationFiles, dir)
+	if err != nil {
+		return 0, err
+	}
+	migrations, err := migrator.Load(fsys)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, mig := range migrations {
+		if mig.Version > version {
+			pending++
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS urls (
+    user_id integer,
+    short_url text,
+    original_url text,
+    deleted_flag boolean DEFAULT false,
+    PRIMARY KEY(user_id, original_url)
+);
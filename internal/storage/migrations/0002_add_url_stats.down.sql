@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS clicks,
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS clicks bigint NOT NULL DEFAULT 0;
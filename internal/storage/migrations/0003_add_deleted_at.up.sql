@@ -0,0 +1,2 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+UPDATE urls SET deleted_at = now() WHERE deleted_flag AND deleted_at IS NULL;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS urls_short_url_idx ON urls (short_url);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS urls_short_url_idx;
+CREATE INDEX IF NOT EXISTS urls_short_url_idx ON urls (short_url);
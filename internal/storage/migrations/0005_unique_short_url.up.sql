@@ -0,0 +1,11 @@
+DO $$
+DECLARE
+    dup text;
+BEGIN
+    SELECT short_url INTO dup FROM urls GROUP BY short_url HAVING count(*) > 1 LIMIT 1;
+    IF FOUND THEN
+        RAISE EXCEPTION 'short URL % is used by several URLs, remove the duplicates before the migration', dup;
+    END IF;
+END $$;
+DROP INDEX IF EXISTS urls_short_url_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS urls_short_url_idx ON urls (short_url);
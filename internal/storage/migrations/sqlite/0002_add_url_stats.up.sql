@@ -0,0 +1,13 @@
+CREATE TABLE urls_new (
+    user_id integer,
+    short_url text,
+    original_url text,
+    deleted_flag boolean DEFAULT false,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    clicks integer NOT NULL DEFAULT 0,
+    PRIMARY KEY(user_id, original_url)
+);
+INSERT INTO urls_new (user_id, short_url, original_url, deleted_flag)
+    SELECT user_id, short_url, original_url, deleted_flag FROM urls;
+DROP TABLE urls;
+ALTER TABLE urls_new RENAME TO urls;
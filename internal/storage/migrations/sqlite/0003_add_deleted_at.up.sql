@@ -0,0 +1,2 @@
+ALTER TABLE urls ADD COLUMN deleted_at timestamp;
+UPDATE urls SET deleted_at = CURRENT_TIMESTAMP WHERE deleted_flag AND deleted_at IS NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS urls_short_url_idx;
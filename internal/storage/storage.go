@@ -2,8 +2,17 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/pkg/randomizer"
 )
 
 // RequestBatch stores data from the request body for adding a batch of URLs.
@@ -22,6 +31,31 @@ type ResponseBatch struct {
 	ShortURLFull string `json:"short_url"`
 	// ShURL - short url for adding in storage.
 	ShortURL string `json:"-"`
+	// Status - BatchCreated or BatchExists.
+	Status string `json:"status,omitempty"`
+}
+
+// ErrConflict is returned by AddURL when the user has already shortened the original URL.
+var ErrConflict = errors.New("URL already exists")
+
+// ErrShortURLExists is returned when the short URL is already used by another URL,
+// the URL can be added again with a new short URL.
+var ErrShortURLExists = errors.New("short URL already exists")
+
+// Statuses of the URLs of a batch.
+const (
+	// BatchCreated - a new short URL was created.
+	BatchCreated = "created"
+	// BatchExists - the user already has a short URL for the original URL.
+	BatchExists = "exists"
+)
+
+// BatchResult stores the result of adding one URL of a batch.
+type BatchResult struct {
+	// ShortURL - the new short URL or the existing one if the URL was already added.
+	ShortURL string
+	// Status - BatchCreated or BatchExists.
+	Status string
 }
 
 // ServiceStats stores the amount of all users and URLs in the service.
@@ -30,20 +64,141 @@ type ServiceStats struct {
 	URLs int `json:"urls"`
 	// Users - amount of users.
 	Users int `json:"users"`
+	// Pool - the state of the database connection pool, nil for other storages.
+	Pool *PoolStats `json:"pool,omitempty"`
+	// Cache - the counters of the URL cache, nil if the cache is disabled.
+	Cache *CacheStats `json:"cache,omitempty"`
+}
+
+// PoolStats stores the state of the database connection pool.
+type PoolStats struct {
+	// MaxConns - the maximum size of the pool.
+	MaxConns int32 `json:"max_conns"`
+	// TotalConns - the number of open connections.
+	TotalConns int32 `json:"total_conns"`
+	// IdleConns - the number of idle connections.
+	IdleConns int32 `json:"idle_conns"`
+	// AcquiredConns - the number of connections in use.
+	AcquiredConns int32 `json:"acquired_conns"`
+	// AcquireCount - the number of successful acquires from the pool.
+	AcquireCount int64 `json:"acquire_count"`
+	// EmptyAcquireCount - the number of acquires that waited for a connection.
+	EmptyAcquireCount int64 `json:"empty_acquire_count"`
+	// CanceledAcquireCount - the number of acquires cancelled by the context.
+	CanceledAcquireCount int64 `json:"canceled_acquire_count"`
+	// AcquireDuration - the total time spent acquiring connections.
+	AcquireDuration time.Duration `json:"acquire_duration_ns"`
+}
+
+// PoolStatter is implemented by storages with a connection pool.
+type PoolStatter interface {
+	// PoolStats returns the current state of the connection pool.
+	PoolStats() PoolStats
+}
+
+// SchemaChecker is implemented by storages with a migrated schema.
+type SchemaChecker interface {
+	// PendingMigrations returns the number of schema migrations that are not applied yet.
+	PendingMigrations(ctx context.Context) (int, error)
+}
+
+// ClickAdder is implemented by storages that add the redirects of many short URLs with one write.
+type ClickAdder interface {
+	// AddClicks increases the numbers of redirects by the short URLs, unknown short URLs are skipped.
+	AddClicks(ctx context.Context, clicks map[string]int64) error
+}
+
+// Timeouts stores the time limits of storage operations, 0 - no limit.
+type Timeouts struct {
+	// Read - the limit of reading URLs and statistics.
+	Read time.Duration
+	// Write - the limit of adding and updating URLs.
+	Write time.Duration
+	// Delete - the limit of deleting and purging URLs.
+	Delete time.Duration
+}
+
+// NewTimeouts gets the time limits of storage operations from the flags.
+func NewTimeouts(flags config.Flags) Timeouts {
+	return Timeouts{
+		Read:   flags.ReadTimeout.Duration,
+		Write:  flags.WriteTimeout.Duration,
+		Delete: flags.DeleteTimeout.Duration,
+	}
 }
 
+// withTimeout limits the context by the timeout, the context is not changed if the timeout is 0.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// URLInfo stores full information about the short URL.
+type URLInfo struct {
+	// ShortURL - short url without the base address.
+	ShortURL string
+	// OriginalURL - original url.
+	OriginalURL string
+	// UserID - ID of the user who added the URL.
+	UserID int
+	// DeletedFlag - true if the URL has been deleted.
+	DeletedFlag bool
+	// CreatedAt - the time the URL was added.
+	CreatedAt time.Time
+	// Clicks - the number of redirects by the short URL.
+	Clicks int64
+}
+
+// URLRecord stores all data of the short URL for copying between storages.
+type URLRecord struct {
+	URLInfo
+	// DeletedAt - the time the URL was deleted, nil if it is not deleted.
+	DeletedAt *time.Time
+}
+
+// BulkStorage is implemented by storages that can copy all URLs with their users, flags and counters.
+type BulkStorage interface {
+	// IterateURLs calls fn for each URL ordered by the short URL, starting after the short URL after.
+	// Iteration stops at the first error returned by fn.
+	IterateURLs(ctx context.Context, after string, fn func(url URLRecord) error) (err error)
+	// LoadURLs adds the URLs with all their data.
+	// URLs with a used short URL or an original URL already added by the same user are skipped.
+	// Returns the number of added URLs.
+	LoadURLs(ctx context.Context, urls []URLRecord) (loaded int, err error)
+}
+
+// iteratePageSize - the number of URLs copied from memory at once when iterating.
+const iteratePageSize = 100
+
 // Repositories - the interface contains methods for working with the repository.
+// Methods stop and return the context error when ctx is cancelled.
 type Repositories interface {
 	// GetURL gets the original URL matching the short URL.
 	GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool)
 	// AddURL adds a new short url.
+	// If the user has already shortened originURL, returns the existing short URL and ErrConflict.
 	AddURL(ctx context.Context, shortURL string, originURL string, userID int) (findURL string, err error)
 	// AddBatch adds a batch of new short URLs.
-	AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (err error)
+	// The results are in the order of the batch, URLs already added by the user are not added again.
+	AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (results []BatchResult, err error)
 	// GetAllUserURLs gets all user's short url.
 	GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []UserURL, err error)
+	// IterateUserURLs calls fn for each user's URL without loading all of them into memory.
+	// Iteration stops at the first error returned by fn.
+	IterateUserURLs(ctx context.Context, userID int, fn func(url URLInfo) error) (err error)
+	// AddClick increases the number of redirects by the short URL.
+	AddClick(ctx context.Context, shortURL string) (err error)
 	// DeleteUserURLs sets the deletion flag to the user URLs sent in the request.
-	DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (err error)
+	// Returns the short URLs that were found and marked as deleted.
+	DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error)
+	// RestoreUserURLs clears the deletion flag of the user URLs deleted after deletedAfter.
+	// Returns the short URLs that were restored.
+	RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error)
+	// PurgeDeletedURLs permanently removes URLs deleted before deletedBefore.
+	// Returns the number of removed URLs.
+	PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error)
 	// GetStats gets the amount of all users and URLs in the service.
 	GetStats(ctx context.Context) (stats ServiceStats, err error)
 	// PingStor checking access to storage.
@@ -52,23 +207,193 @@ type Repositories interface {
 	Close() (err error)
 }
 
-// NewURLs creates a storage instance.
-func NewURLs(flags config.Flags) (Repositories, error) {
+// NewURLs creates a storage instance, log receives the messages of background work.
+func NewURLs(flags config.Flags, log *zap.SugaredLogger) (Repositories, error) {
+	if IsSQLiteDSN(flags.DBDSN) {
+		db, err := NewSQLiteURLs(flags)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+
 	if flags.DBDSN != "" {
-		db, err := NewConnectDB(flags.DBDSN)
+		db, err := NewConnectDB(flags)
 		if err != nil {
 			return nil, err
 		}
 		return db, nil
 	}
 
+	if flags.KVFileName != "" {
+		kv, err := NewKVURLs(flags.KVFileName)
+		if err != nil {
+			return nil, err
+		}
+		return kv, nil
+	}
+
 	if flags.FileName != "" {
-		fUrls, err := NewFileURLs(flags.FileName)
+		fUrls, err := NewFileURLs(flags.FileName, flags.FileCompression, log)
 		if err != nil {
 			return nil, err
 		}
+		fUrls.StartCompaction(flags.FileCompactInterval.Duration)
 		return fUrls, nil
 	}
 
 	return NewMapURLs(), nil
 }
+
+// Names of the storage backends.
+const (
+	BackendPostgres = "postgres"
+	BackendSQLite   = "sqlite"
+	BackendKV       = "kv"
+	BackendFile     = "file"
+	BackendMemory   = "memory"
+)
+
+// BackendName returns the name of the storage that NewURLs creates for the flags.
+func BackendName(flags config.Flags) string {
+	switch {
+	case IsSQLiteDSN(flags.DBDSN):
+		return BackendSQLite
+	case flags.DBDSN != "":
+		return BackendPostgres
+	case flags.KVFileName != "":
+		return BackendKV
+	case flags.FileName != "":
+		return BackendFile
+	}
+	return BackendMemory
+}
+
+// iterateSorted calls fn for each record after the short URL after in the order of the short URLs.
+// The records are a copy, so fn can run without holding the lock of the storage.
+func iterateSorted(ctx context.Context, records []URLRecord, after string, fn func(url URLRecord) error) error {
+	slices.SortFunc(records, func(a, b URLRecord) int {
+		return strings.Compare(a.ShortURL, b.ShortURL)
+	})
+	for k, v := range records {
+		if v.ShortURL <= after {
+			continue
+		}
+		if k%iteratePageSize == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addToPage adds the URL to the page sorted by the short URLs,
+// only the first iteratePageSize URLs are kept.
+func addToPage(page []URLInfo, url URLInfo) []URLInfo {
+	k, _ := slices.BinarySearchFunc(page, url.ShortURL, func(v URLInfo, shortURL string) int {
+		return strings.Compare(v.ShortURL, shortURL)
+	})
+	if k == iteratePageSize {
+		return page
+	}
+	if len(page) == iteratePageSize {
+		page = page[:iteratePageSize-1]
+	}
+	return slices.Insert(page, k, url)
+}
+
+// newLoadFilter returns a function that reports whether the record can be added
+// and remembers it, so repeated records of one load are skipped too.
+// shortURLs and origins are the short URLs and the user ID + original URL keys already in the storage.
+func newLoadFilter(shortURLs map[string]bool, origins map[string]bool) func(url URLRecord) bool {
+	return func(url URLRecord) bool {
+		origin := strconv.Itoa(url.UserID) + " " + url.OriginalURL
+		if shortURLs[url.ShortURL] || origins[origin] {
+			return false
+		}
+		shortURLs[url.ShortURL] = true
+		origins[origin] = true
+		return true
+	}
+}
+
+// resolveBatch finds the URLs of the batch that the user has already added.
+// existing maps the user's original URLs to short URLs and is extended with the new ones,
+// so a URL repeated in the batch is added only once.
+// Returns the results and the indexes of the URLs to add.
+func resolveBatch(existing map[string]string, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch) (results []BatchResult, added []int) {
+	results = make([]BatchResult, len(shortURLBatch))
+	for k, v := range shortURLBatch {
+		origin := originURLBatch[k].OriginalURL
+		if short, ok := existing[origin]; ok {
+			results[k] = BatchResult{ShortURL: short, Status: BatchExists}
+			continue
+		}
+		existing[origin] = v.ShortURL
+		results[k] = BatchResult{ShortURL: v.ShortURL, Status: BatchCreated}
+		added = append(added, k)
+	}
+	return results, added
+}
+
+// checkShortURLs returns ErrShortURLExists if a short URL of the added batch items is in used
+// or is repeated in the batch.
+func checkShortURLs(used map[string]bool, shortURLBatch []ResponseBatch, added []int) error {
+	for _, k := range added {
+		shortURL := shortURLBatch[k].ShortURL
+		if used[shortURL] {
+			return fmt.Errorf("%w: %s", ErrShortURLExists, shortURL)
+		}
+		used[shortURL] = true
+	}
+	return nil
+}
+
+// shortURLAttempts - how many times the short URLs are generated when they are already used.
+const shortURLAttempts = 3
+
+// AddNewURL adds originURL with a new random short URL and returns the short URL.
+// A short URL already used by another URL is generated again.
+// If the user has already shortened originURL, returns the existing short URL and ErrConflict.
+func AddNewURL(ctx context.Context, repo Repositories, originURL string, userID int) (shortURL string, err error) {
+	for attempt := 1; ; attempt++ {
+		shortURL, err = randomizer.GenerateRandomString(randomizer.LengthShortURL)
+		if err != nil {
+			return "", err
+		}
+		findURL, err := repo.AddURL(ctx, shortURL, originURL, userID)
+		switch {
+		case err == nil:
+			return shortURL, nil
+		case errors.Is(err, ErrConflict):
+			return findURL, err
+		case !errors.Is(err, ErrShortURLExists) || attempt == shortURLAttempts:
+			return "", err
+		}
+	}
+}
+
+// AddNewBatch adds the batch of original URLs with new random short URLs.
+// If one of the short URLs is already used by another URL, nothing is added
+// and the whole batch is added again with new short URLs.
+// The results are in the order of the batch, as returned by AddBatch.
+func AddNewBatch(ctx context.Context, repo Repositories, originURLBatch []RequestBatch, userID int) (results []BatchResult, err error) {
+	shortURLBatch := make([]ResponseBatch, len(originURLBatch))
+	for attempt := 1; ; attempt++ {
+		for k, v := range originURLBatch {
+			shortURL, err := randomizer.GenerateRandomString(randomizer.LengthShortURL)
+			if err != nil {
+				return nil, err
+			}
+			shortURLBatch[k] = ResponseBatch{CorrelationID: v.CorrelationID, ShortURL: shortURL}
+		}
+		results, err = repo.AddBatch(ctx, shortURLBatch, originURLBatch, userID)
+		if !errors.Is(err, ErrShortURLExists) || attempt == shortURLAttempts {
+			return results, err
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheStats stores the counters of the URL cache.
+type CacheStats struct {
+	// Hits - the number of lookups answered from the cache, including unknown short URLs.
+	Hits int64 `json:"hits"`
+	// Misses - the number of lookups passed to the storage.
+	Misses int64 `json:"misses"`
+	// Entries - the number of cached short URLs.
+	Entries int `json:"entries"`
+}
+
+// cacheEntry stores the result of looking up the short URL in the storage.
+type cacheEntry struct {
+	shortURL  string
+	originURL string
+	isDel     bool
+	ok        bool
+	expires   time.Time
+}
+
+// CachedURLs is a read-through cache of GetURL in front of any storage.
+// Least recently used entries are evicted when the cache is full,
+// entries expire after the TTL, unknown short URLs are cached with their own TTL.
+// Cached entries are invalidated by the changes made through the cache.
+type CachedURLs struct {
+	Repositories
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	entries     map[string]*list.Element
+	lru         *list.List
+	// generation is increased by each invalidation,
+	// a lookup started before an invalidation does not store its result.
+	generation uint64
+	group      singleflight.Group
+	hits       atomic.Int64
+	misses     atomic.Int64
+	sync.Mutex
+}
+
+// NewCachedURLs creates a cache of up to size short URLs in front of the storage.
+// Found URLs are cached for ttl, unknown ones for negativeTTL, 0 - unknown URLs are not cached.
+func NewCachedURLs(repo Repositories, size int, ttl time.Duration, negativeTTL time.Duration) *CachedURLs {
+	return &CachedURLs{
+		Repositories: repo,
+		size:         size,
+		ttl:          ttl,
+		negativeTTL:  negativeTTL,
+		entries:      make(map[string]*list.Element, size),
+		lru:          list.New(),
+	}
+}
+
+// lookupResult is the result of GetURL shared by concurrent lookups.
+type lookupResult struct {
+	originURL string
+	isDel     bool
+	ok        bool
+}
+
+// GetURL gets the original URL matching the short URL from the cache or the storage.
+// Concurrent lookups of the same short URL missing the cache make one storage call.
+func (c *CachedURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
+	if e, found := c.get(shortURL); found {
+		c.hits.Add(1)
+		return e.originURL, e.isDel, e.ok
+	}
+	c.misses.Add(1)
+
+	ch := c.group.DoChan(shortURL, func() (any, error) {
+		c.Lock()
+		generation := c.generation
+		c.Unlock()
+
+		// The lookup is shared, so it is not cancelled with the context of the first caller.
+		lookupCtx := context.WithoutCancel(ctx)
+		var res lookupResult
+		res.originURL, res.isDel, res.ok = c.Repositories.GetURL(lookupCtx, shortURL)
+		c.put(generation, shortURL, res)
+		return res, nil
+	})
+	select {
+	case <-ctx.Done():
+		return "", false, false
+	case r := <-ch:
+		res := r.Val.(lookupResult)
+		return res.originURL, res.isDel, res.ok
+	}
+}
+
+// get returns the unexpired cache entry and marks it as recently used.
+func (c *CachedURLs) get(shortURL string) (cacheEntry, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	el, ok := c.entries[shortURL]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return cacheEntry{}, false
+	}
+	c.lru.MoveToFront(el)
+	return *e, true
+}
+
+// put caches the lookup result unless the cache was invalidated after generation.
+func (c *CachedURLs) put(generation uint64, shortURL string, res lookupResult) {
+	ttl := c.ttl
+	if !res.ok {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	e := &cacheEntry{
+		shortURL:  shortURL,
+		originURL: res.originURL,
+		isDel:     res.isDel,
+		ok:        res.ok,
+		expires:   time.Now().Add(ttl),
+	}
+	if el, ok := c.entries[shortURL]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[shortURL] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove removes the entry from the cache. Must be called under lock.
+func (c *CachedURLs) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).shortURL)
+}
+
+// Invalidate removes the short URLs from the cache.
+func (c *CachedURLs) Invalidate(shortURLs ...string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	for _, shortURL := range shortURLs {
+		// Later lookups do not wait for the result read before the change.
+		c.group.Forget(shortURL)
+		if el, ok := c.entries[shortURL]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+// invalidateDeleted removes the deleted URLs from the cache.
+func (c *CachedURLs) invalidateDeleted() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	for _, el := range c.entries {
+		if el.Value.(*cacheEntry).isDel {
+			c.remove(el)
+		}
+	}
+}
+
+// clear removes all URLs from the cache.
+func (c *CachedURLs) clear() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	for shortURL := range c.entries {
+		c.group.Forget(shortURL)
+	}
+	c.entries = make(map[string]*list.Element, c.size)
+	c.lru.Init()
+}
+
+// HandleChange removes the URLs changed by another instance from the cache.
+func (c *CachedURLs) HandleChange(ev ChangeEvent) {
+	switch ev.Op {
+	case ChangeAdd, ChangeDelete, ChangeRestore:
+		c.Invalidate(ev.ShortURLs...)
+	case ChangePurge:
+		c.invalidateDeleted()
+	default:
+		c.clear()
+	}
+}
+
+// AddURL adds a new short url and removes its cached absence.
+func (c *CachedURLs) AddURL(ctx context.Context, shortURL string, originURL string, userID int) (findURL string, err error) {
+	findURL, err = c.Repositories.AddURL(ctx, shortURL, originURL, userID)
+	c.Invalidate(shortURL)
+	return findURL, err
+}
+
+// AddBatch adds a batch of new short URLs and removes their cached absence.
+func (c *CachedURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (results []BatchResult, err error) {
+	results, err = c.Repositories.AddBatch(ctx, shortURLBatch, originURLBatch, userID)
+	shortURLs := make([]string, len(shortURLBatch))
+	for k, v := range shortURLBatch {
+		shortURLs[k] = v.ShortURL
+	}
+	c.Invalidate(shortURLs...)
+	return results, err
+}
+
+// DeleteUserURLs sets the deletion flag to the user URLs and removes them from the cache.
+func (c *CachedURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
+	deleted, err = c.Repositories.DeleteUserURLs(ctx, delURLs, userID)
+	c.Invalidate(deleted...)
+	return deleted, err
+}
+
+// RestoreUserURLs clears the deletion flag of the user URLs and removes them from the cache.
+func (c *CachedURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	restored, err = c.Repositories.RestoreUserURLs(ctx, shortURLs, userID, deletedAfter)
+	c.Invalidate(restored...)
+	return restored, err
+}
+
+// PurgeDeletedURLs permanently removes URLs deleted before deletedBefore
+// and removes all deleted URLs from the cache.
+func (c *CachedURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	purged, err = c.Repositories.PurgeDeletedURLs(ctx, deletedBefore)
+	if purged > 0 {
+		c.invalidateDeleted()
+	}
+	return purged, err
+}
+
+// CacheStats returns the cache counters.
+func (c *CachedURLs) CacheStats() CacheStats {
+	c.Lock()
+	entries := c.lru.Len()
+	c.Unlock()
+
+	return CacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: entries,
+	}
+}
+
+// GetStats gets the statistics of the storage with the cache counters.
+func (c *CachedURLs) GetStats(ctx context.Context) (stats ServiceStats, err error) {
+	stats, err = c.Repositories.GetStats(ctx)
+	if err != nil {
+		return ServiceStats{}, err
+	}
+	cache := c.CacheStats()
+	stats.Cache = &cache
+	return stats, nil
+}
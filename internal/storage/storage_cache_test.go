@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingURLs counts GetURL calls and can block them until release is closed.
+type countingURLs struct {
+	*MemURLs
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (c *countingURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
+	c.calls.Add(1)
+	if c.release != nil {
+		<-c.release
+	}
+	return c.MemURLs.GetURL(ctx, shortURL)
+}
+
+func newTestCache(t *testing.T, size int) (*CachedURLs, *countingURLs) {
+	repo := &countingURLs{MemURLs: NewMapURLs()}
+	cache := NewCachedURLs(repo, size, time.Minute, time.Minute)
+	_, err := cache.AddURL(context.Background(), "EwH", "https://pract.ru/url1", testUserID)
+	require.NoError(t, err)
+	return cache, repo
+}
+
+func TestCacheGetURL(t *testing.T) {
+	cache, repo := newTestCache(t, 10)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		originURL, isDel, ok := cache.GetURL(ctx, "EwH")
+		assert.True(t, ok)
+		assert.False(t, isDel)
+		assert.Equal(t, "https://pract.ru/url1", originURL)
+
+		_, _, ok = cache.GetURL(ctx, "none")
+		assert.False(t, ok)
+	}
+	assert.Equal(t, int64(2), repo.calls.Load(), "found and unknown URLs are read once")
+	assert.Equal(t, CacheStats{Hits: 4, Misses: 2, Entries: 2}, cache.CacheStats())
+
+	stats, err := cache.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &CacheStats{Hits: 4, Misses: 2, Entries: 2}, stats.Cache)
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	cache, repo := newTestCache(t, 10)
+	ctx := context.Background()
+
+	_, _, ok := cache.GetURL(ctx, "Ert")
+	assert.False(t, ok)
+	_, err := cache.AddURL(ctx, "Ert", "https://pract.ru/url2", testUserID)
+	require.NoError(t, err)
+	_, _, ok = cache.GetURL(ctx, "Ert")
+	assert.True(t, ok, "the cached absence is removed when the URL is added")
+
+	_, _, ok = cache.GetURL(ctx, "Rty")
+	assert.False(t, ok)
+	_, err = cache.AddBatch(ctx, []ResponseBatch{{ShortURL: "Rty"}}, []RequestBatch{{OriginalURL: "https://pract.ru/url3"}}, testUserID)
+	require.NoError(t, err)
+	_, _, ok = cache.GetURL(ctx, "Rty")
+	assert.True(t, ok)
+
+	_, err = cache.DeleteUserURLs(ctx, []string{"Ert"}, testUserID)
+	require.NoError(t, err)
+	_, isDel, _ := cache.GetURL(ctx, "Ert")
+	assert.True(t, isDel, "the deleted URL is removed from the cache")
+
+	_, err = cache.RestoreUserURLs(ctx, []string{"Ert"}, testUserID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	_, isDel, _ = cache.GetURL(ctx, "Ert")
+	assert.False(t, isDel)
+
+	_, err = cache.DeleteUserURLs(ctx, []string{"Ert"}, testUserID)
+	require.NoError(t, err)
+	_, isDel, _ = cache.GetURL(ctx, "Ert")
+	assert.True(t, isDel)
+	purged, err := cache.PurgeDeletedURLs(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	_, _, ok = cache.GetURL(ctx, "Ert")
+	assert.False(t, ok, "purged URLs are removed from the cache")
+
+	calls := repo.calls.Load()
+	cache.GetURL(ctx, "Rty")
+	assert.Equal(t, calls, repo.calls.Load(), "other URLs stay cached")
+}
+
+func TestCacheEviction(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		ttl         time.Duration
+		negativeTTL time.Duration
+		wantCalls   int64
+	}{
+		{name: "least recently used evicted", size: 2, ttl: time.Minute, negativeTTL: time.Minute, wantCalls: 5},
+		{name: "all fit", size: 3, ttl: time.Minute, negativeTTL: time.Minute, wantCalls: 3},
+		{name: "expired", size: 3, ttl: time.Nanosecond, negativeTTL: time.Minute, wantCalls: 6},
+		{name: "unknown not cached", size: 3, ttl: time.Minute, negativeTTL: 0, wantCalls: 4},
+		{name: "disabled", size: 0, ttl: time.Minute, negativeTTL: time.Minute, wantCalls: 7},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &countingURLs{MemURLs: NewMapURLs()}
+			cache := NewCachedURLs(repo, test.size, test.ttl, test.negativeTTL)
+			ctx := context.Background()
+			for _, v := range []string{"aaa", "bbb"} {
+				_, err := cache.AddURL(ctx, v, "https://pract.ru/"+v, testUserID)
+				require.NoError(t, err)
+			}
+
+			// aaa is used again before none is added, so bbb is the least recently used.
+			for _, v := range []string{"aaa", "bbb", "aaa", "none", "aaa", "bbb", "none"} {
+				cache.GetURL(ctx, v)
+			}
+			assert.Equal(t, test.wantCalls, repo.calls.Load())
+			assert.LessOrEqual(t, cache.CacheStats().Entries, test.size)
+		})
+	}
+}
+
+func TestCacheCollapsesConcurrentMisses(t *testing.T) {
+	cache, repo := newTestCache(t, 10)
+	repo.release = make(chan struct{})
+	ctx := context.Background()
+
+	const lookups = 20
+	var wg sync.WaitGroup
+	results := make([]string, lookups)
+	for i := 0; i < lookups; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, _ = cache.GetURL(ctx, "EwH")
+		}(i)
+	}
+	assert.Eventually(t, func() bool {
+		return cache.CacheStats().Misses+cache.CacheStats().Hits == lookups
+	}, time.Second, time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), repo.calls.Load())
+	for i, v := range results {
+		assert.Equal(t, "https://pract.ru/url1", v, fmt.Sprintf("lookup %d", i))
+	}
+}
+
+func TestCacheSkipsLookupsStartedBeforeChange(t *testing.T) {
+	cache, repo := newTestCache(t, 10)
+	repo.release = make(chan struct{})
+	ctx := context.Background()
+
+	done := make(chan bool)
+	go func() {
+		_, _, ok := cache.GetURL(ctx, "Ert")
+		done <- ok
+	}()
+	assert.Eventually(t, func() bool { return repo.calls.Load() == 1 }, time.Second, time.Millisecond)
+	_, err := repo.MemURLs.AddURL(ctx, "Ert", "https://pract.ru/url2", testUserID)
+	require.NoError(t, err)
+	cache.Invalidate("Ert")
+	close(repo.release)
+	<-done
+
+	_, _, ok := cache.GetURL(ctx, "Ert")
+	assert.True(t, ok, "the result read before the change is not cached")
+}
+
+func TestCacheHandleChange(t *testing.T) {
+	tests := []struct {
+		name      string
+		ev        ChangeEvent
+		wantCalls int64
+	}{
+		{name: "delete", ev: ChangeEvent{Op: ChangeDelete, ShortURLs: []string{"EwH"}}, wantCalls: 3},
+		{name: "other url", ev: ChangeEvent{Op: ChangeAdd, ShortURLs: []string{"Ert"}}, wantCalls: 2},
+		{name: "purge", ev: ChangeEvent{Op: ChangePurge}, wantCalls: 3},
+		{name: "reset", ev: ChangeEvent{Op: ChangeReset}, wantCalls: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache, repo := newTestCache(t, 10)
+			ctx := context.Background()
+			_, err := cache.DeleteUserURLs(ctx, []string{"EwH"}, testUserID)
+			require.NoError(t, err)
+			cache.GetURL(ctx, "EwH")
+			cache.GetURL(ctx, "Ert")
+
+			cache.HandleChange(test.ev)
+			cache.GetURL(ctx, "EwH")
+			assert.Equal(t, test.wantCalls, repo.calls.Load())
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+)
+
+// ClickBuffer counts redirects in memory in front of any storage,
+// the counts are added to the storage periodically and on shutdown,
+// so a redirect does not write to the storage.
+// Clicks counted since the last flush are lost in a crash.
+type ClickBuffer struct {
+	Repositories
+	clicks map[string]int64
+	// flushMu serializes flushes, so the counts of a failed flush are returned before the next one.
+	flushMu sync.Mutex
+	sync.Mutex
+}
+
+// NewClickBuffer creates a buffer of redirects in front of the storage.
+func NewClickBuffer(repo Repositories) *ClickBuffer {
+	return &ClickBuffer{
+		Repositories: repo,
+		clicks:       make(map[string]int64),
+	}
+}
+
+// AddClick counts the redirect by the short URL in memory.
+func (b *ClickBuffer) AddClick(ctx context.Context, shortURL string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.clicks[shortURL]++
+	return nil
+}
+
+// Flush adds the counted redirects to the storage.
+// Storages without AddClicks get a write per short URL.
+// If the storage fails, the counts are kept for the next flush.
+func (b *ClickBuffer) Flush(ctx context.Context) (err error) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.Lock()
+	clicks := b.clicks
+	b.clicks = make(map[string]int64)
+	b.Unlock()
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	if adder, ok := b.Repositories.(ClickAdder); ok {
+		err = adder.AddClicks(ctx, clicks)
+	} else {
+		err = b.addEach(ctx, clicks)
+	}
+	if err != nil {
+		b.Lock()
+		for shortURL, n := range clicks {
+			b.clicks[shortURL] += n
+		}
+		b.Unlock()
+	}
+	return err
+}
+
+// addEach adds the redirects one by one, the added ones are removed from clicks.
+func (b *ClickBuffer) addEach(ctx context.Context, clicks map[string]int64) error {
+	for shortURL, n := range clicks {
+		for ; n > 0; n-- {
+			if err := b.Repositories.AddClick(ctx, shortURL); err != nil {
+				clicks[shortURL] = n
+				return err
+			}
+		}
+		delete(clicks, shortURL)
+	}
+	return nil
+}
+
+// Run flushes the counted redirects every interval until ctx is cancelled.
+// The last redirects are added by Flush on shutdown.
+func (b *ClickBuffer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.Flush(ctx); err != nil && ctx.Err() == nil {
+				logger.FromContext(ctx).Infow("flush clicks", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// userClicks returns the numbers of redirects of the user's URLs.
+func userClicks(t *testing.T, repo Repositories, userID int) map[string]int64 {
+	clicks := make(map[string]int64)
+	err := repo.IterateUserURLs(context.Background(), userID, func(url URLInfo) error {
+		clicks[url.ShortURL] = url.Clicks
+		return nil
+	})
+	require.NoError(t, err)
+	return clicks
+}
+
+func TestClickBuffer(t *testing.T) {
+	for name, newStorage := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			repo := newStorage(t)
+			ctx := context.Background()
+			_, err := repo.AddURL(ctx, "aaa", "https://pract.ru/url1", testUserID)
+			require.NoError(t, err)
+			_, err = repo.AddURL(ctx, "bbb", "https://pract.ru/url2", testUserID)
+			require.NoError(t, err)
+
+			buf := NewClickBuffer(repo)
+			for i := 0; i < 3; i++ {
+				require.NoError(t, buf.AddClick(ctx, "aaa"))
+			}
+			require.NoError(t, buf.AddClick(ctx, "bbb"))
+			require.NoError(t, buf.AddClick(ctx, "unknown"))
+			assert.Equal(t, map[string]int64{"aaa": 0, "bbb": 0}, userClicks(t, repo, testUserID), "clicks are counted in memory")
+
+			require.NoError(t, buf.Flush(ctx))
+			assert.Equal(t, map[string]int64{"aaa": 3, "bbb": 1}, userClicks(t, repo, testUserID))
+			require.NoError(t, buf.Flush(ctx))
+			assert.Equal(t, map[string]int64{"aaa": 3, "bbb": 1}, userClicks(t, repo, testUserID), "flushed clicks are not added again")
+		})
+	}
+}
+
+// failingClicks fails AddClick once and has no AddClicks.
+type failingClicks struct {
+	Repositories
+	failed bool
+}
+
+func (f *failingClicks) AddClick(ctx context.Context, shortURL string) error {
+	if !f.failed {
+		f.failed = true
+		return errors.New("storage is unavailable")
+	}
+	return f.Repositories.AddClick(ctx, shortURL)
+}
+
+func TestClickBufferFailedFlush(t *testing.T) {
+	repo := NewMapURLs()
+	ctx := context.Background()
+	_, err := repo.AddURL(ctx, "aaa", "https://pract.ru/url1", testUserID)
+	require.NoError(t, err)
+
+	buf := NewClickBuffer(&failingClicks{Repositories: repo})
+	require.NoError(t, buf.AddClick(ctx, "aaa"))
+	require.NoError(t, buf.AddClick(ctx, "aaa"))
+	assert.Error(t, buf.Flush(ctx))
+	assert.Equal(t, map[string]int64{"aaa": 0}, userClicks(t, repo, testUserID))
+
+	require.NoError(t, buf.Flush(ctx), "the clicks are added one by one by the next flush")
+	assert.Equal(t, map[string]int64{"aaa": 2}, userClicks(t, repo, testUserID))
+}
+
+func TestFileAddClicksReplay(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "urls.json")
+	ctx := context.Background()
+
+	repo, err := NewFileURLs(fileName, CompressionGzip, zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	_, err = repo.AddURL(ctx, "aaa", "https://pract.ru/url1", testUserID)
+	require.NoError(t, err)
+	require.NoError(t, repo.AddClick(ctx, "aaa"))
+	require.NoError(t, repo.AddClicks(ctx, map[string]int64{"aaa": 5}))
+	// The log file is read without closing the storage, as after a crash.
+	check, err := VerifyFile(fileName)
+	require.NoError(t, err)
+	if assert.Len(t, check.URLs, 1) {
+		assert.Equal(t, int64(6), check.URLs[0].Clicks)
+	}
+	require.NoError(t, repo.Close())
+}
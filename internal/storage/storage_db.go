@@ -2,50 +2,114 @@ package storage
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 
 	"github.com/Julia-ivv/shortener-url/pkg/logger"
 
-	"github.com/Julia-ivv/shortener-url.git/internal/deleter"
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
 )
 
-// DBURLs stores a pointer to the database.
+// migrateTimeout - how long to wait for migrations at startup,
+// including waiting for another instance to finish migrating.
+const migrateTimeout = time.Minute
+
+// pgxPool contains the pgxpool.Pool methods used by the storage.
+type pgxPool interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// DBURLs stores the database connection pool.
 type DBURLs struct {
-	dbHandle *sql.DB
+	pool     pgxPool
+	stat     func() *pgxpool.Stat
+	timeouts Timeouts
+	// notifyChannel - the channel the changes are published to, empty - not published.
+	notifyChannel string
 }
 
-// NewConnectDB creates a connection to the database.
-func NewConnectDB(DBDSN string) (*DBURLs, error) {
-	db, err := sql.Open("pgx", DBDSN)
+// newPoolConfig creates the pool settings from the DSN and the pool flags.
+// Zero values keep the settings from the DSN or the pgx defaults.
+func newPoolConfig(flags config.Flags) (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(flags.DBDSN)
 	if err != nil {
 		return nil, err
 	}
+	if flags.DBMaxConns > 0 {
+		poolCfg.MaxConns = int32(flags.DBMaxConns)
+	}
+	if flags.DBMinConns > 0 {
+		poolCfg.MinConns = int32(flags.DBMinConns)
+	}
+	if flags.DBMaxConnLifetime.Duration > 0 {
+		poolCfg.MaxConnLifetime = flags.DBMaxConnLifetime.Duration
+	}
+	if flags.DBMaxConnIdleTime.Duration > 0 {
+		poolCfg.MaxConnIdleTime = flags.DBMaxConnIdleTime.Duration
+	}
+	if flags.DBHealthCheckPeriod.Duration > 0 {
+		poolCfg.HealthCheckPeriod = flags.DBHealthCheckPeriod.Duration
+	}
+	if flags.DBStatementCache > 0 {
+		poolCfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+		poolCfg.ConnConfig.StatementCacheCapacity = flags.DBStatementCache
+	}
+	return poolCfg, nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// NewConnectDB creates a database connection pool and migrates the schema to the latest version.
+func NewConnectDB(flags config.Flags) (*DBURLs, error) {
+	poolCfg, err := newPoolConfig(flags)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
 	defer cancel()
 
-	_, err = db.ExecContext(ctx,
-		"CREATE TABLE IF NOT EXISTS urls (user_id integer, short_url text, original_url text, deleted_flag boolean DEFAULT false, PRIMARY KEY(user_id, original_url))")
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &DBURLs{dbHandle: db}, nil
+	db := stdlib.OpenDBFromPool(pool)
+	defer db.Close()
+	mig, err := NewMigrator(db)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	if err = mig.Up(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &DBURLs{
+		pool:          pool,
+		stat:          pool.Stat,
+		timeouts:      NewTimeouts(flags),
+		notifyChannel: flags.DBNotifyChannel,
+	}, nil
 }
 
 // GetURL gets the original URL matching the short URL.
 func (db *DBURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	ctx, cancel := withTimeout(ctx, db.timeouts.Read)
 	defer cancel()
 
-	row := db.dbHandle.QueryRowContext(ctx,
+	row := db.pool.QueryRow(ctx,
 		"SELECT original_url, deleted_flag FROM urls WHERE short_url=$1", shortURL)
 
 	err := row.Scan(&originURL, &isDel)
@@ -64,10 +128,10 @@ type UserURL struct {
 
 // GetAllUserURLs gets all user's short url.
 func (db *DBURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []UserURL, err error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	ctx, cancel := withTimeout(ctx, db.timeouts.Read)
 	defer cancel()
 
-	rows, err := db.dbHandle.QueryContext(ctx,
+	rows, err := db.pool.Query(ctx,
 		"SELECT short_url, original_url FROM urls WHERE user_id=$1", userID)
 	if err != nil {
 		return nil, err
@@ -92,116 +156,395 @@ func (db *DBURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int
 	return userURLs, nil
 }
 
+// IterateUserURLs calls fn for each user's URL while reading rows from the database.
+func (db *DBURLs) IterateUserURLs(ctx context.Context, userID int, fn func(url URLInfo) error) (err error) {
+	rows, err := db.pool.Query(ctx,
+		"SELECT short_url, original_url, deleted_flag, created_at, clicks FROM urls WHERE user_id=$1 ORDER BY created_at", userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u := URLInfo{UserID: userID}
+		err = rows.Scan(&u.ShortURL, &u.OriginalURL, &u.DeletedFlag, &u.CreatedAt, &u.Clicks)
+		if err != nil {
+			return err
+		}
+		if err = fn(u); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// IterateURLs calls fn for each URL ordered by the short URL, starting after the short URL after.
+// URLs are read in pages, so a connection is not held while fn is running.
+func (db *DBURLs) IterateURLs(ctx context.Context, after string, fn func(url URLRecord) error) (err error) {
+	for {
+		page, err := db.urlsPage(ctx, after)
+		if err != nil {
+			return err
+		}
+		for _, v := range page {
+			if err = fn(v); err != nil {
+				return err
+			}
+		}
+		if len(page) < iteratePageSize {
+			return nil
+		}
+		after = page[len(page)-1].ShortURL
+	}
+}
+
+// urlsPage reads the page of URLs after the short URL after.
+func (db *DBURLs) urlsPage(ctx context.Context, after string) (page []URLRecord, err error) {
+	ctx, cancel := withTimeout(ctx, db.timeouts.Read)
+	defer cancel()
+
+	rows, err := db.pool.Query(ctx,
+		`SELECT short_url, original_url, user_id, deleted_flag, created_at, deleted_at, clicks
+		FROM urls WHERE short_url > $1 ORDER BY short_url LIMIT $2`,
+		after, iteratePageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u URLRecord
+		err = rows.Scan(&u.ShortURL, &u.OriginalURL, &u.UserID, &u.DeletedFlag, &u.CreatedAt, &u.DeletedAt, &u.Clicks)
+		if err != nil {
+			return nil, err
+		}
+		page = append(page, u)
+	}
+	return page, rows.Err()
+}
+
+// LoadURLs adds the URLs with all their data in one statement, existing ones are skipped.
+func (db *DBURLs) LoadURLs(ctx context.Context, urls []URLRecord) (loaded int, err error) {
+	ctx, cancel := withTimeout(ctx, db.timeouts.Write)
+	defer cancel()
+
+	now := time.Now()
+	userIDs := make([]int, len(urls))
+	shortURLs := make([]string, len(urls))
+	originURLs := make([]string, len(urls))
+	deletedFlags := make([]bool, len(urls))
+	createdAt := make([]time.Time, len(urls))
+	deletedAt := make([]*time.Time, len(urls))
+	clicks := make([]int64, len(urls))
+	for k, v := range urls {
+		userIDs[k] = v.UserID
+		shortURLs[k] = v.ShortURL
+		originURLs[k] = v.OriginalURL
+		deletedFlags[k] = v.DeletedFlag
+		createdAt[k] = v.CreatedAt
+		deletedAt[k] = v.DeletedAt
+		if v.DeletedFlag && v.DeletedAt == nil {
+			deletedAt[k] = &now
+		}
+		clicks[k] = v.Clicks
+	}
+
+	result, err := db.pool.Exec(ctx,
+		`INSERT INTO urls (user_id, short_url, original_url, deleted_flag, created_at, deleted_at, clicks)
+		SELECT DISTINCT ON (short_url) * FROM unnest($1::integer[], $2::text[], $3::text[], $4::boolean[],
+			$5::timestamptz[], $6::timestamptz[], $7::bigint[])
+			AS batch(user_id, short_url, original_url, deleted_flag, created_at, deleted_at, clicks)
+		WHERE NOT EXISTS (SELECT 1 FROM urls WHERE urls.short_url = batch.short_url)
+		ON CONFLICT (user_id, original_url) DO NOTHING`,
+		userIDs, shortURLs, originURLs, deletedFlags, createdAt, deletedAt, clicks)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// AddClick increases the number of redirects by the short URL.
+func (db *DBURLs) AddClick(ctx context.Context, shortURL string) (err error) {
+	ctx, cancel := withTimeout(ctx, db.timeouts.Write)
+	defer cancel()
+
+	_, err = db.pool.Exec(ctx,
+		"UPDATE urls SET clicks = clicks + 1 WHERE short_url=$1", shortURL)
+	return err
+}
+
 // AddURL adds a new short url.
 func (db *DBURLs) AddURL(ctx context.Context, shortURL string, originURL string, userID int) (findURL string, err error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	ctx, cancel := withTimeout(ctx, db.timeouts.Write)
 	defer cancel()
 
-	result, err := db.dbHandle.ExecContext(ctx,
+	result, err := db.pool.Exec(ctx,
 		"INSERT INTO urls VALUES ($1, $2, $3)", userID, shortURL, originURL)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			row := db.dbHandle.QueryRowContext(ctx,
+			// Either the user has already added the URL or the short URL is used by another URL.
+			row := db.pool.QueryRow(ctx,
 				"SELECT short_url FROM urls WHERE original_url=$1 AND user_id=$2", originURL, userID)
 			errScan := row.Scan(&findURL)
+			if errors.Is(errScan, pgx.ErrNoRows) {
+				return "", fmt.Errorf("%w: %w", ErrShortURLExists, err)
+			}
 			if errScan != nil {
 				return "", err
 			}
-			return findURL, err
+			return findURL, fmt.Errorf("%w: %w", ErrConflict, err)
 		}
 		return "", err
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return "", err
-	}
-	if rows != 1 {
+	if rows := result.RowsAffected(); rows != 1 {
 		return "", fmt.Errorf("expected to affect 1 row, affected %d", rows)
 	}
+	db.publish(ctx, ChangeAdd, []string{shortURL})
 	return "", nil
 }
 
-// AddBatch adds a batch of new short URLs.
-func (db *DBURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (err error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+// AddClicks increases the numbers of redirects by the short URLs with one update.
+func (db *DBURLs) AddClicks(ctx context.Context, clicks map[string]int64) (err error) {
+	ctx, cancel := withTimeout(ctx, db.timeouts.Write)
 	defer cancel()
 
-	tx, err := db.dbHandle.Begin()
-	if err != nil {
-		return err
+	shortURLs := make([]string, 0, len(clicks))
+	counts := make([]int64, 0, len(clicks))
+	for shortURL, n := range clicks {
+		shortURLs = append(shortURLs, shortURL)
+		counts = append(counts, n)
 	}
+	_, err = db.pool.Exec(ctx,
+		`UPDATE urls SET clicks = urls.clicks + batch.clicks
+		FROM unnest($1::text[], $2::bigint[]) AS batch(short_url, clicks)
+		WHERE urls.short_url = batch.short_url`,
+		shortURLs, counts)
+	return err
+}
+
+// AddBatch adds a batch of new short URLs with one multi-row insert.
+// URLs already added by the user are skipped and their existing short URLs are returned.
+// Returns ErrShortURLExists and adds nothing if one of the short URLs is already used.
+func (db *DBURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (results []BatchResult, err error) {
+	ctx, cancel := withTimeout(ctx, db.timeouts.Write)
+	defer cancel()
 
+	shortURLs := make([]string, len(shortURLBatch))
+	originURLs := make([]string, len(shortURLBatch))
 	for k, v := range shortURLBatch {
-		result, err := tx.ExecContext(ctx, "INSERT INTO urls VALUES ($1, $2, $3)",
-			userID, v.ShortURL, originURLBatch[k].OriginalURL)
-		if err != nil {
-			tx.Rollback()
-			return err
+		shortURLs[k] = v.ShortURL
+		originURLs[k] = originURLBatch[k].OriginalURL
+	}
+
+	tx, err := db.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		`INSERT INTO urls (user_id, short_url, original_url)
+		SELECT $1, short_url, original_url FROM unnest($2::text[], $3::text[]) AS batch(short_url, original_url)
+		ON CONFLICT DO NOTHING RETURNING short_url`,
+		userID, shortURLs, originURLs)
+	if err != nil {
+		return nil, err
+	}
+	created := make(map[string]bool, len(shortURLs))
+	for rows.Next() {
+		var shortURL string
+		if err = rows.Scan(&shortURL); err != nil {
+			rows.Close()
+			return nil, err
 		}
+		created[shortURL] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-		rows, err := result.RowsAffected()
+	results = make([]BatchResult, len(shortURLs))
+	if len(created) < len(shortURLs) {
+		existing := make(map[string]string)
+		rows, err = tx.Query(ctx,
+			"SELECT original_url, short_url FROM urls WHERE user_id = $1 AND original_url = ANY($2)",
+			userID, originURLs)
 		if err != nil {
-			tx.Rollback()
-			return err
+			return nil, err
+		}
+		for rows.Next() {
+			var originURL, shortURL string
+			if err = rows.Scan(&originURL, &shortURL); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			existing[originURL] = shortURL
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		for k, v := range originURLs {
+			results[k] = BatchResult{ShortURL: existing[v], Status: BatchExists}
 		}
-		if rows != 1 {
-			tx.Rollback()
-			return fmt.Errorf("expected to affect 1 row, affected %d", rows)
+	}
+	for k, v := range shortURLs {
+		if created[v] {
+			results[k] = BatchResult{ShortURL: v, Status: BatchCreated}
+		} else if results[k].ShortURL == "" {
+			// The URL is neither added nor found, its short URL is used by another URL.
+			return nil, fmt.Errorf("%w: %s", ErrShortURLExists, v)
 		}
 	}
 
-	return tx.Commit()
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	added := make([]string, 0, len(created))
+	for shortURL := range created {
+		added = append(added, shortURL)
+	}
+	db.publish(ctx, ChangeAdd, added)
+	return results, nil
 }
 
-// DeleteUserURLs sets the deletion flag to the user URLs sent in the request.
-func (db *DBURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (err error) {
-	doneCh := make(chan struct{})
-	defer close(doneCh)
+// DeleteUserURLs sets the deletion flag to the user URLs sent in the request
+// with one batched update and returns the URLs that were marked.
+func (db *DBURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
+	ctx, cancel := withTimeout(ctx, db.timeouts.Delete)
+	defer cancel()
 
-	stmt, err := db.dbHandle.Prepare("UPDATE urls SET deleted_flag = true WHERE user_id = $1 AND short_url = $2")
+	rows, err := db.pool.Query(ctx,
+		"UPDATE urls SET deleted_flag = true, deleted_at = COALESCE(deleted_at, now()) WHERE user_id = $1 AND short_url = ANY($2) RETURNING short_url",
+		userID, delURLs)
 	if err != nil {
-		logger.ZapSugar.Infow("prepare context error", err)
-		return err
+		return nil, err
 	}
-	defer stmt.Close()
+	defer rows.Close()
 
-	inputCh := deleter.Generator(doneCh, delURLs, userID)
-	chans := deleter.FanOut(doneCh, inputCh, stmt)
-	resCh := deleter.FanIn(stmt, doneCh, chans...)
-	cnt := 0
-	for res := range resCh {
-		if res.Err == nil {
-			cnt += int(res.Rows)
+	for rows.Next() {
+		var shortURL string
+		if err = rows.Scan(&shortURL); err != nil {
+			return nil, err
 		}
+		deleted = append(deleted, shortURL)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
-	logger.ZapSugar.Infof("user ID %d - removed %d out of %d", userID, cnt, len(delURLs))
+	logger.FromContext(ctx).Infof("user ID %d - removed %d out of %d", userID, len(deleted), len(delURLs))
+	db.publish(ctx, ChangeDelete, deleted)
 
-	return nil
+	return deleted, nil
+}
+
+// RestoreUserURLs clears the deletion flag of the user URLs deleted after deletedAfter.
+func (db *DBURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	ctx, cancel := withTimeout(ctx, db.timeouts.Write)
+	defer cancel()
+
+	rows, err := db.pool.Query(ctx,
+		"UPDATE urls SET deleted_flag = false, deleted_at = NULL WHERE user_id = $1 AND short_url = ANY($2) AND deleted_flag AND deleted_at > $3 RETURNING short_url",
+		userID, shortURLs, deletedAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shortURL string
+		if err = rows.Scan(&shortURL); err != nil {
+			return nil, err
+		}
+		restored = append(restored, shortURL)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	db.publish(ctx, ChangeRestore, restored)
+
+	return restored, nil
+}
+
+// PurgeDeletedURLs permanently removes URLs deleted before deletedBefore.
+func (db *DBURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	ctx, cancel := withTimeout(ctx, db.timeouts.Delete)
+	defer cancel()
+
+	result, err := db.pool.Exec(ctx,
+		"DELETE FROM urls WHERE deleted_flag AND deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	if purged = result.RowsAffected(); purged > 0 {
+		db.publish(ctx, ChangePurge, nil)
+	}
+	return purged, nil
 }
 
 // GetStats gets statistics - amount URLs and users.
 func (db *DBURLs) GetStats(ctx context.Context) (stats ServiceStats, err error) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	ctx, cancel := withTimeout(ctx, db.timeouts.Read)
 	defer cancel()
 
-	row := db.dbHandle.QueryRowContext(ctx,
+	row := db.pool.QueryRow(ctx,
 		"SELECT COUNT(short_url) AS urls, COUNT(DISTINCT user_id) AS users FROM urls")
 	err = row.Scan(&stats.URLs, &stats.Users)
 	if err != nil {
 		return ServiceStats{}, err
 	}
+	if db.stat != nil {
+		pool := db.PoolStats()
+		stats.Pool = &pool
+	}
 
 	return stats, nil
 }
 
+// PoolStats returns the current state of the connection pool.
+func (db *DBURLs) PoolStats() PoolStats {
+	if db.stat == nil {
+		return PoolStats{}
+	}
+	st := db.stat()
+	return PoolStats{
+		MaxConns:             st.MaxConns(),
+		TotalConns:           st.TotalConns(),
+		IdleConns:            st.IdleConns(),
+		AcquiredConns:        st.AcquiredConns(),
+		AcquireCount:         st.AcquireCount(),
+		EmptyAcquireCount:    st.EmptyAcquireCount(),
+		CanceledAcquireCount: st.CanceledAcquireCount(),
+		AcquireDuration:      st.AcquireDuration(),
+	}
+}
+
 // PingStor checking access to storage.
 func (db *DBURLs) PingStor(ctx context.Context) error {
-	return db.dbHandle.PingContext(ctx)
+	ctx, cancel := withTimeout(ctx, db.timeouts.Read)
+	defer cancel()
+
+	return db.pool.Ping(ctx)
+}
+
+// PendingMigrations returns the number of schema migrations that are not applied yet.
+func (db *DBURLs) PendingMigrations(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, db.timeouts.Read)
+	defer cancel()
+
+	var version int
+	row := db.pool.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	if err := row.Scan(&version); err != nil {
+		return 0, err
+	}
+	return pendingMigrations("migrations", version)
 }
 
 // Close closes the storage.
 func (db *DBURLs) Close() error {
-	return db.dbHandle.Close()
+	db.pool.Close()
+	return nil
 }
@@ -1,68 +1,319 @@
 package storage
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 // FileURL stores URL information in file.
 type FileURL struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
-	DeletedFlag bool   `json:"is_deleted"`
-	UserID      int    `json:"user_id"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	DeletedFlag bool       `json:"is_deleted"`
+	UserID      int        `json:"user_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Clicks      int64      `json:"clicks"`
+}
+
+// Operations of the log records that do not contain the full URL.
+// A record without an operation contains the full URL and replaces the URL with the same short URL.
+const (
+	opClick = "click"
+	opPurge = "purge"
+)
+
+// fileRecord is one line of the storage log.
+type fileRecord struct {
+	Op string `json:"op,omitempty"`
+	FileURL
+}
+
+// opRecord is a log record changing the URL without its full data.
+// Clicks is the number of redirects of the click record, 0 - one redirect.
+type opRecord struct {
+	Op       string `json:"op"`
+	ShortURL string `json:"short_url"`
+	Clicks   int64  `json:"clicks,omitempty"`
 }
 
 // FileURLs stores information about all URLs in file.
+// The file is an append-only log: new URLs and every change are appended as JSON lines
+// and flushed to disk, the log is compacted in the background and on closing.
+// The compacted log starts with the format header, appends are compressed separately.
 type FileURLs struct {
-	fileName string
-	file     *os.File
-	Urls     []FileURL
+	fileName    string
+	compression string
+	log         *zap.SugaredLogger
+	file        *os.File
+	Urls        []FileURL
+	stop        chan struct{}
+	done        chan struct{}
+	// tail collects the records appended while the log is compacted, nil otherwise.
+	tail *bytes.Buffer
+	// failed is returned by all changes once the log cannot be written reliably.
+	failed    error
+	compactMu sync.Mutex
+	closeOnce sync.Once
+	closeErr  error
 	sync.RWMutex
 }
 
+// errFileClosed is returned by the changes of the closed storage.
+var errFileClosed = errors.New("storage file is closed")
+
+// ErrFileLocked is returned when the storage file is used by another process,
+// e.g. a running service or the filestore subcommand.
+var ErrFileLocked = errors.New("storage file is used by another process")
+
+// openLocked opens the storage file for appending and takes an exclusive lock on it.
+// The log is replaced by renaming, so the lock is checked to be on the file the name points to:
+// a file replaced by the owner of the lock between opening and locking is opened again.
+func openLocked(fileName string, flag int) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|flag, 0666)
+		if err != nil {
+			return nil, err
+		}
+		if err = lockFile(file); err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		locked, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		current, err := os.Stat(fileName)
+		if err == nil && os.SameFile(locked, current) {
+			return file, nil
+		}
+		file.Close()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
+
 // NewFileURLs creates an instance for storing URLs.
-func NewFileURLs(fileName string) (*FileURLs, error) {
-	urls := make([]FileURL, 0)
-	fileRd, err := os.OpenFile(fileName, os.O_RDONLY|os.O_CREATE|os.O_APPEND, 0666)
+// The log is replayed, migrated to the current format and compacted with the compression.
+// A torn last line left by a crash is skipped, corrupt lines are moved to the <fileName>.corrupt file.
+// The file is locked until the storage is closed, ErrFileLocked is returned if it is used by another process.
+// log receives the quarantine and background compaction messages.
+func NewFileURLs(fileName string, compression string, log *zap.SugaredLogger) (*FileURLs, error) {
+	compression, err := validCompression(compression)
 	if err != nil {
 		return nil, err
 	}
-	defer fileRd.Close()
-
-	scan := bufio.NewScanner(fileRd)
-	for scan.Scan() {
-		url := FileURL{}
-		data := scan.Bytes()
-		err = json.Unmarshal(data, &url)
-		if err != nil {
+	file, err := openLocked(fileName, os.O_CREATE)
+	if err != nil {
+		return nil, err
+	}
+	check, err := readFileLog(fileName)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if len(check.Corrupt) > 0 {
+		if err = quarantine(fileName, check.Corrupt); err != nil {
+			file.Close()
 			return nil, err
 		}
-		urls = append(urls, url)
+		log.Infow("corrupt records moved to quarantine",
+			"file", fileName, "records", len(check.Corrupt), "quarantine", fileName+".corrupt")
 	}
-	if err = scan.Err(); err != nil {
+
+	f := &FileURLs{
+		fileName:    fileName,
+		compression: compression,
+		log:         log,
+		file:        file,
+		Urls:        check.URLs,
+	}
+	if err = f.compact(); err != nil {
+		f.file.Close()
 		return nil, err
 	}
+	return f, nil
+}
 
-	fileWr, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+// StartCompaction compacts the log every interval until the storage is closed.
+func (f *FileURLs) StartCompaction(interval time.Duration) {
+	if interval <= 0 || f.stop != nil {
+		return
+	}
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+	go func() {
+		defer close(f.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				if err := f.compact(); err != nil {
+					f.log.Infow("compact storage file", "file", f.fileName, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// compact writes the current URLs to a temporary file, flushes it to disk
+// and replaces the log with it, so a crash leaves either the old or the new log.
+// The URLs are encoded and written without the lock, the records appended meanwhile
+// are copied to the new log under the lock just before the replacement.
+func (f *FileURLs) compact() error {
+	f.compactMu.Lock()
+	defer f.compactMu.Unlock()
+
+	f.Lock()
+	if f.failed != nil {
+		f.Unlock()
+		return f.failed
+	}
+	urls := slices.Clone(f.Urls)
+	f.tail = new(bytes.Buffer)
+	f.Unlock()
+
+	file, err := f.writeCompacted(urls)
+	if err != nil {
+		f.Lock()
+		f.tail = nil
+		f.Unlock()
+		return err
+	}
+	return f.replaceLog(file)
+}
+
+// writeCompacted writes the URLs to a new temporary file next to the log and flushes it to disk.
+func (f *FileURLs) writeCompacted(urls []FileURL) (*os.File, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(f.fileName), filepath.Base(f.fileName)+".tmp")
 	if err != nil {
 		return nil, err
 	}
+	data, err := encodeFileLog(f.compression, urls)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// replaceLog appends the records collected during the compaction to the temporary file
+// and renames it over the log. The new log is opened for appending and locked before the rename,
+// so the storage never keeps writing to the replaced file and the log is never left unlocked.
+func (f *FileURLs) replaceLog(tmp *os.File) error {
+	f.Lock()
+	defer f.Unlock()
 
-	return &FileURLs{
-		fileName: fileName,
-		file:     fileWr,
-		Urls:     urls,
-	}, nil
+	tail := f.tail
+	f.tail = nil
+	_, err := tmp.Write(tail.Bytes())
+	if err == nil {
+		err = tmp.Sync()
+	}
+	err = errors.Join(err, tmp.Close())
+	var file *os.File
+	if err == nil {
+		file, err = os.OpenFile(tmp.Name(), os.O_WRONLY|os.O_APPEND, 0666)
+	}
+	if err == nil {
+		if err = lockFile(file); err != nil {
+			file.Close()
+		}
+	}
+	if err == nil {
+		if err = os.Rename(tmp.Name(), f.fileName); err != nil {
+			file.Close()
+		}
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if f.file != nil {
+		f.file.Close()
+	}
+	f.file = file
+	if err = syncDir(filepath.Dir(f.fileName)); err != nil {
+		// The replacement and all later records may be lost in a crash.
+		f.failed = fmt.Errorf("replace storage file %s: %w", f.fileName, err)
+		return f.failed
+	}
+	return nil
+}
+
+// syncDir flushes the directory entry changes, e.g. a rename, to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// appendRecords writes the records to the end of the log with one write.
+// The log is flushed to disk unless sync is false. Must be called under lock.
+func (f *FileURLs) appendRecords(sync bool, records ...any) error {
+	if f.failed != nil {
+		return f.failed
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	data, err := compress(f.compression, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if _, err = f.file.Write(data); err != nil {
+		return err
+	}
+	if f.tail != nil {
+		f.tail.Write(data)
+	}
+	if !sync {
+		return nil
+	}
+	return f.file.Sync()
 }
 
 // GetURL gets the original URL matching the short URL.
 func (f *FileURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
+	if ctx.Err() != nil {
+		return "", false, false
+	}
+
 	f.RLock()
 	defer f.RUnlock()
 
@@ -75,74 +326,94 @@ func (f *FileURLs) GetURL(ctx context.Context, shortURL string) (originURL strin
 }
 
 // AddURL adds a new short url.
+// If the user has already shortened originURL, returns the existing short URL and ErrConflict.
+// Returns ErrShortURLExists if shortURL is used by another URL.
 func (f *FileURLs) AddURL(ctx context.Context, shortURL string, originURL string, userID int) (findURL string, err error) {
+	if err = ctx.Err(); err != nil {
+		return "", err
+	}
+
 	url := FileURL{
 		UserID:      userID,
 		ShortURL:    shortURL,
 		OriginalURL: originURL,
 		DeletedFlag: false,
+		CreatedAt:   time.Now(),
 	}
 
 	f.Lock()
 	defer f.Unlock()
 
-	wr := bufio.NewWriter(f.file)
-	data, err := json.Marshal(&url)
-	if err != nil {
-		return "", err
+	taken := false
+	for _, v := range f.Urls {
+		if v.UserID == userID && v.OriginalURL == originURL {
+			return v.ShortURL, ErrConflict
+		}
+		taken = taken || v.ShortURL == shortURL
 	}
-	if _, err := wr.Write(data); err != nil {
-		return "", err
+	if taken {
+		return "", ErrShortURLExists
 	}
-	if err := wr.WriteByte('\n'); err != nil {
+	if err = f.appendRecords(true, url); err != nil {
 		return "", err
 	}
-
 	f.Urls = append(f.Urls, url)
 
-	return "", wr.Flush()
+	return "", nil
 }
 
 // AddBatch adds a batch of new short URLs.
-func (f *FileURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (err error) {
-	var allData []byte
-	urls := make([]FileURL, 0)
-	for k, v := range shortURLBatch {
+// Returns ErrShortURLExists and adds nothing if one of the short URLs is already used.
+func (f *FileURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (results []BatchResult, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	existing := make(map[string]string)
+	used := make(map[string]bool, len(f.Urls))
+	for _, v := range f.Urls {
+		if v.UserID == userID {
+			existing[v.OriginalURL] = v.ShortURL
+		}
+		used[v.ShortURL] = true
+	}
+
+	now := time.Now()
+	results, added := resolveBatch(existing, shortURLBatch, originURLBatch)
+	if err = checkShortURLs(used, shortURLBatch, added); err != nil {
+		return nil, err
+	}
+	urls := make([]FileURL, 0, len(added))
+	records := make([]any, 0, len(added))
+	for _, k := range added {
 		url := FileURL{
 			UserID:      userID,
-			ShortURL:    v.ShortURL,
+			ShortURL:    shortURLBatch[k].ShortURL,
 			OriginalURL: originURLBatch[k].OriginalURL,
 			DeletedFlag: false,
+			CreatedAt:   now,
 		}
 		urls = append(urls, url)
-		var data []byte
-		data, err = json.Marshal(url)
-		if err != nil {
-			return err
-		}
-		allData = append(allData, data...)
-		allData = append(allData, '\n')
+		records = append(records, url)
 	}
 
-	f.Lock()
-	defer f.Unlock()
-
-	wr := bufio.NewWriter(f.file)
-	if _, err = wr.Write(allData); err != nil {
-		return err
-	}
-	err = wr.Flush()
-	if err != nil {
-		return err
+	if err = f.appendRecords(true, records...); err != nil {
+		return nil, err
 	}
-
 	f.Urls = append(f.Urls, urls...)
 
-	return nil
+	return results, nil
 }
 
 // GetAllUserURLs gets all user's short url.
 func (f *FileURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []UserURL, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	f.RLock()
 	defer f.RUnlock()
 
@@ -157,24 +428,267 @@ func (f *FileURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID in
 	return userURLs, nil
 }
 
+// IterateUserURLs calls fn for each user's URL in the order of the short URLs.
+// URLs are copied in small pages, so the lock is not held while fn is running.
+// The next page starts after the last short URL of the previous one,
+// so URLs added or purged while fn is running do not shift the pages.
+func (f *FileURLs) IterateUserURLs(ctx context.Context, userID int, fn func(url URLInfo) error) (err error) {
+	page := make([]URLInfo, 0, iteratePageSize)
+	after := ""
+	for {
+		page = page[:0]
+		f.RLock()
+		for _, v := range f.Urls {
+			if v.UserID == userID && v.ShortURL > after {
+				page = addToPage(page, URLInfo{
+					ShortURL:    v.ShortURL,
+					OriginalURL: v.OriginalURL,
+					UserID:      v.UserID,
+					DeletedFlag: v.DeletedFlag,
+					CreatedAt:   v.CreatedAt,
+					Clicks:      v.Clicks,
+				})
+			}
+		}
+		f.RUnlock()
+
+		for _, v := range page {
+			if err = fn(v); err != nil {
+				return err
+			}
+		}
+		if len(page) < iteratePageSize {
+			return nil
+		}
+		after = page[len(page)-1].ShortURL
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// IterateURLs calls fn for each URL ordered by the short URL, starting after the short URL after.
+func (f *FileURLs) IterateURLs(ctx context.Context, after string, fn func(url URLRecord) error) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	f.RLock()
+	records := make([]URLRecord, 0, len(f.Urls))
+	for _, v := range f.Urls {
+		records = append(records, URLRecord{
+			URLInfo: URLInfo{
+				ShortURL:    v.ShortURL,
+				OriginalURL: v.OriginalURL,
+				UserID:      v.UserID,
+				DeletedFlag: v.DeletedFlag,
+				CreatedAt:   v.CreatedAt,
+				Clicks:      v.Clicks,
+			},
+			DeletedAt: v.DeletedAt,
+		})
+	}
+	f.RUnlock()
+
+	return iterateSorted(ctx, records, after, fn)
+}
+
+// LoadURLs adds the URLs with all their data with one write, existing ones are skipped.
+func (f *FileURLs) LoadURLs(ctx context.Context, records []URLRecord) (loaded int, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	shortURLs := make(map[string]bool, len(f.Urls))
+	origins := make(map[string]bool, len(f.Urls))
+	for _, v := range f.Urls {
+		shortURLs[v.ShortURL] = true
+		origins[strconv.Itoa(v.UserID)+" "+v.OriginalURL] = true
+	}
+	canLoad := newLoadFilter(shortURLs, origins)
+
+	var urls []FileURL
+	var added []any
+	for _, v := range records {
+		if !canLoad(v) {
+			continue
+		}
+		url := FileURL{
+			ShortURL:    v.ShortURL,
+			OriginalURL: v.OriginalURL,
+			DeletedFlag: v.DeletedFlag,
+			UserID:      v.UserID,
+			CreatedAt:   v.CreatedAt,
+			DeletedAt:   v.DeletedAt,
+			Clicks:      v.Clicks,
+		}
+		urls = append(urls, url)
+		added = append(added, url)
+	}
+
+	if err = f.appendRecords(true, added...); err != nil {
+		return 0, err
+	}
+	f.Urls = append(f.Urls, urls...)
+	return len(urls), nil
+}
+
+// AddClick increases the number of redirects by the short URL.
+// Click records are not flushed to disk one by one, a crash can lose the last clicks.
+func (f *FileURLs) AddClick(ctx context.Context, shortURL string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	for k, v := range f.Urls {
+		if v.ShortURL == shortURL {
+			if err = f.appendRecords(false, opRecord{Op: opClick, ShortURL: shortURL}); err != nil {
+				return err
+			}
+			f.Urls[k].Clicks++
+			return nil
+		}
+	}
+	return nil
+}
+
+// AddClicks increases the numbers of redirects by the short URLs with one log write.
+// The records are not flushed to disk, a crash can lose the last clicks.
+func (f *FileURLs) AddClicks(ctx context.Context, clicks map[string]int64) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	var records []any
+	for _, v := range f.Urls {
+		if n := clicks[v.ShortURL]; n > 0 {
+			records = append(records, opRecord{Op: opClick, ShortURL: v.ShortURL, Clicks: n})
+		}
+	}
+	if err = f.appendRecords(false, records...); err != nil {
+		return err
+	}
+	for k, v := range f.Urls {
+		f.Urls[k].Clicks += clicks[v.ShortURL]
+	}
+	return nil
+}
+
 // DeleteUserURLs sets the deletion flag to the user URLs sent in the request.
-func (f *FileURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (err error) {
+func (f *FileURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
 	f.Lock()
 	defer f.Unlock()
 
+	now := time.Now()
+	changed := make(map[int]FileURL)
 	for _, delURL := range delURLs {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
 		for k, curURL := range f.Urls {
 			if (delURL == curURL.ShortURL) && (userID == curURL.UserID) {
-				f.Urls[k].DeletedFlag = true
+				if !curURL.DeletedFlag {
+					curURL.DeletedFlag = true
+					curURL.DeletedAt = &now
+					changed[k] = curURL
+				}
+				deleted = append(deleted, delURL)
 				break
 			}
 		}
 	}
+
+	if err = f.applyChanges(changed); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// RestoreUserURLs clears the deletion flag of the user URLs deleted after deletedAfter.
+func (f *FileURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	f.Lock()
+	defer f.Unlock()
+
+	changed := make(map[int]FileURL)
+	for _, shortURL := range shortURLs {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		for k, curURL := range f.Urls {
+			if (shortURL == curURL.ShortURL) && (userID == curURL.UserID) {
+				if curURL.DeletedFlag && curURL.DeletedAt != nil && curURL.DeletedAt.After(deletedAfter) {
+					curURL.DeletedFlag = false
+					curURL.DeletedAt = nil
+					changed[k] = curURL
+					restored = append(restored, shortURL)
+				}
+				break
+			}
+		}
+	}
+
+	if err = f.applyChanges(changed); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// applyChanges appends the changed URLs to the log and then updates them in memory.
+// Must be called under lock.
+func (f *FileURLs) applyChanges(changed map[int]FileURL) error {
+	records := make([]any, 0, len(changed))
+	for _, v := range changed {
+		records = append(records, v)
+	}
+	if err := f.appendRecords(true, records...); err != nil {
+		return err
+	}
+	for k, v := range changed {
+		f.Urls[k] = v
+	}
 	return nil
 }
 
+// PurgeDeletedURLs permanently removes URLs deleted before deletedBefore.
+func (f *FileURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	var records []any
+	for _, v := range f.Urls {
+		if v.DeletedFlag && v.DeletedAt != nil && v.DeletedAt.Before(deletedBefore) {
+			records = append(records, opRecord{Op: opPurge, ShortURL: v.ShortURL})
+		}
+	}
+	if err = f.appendRecords(true, records...); err != nil {
+		return 0, err
+	}
+
+	f.Urls = slices.DeleteFunc(f.Urls, func(v FileURL) bool {
+		return v.DeletedFlag && v.DeletedAt != nil && v.DeletedAt.Before(deletedBefore)
+	})
+	return int64(len(records)), nil
+}
+
 // GetStats gets statistics - amount URLs and users.
 func (f *FileURLs) GetStats(ctx context.Context) (stats ServiceStats, err error) {
+	if err = ctx.Err(); err != nil {
+		return ServiceStats{}, err
+	}
+
 	f.Lock()
 	defer f.Unlock()
 
@@ -194,6 +708,9 @@ func (f *FileURLs) GetStats(ctx context.Context) (stats ServiceStats, err error)
 
 // PingStor checking access to storage.
 func (f *FileURLs) PingStor(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	_, err := os.Stat(f.fileName)
 	if os.IsNotExist(err) {
 		return errors.New("file not exists")
@@ -201,39 +718,20 @@ func (f *FileURLs) PingStor(ctx context.Context) error {
 	return nil
 }
 
-// Close closes the storage.
+// Close stops the background compaction, compacts the log and closes the file.
+// Repeated calls return the result of the first one.
 func (f *FileURLs) Close() error {
-	f.file.Close()
-
-	var allData []byte
-	for _, v := range f.Urls {
-		url := FileURL{
-			UserID:      v.UserID,
-			ShortURL:    v.ShortURL,
-			OriginalURL: v.OriginalURL,
-			DeletedFlag: v.DeletedFlag,
-		}
-		data, err := json.Marshal(url)
-		if err != nil {
-			return err
+	f.closeOnce.Do(func() {
+		if f.stop != nil {
+			close(f.stop)
+			<-f.done
 		}
-		allData = append(allData, data...)
-		allData = append(allData, '\n')
-	}
-
-	newFile, err := os.Create(f.fileName)
-	if err != nil {
-		return err
-	}
-
-	wr := bufio.NewWriter(newFile)
-	if _, err = wr.Write(allData); err != nil {
-		return err
-	}
-	err = wr.Flush()
-	if err != nil {
-		return err
-	}
 
-	return newFile.Close()
+		err := f.compact()
+		f.Lock()
+		defer f.Unlock()
+		f.closeErr = errors.Join(err, f.file.Close())
+		f.failed = errFileClosed
+	})
+	return f.closeErr
 }
@@ -0,0 +1,375 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/zap"
+)
+
+// fileFormat - the format name in the header of the storage file.
+const fileFormat = "shortener-urls"
+
+// fileVersion - the current version of the storage file format.
+// Files without a header have version 1.
+const fileVersion = 2
+
+// fileMigrations upgrade the URLs of older files, fileMigrations[i] upgrades version i+1 to i+2.
+// A migration is added with each new FileURL field that needs a value for the old URLs.
+var fileMigrations = []func(url *FileURL, now time.Time){
+	// 1 -> 2: URLs deleted before the deletion time was saved, the grace period starts now.
+	func(url *FileURL, now time.Time) {
+		if url.DeletedFlag && url.DeletedAt == nil {
+			url.DeletedAt = &now
+		}
+	},
+}
+
+// Compressions of the storage file.
+const (
+	// CompressionNone - the file is plain JSON lines.
+	CompressionNone = "none"
+	// CompressionGzip - the file is a sequence of gzip members.
+	CompressionGzip = "gzip"
+	// CompressionZstd - the file is a sequence of zstd frames.
+	CompressionZstd = "zstd"
+)
+
+// Magic numbers of the compressed files.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// fileHeader is the first line of the storage file.
+type fileHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+// CorruptRecord stores a log line that could not be read.
+type CorruptRecord struct {
+	// Line - the line number in the log, starting from 1.
+	Line int `json:"line"`
+	// Error - why the line could not be read.
+	Error string `json:"error"`
+	// Data - the line as it is in the log.
+	Data string `json:"data"`
+}
+
+// FileCheck stores the result of reading the storage file.
+type FileCheck struct {
+	// Version - the format version of the file.
+	Version int
+	// Compression - the compression of the file.
+	Compression string
+	// Records - the number of read log records.
+	Records int
+	// URLs - the current URLs after replaying the log.
+	URLs []FileURL
+	// Corrupt - the lines that could not be read.
+	Corrupt []CorruptRecord
+	// TornTail - true if the end of the log was torn by a crash during writing.
+	TornTail bool
+}
+
+// validCompression checks the compression name, an empty name means no compression.
+func validCompression(compression string) (string, error) {
+	switch compression {
+	case "", CompressionNone:
+		return CompressionNone, nil
+	case CompressionGzip, CompressionZstd:
+		return compression, nil
+	}
+	return "", fmt.Errorf("unknown file compression %q", compression)
+}
+
+// decompress detects the compression of the file by its first bytes.
+func decompress(rd *bufio.Reader) (io.ReadCloser, string, error) {
+	magic, _ := rd.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(rd)
+		if err != nil {
+			return nil, CompressionGzip, err
+		}
+		return zr, CompressionGzip, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(rd, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, CompressionZstd, err
+		}
+		return zr.IOReadCloser(), CompressionZstd, nil
+	}
+	return io.NopCloser(rd), CompressionNone, nil
+}
+
+// zstdEncoder is shared by all appends, EncodeAll can be called concurrently.
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+})
+
+// compress returns the data compressed as a separate gzip member or zstd frame,
+// so it can be appended to the end of the file.
+func compress(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		zw, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return zw.EncodeAll(data, nil), nil
+	}
+	return data, nil
+}
+
+// isTorn reports whether the decompression error is a block torn by a crash:
+// the block ends early and there is nothing after it in the file.
+// A corrupt block in the middle of the file is not torn, the records after it cannot be skipped silently.
+func isTorn(err error, file *bufio.Reader) bool {
+	if !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false
+	}
+	_, errPeek := file.Peek(1)
+	return errPeek == io.EOF
+}
+
+// readFileLog replays the log and returns the current URLs.
+// Lines that cannot be read are skipped and returned as corrupt,
+// the end of the log torn by a crash is skipped.
+// A corrupt compressed block before the end of the file is returned as an error.
+func readFileLog(fileName string) (FileCheck, error) {
+	check := FileCheck{Version: fileVersion, Compression: CompressionNone, URLs: make([]FileURL, 0)}
+	file, err := os.Open(fileName)
+	if os.IsNotExist(err) {
+		return check, nil
+	}
+	if err != nil {
+		return check, err
+	}
+	defer file.Close()
+
+	fileRd := bufio.NewReader(file)
+	src, compression, err := decompress(fileRd)
+	if err != nil {
+		check.Compression = compression
+		if !isTorn(err, fileRd) {
+			return check, fmt.Errorf("corrupt compressed block at the start of %s: %w", fileName, err)
+		}
+		// The header of the first compressed block is torn, there are no records.
+		check.TornTail = true
+		return check, nil
+	}
+	defer src.Close()
+	check.Compression = compression
+	check.Version = 1
+
+	var order []string
+	byShort := make(map[string]FileURL)
+	now := time.Now()
+	rd := bufio.NewReader(src)
+	for line := 1; ; line++ {
+		data, errRead := rd.ReadBytes('\n')
+		if errRead != nil && errRead != io.EOF {
+			if compression == CompressionNone {
+				return check, errRead
+			}
+			if !isTorn(errRead, fileRd) {
+				return check, fmt.Errorf("corrupt compressed block after line %d of %s: %w", line-1, fileName, errRead)
+			}
+			check.TornTail = true
+			break
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			if line == 1 {
+				var header fileHeader
+				if json.Unmarshal(data, &header) == nil && header.Format == fileFormat {
+					if header.Version < 1 || header.Version > fileVersion {
+						return check, fmt.Errorf("unsupported file format version %d, supported up to %d", header.Version, fileVersion)
+					}
+					check.Version = header.Version
+					continue
+				}
+			}
+
+			rec, err := decodeRecord(data)
+			if err != nil {
+				// Only the last line without the line end can be torn by a crash during writing.
+				if errRead == io.EOF {
+					check.TornTail = true
+					break
+				}
+				check.Corrupt = append(check.Corrupt, CorruptRecord{
+					Line:  line,
+					Error: err.Error(),
+					Data:  string(bytes.TrimRight(data, "\n")),
+				})
+				continue
+			}
+			check.Records++
+
+			url, ok := byShort[rec.ShortURL]
+			switch rec.Op {
+			case opClick:
+				if ok {
+					// Click records written one by one have no count.
+					url.Clicks += max(rec.Clicks, 1)
+					byShort[rec.ShortURL] = url
+				}
+			case opPurge:
+				delete(byShort, rec.ShortURL)
+			default:
+				for _, migrate := range fileMigrations[check.Version-1:] {
+					migrate(&rec.FileURL, now)
+				}
+				if !ok {
+					order = append(order, rec.ShortURL)
+				}
+				byShort[rec.ShortURL] = rec.FileURL
+			}
+		}
+		if errRead == io.EOF {
+			break
+		}
+	}
+
+	for _, short := range order {
+		if url, ok := byShort[short]; ok {
+			check.URLs = append(check.URLs, url)
+			delete(byShort, short)
+		}
+	}
+	return check, nil
+}
+
+// decodeRecord decodes and checks one log line.
+func decodeRecord(data []byte) (rec fileRecord, err error) {
+	if err = json.Unmarshal(data, &rec); err != nil {
+		return rec, err
+	}
+	if rec.ShortURL == "" {
+		return rec, errors.New("empty short URL")
+	}
+	switch rec.Op {
+	case "", opClick, opPurge:
+		return rec, nil
+	}
+	return rec, fmt.Errorf("unknown operation %q", rec.Op)
+}
+
+// encodeFileLog returns the header and the URLs as the compacted log.
+func encodeFileLog(compression string, urls []FileURL) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(fileHeader{Format: fileFormat, Version: fileVersion}); err != nil {
+		return nil, err
+	}
+	for _, v := range urls {
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+	return compress(compression, buf.Bytes())
+}
+
+// quarantine appends the corrupt records to the <fileName>.corrupt file as JSON lines.
+func quarantine(fileName string, corrupt []CorruptRecord) error {
+	if len(corrupt) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(fileName+".corrupt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	wr := bufio.NewWriter(file)
+	enc := json.NewEncoder(wr)
+	for _, v := range corrupt {
+		if err = enc.Encode(v); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err = wr.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// VerifyFile reads the storage file without changing it.
+func VerifyFile(fileName string) (FileCheck, error) {
+	if _, err := os.Stat(fileName); err != nil {
+		return FileCheck{}, err
+	}
+	return readFileLog(fileName)
+}
+
+// RepairFile moves the corrupt records to the <fileName>.corrupt file
+// and rewrites the storage file in the current format with the compression.
+// ErrFileLocked is returned if the file is used by another process.
+func RepairFile(fileName string, compression string) (check FileCheck, err error) {
+	file, err := openLocked(fileName, 0)
+	if err != nil {
+		return check, err
+	}
+	check, err = readFileLog(fileName)
+	if err == nil {
+		err = quarantine(fileName, check.Corrupt)
+	}
+	if err != nil {
+		file.Close()
+		return check, err
+	}
+	return check, rewriteFile(file, compression, check.URLs)
+}
+
+// CompactFile rewrites the storage file in the current format with the compression.
+// The file must not be corrupt, use RepairFile to remove the corrupt records.
+// ErrFileLocked is returned if the file is used by another process.
+func CompactFile(fileName string, compression string) (check FileCheck, err error) {
+	file, err := openLocked(fileName, 0)
+	if err != nil {
+		return check, err
+	}
+	check, err = readFileLog(fileName)
+	if err == nil && len(check.Corrupt) > 0 {
+		err = fmt.Errorf("%d corrupt records in %s", len(check.Corrupt), fileName)
+	}
+	if err != nil {
+		file.Close()
+		return check, err
+	}
+	return check, rewriteFile(file, compression, check.URLs)
+}
+
+// rewriteFile replaces the locked storage file with the URLs and closes it.
+func rewriteFile(file *os.File, compression string, urls []FileURL) (err error) {
+	f := &FileURLs{fileName: file.Name(), log: zap.NewNop().Sugar(), file: file, Urls: urls}
+	if f.compression, err = validCompression(compression); err == nil {
+		err = f.compact()
+	}
+	return errors.Join(err, f.file.Close())
+}
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestFileFormatMigration(t *testing.T) {
+	tests := []struct {
+		name        string
+		log         string
+		wantVersion int
+		wantErr     bool
+	}{
+		{
+			name: "version 1 without header",
+			log: `{"short_url":"EwH","original_url":"https://pract.ru/url1","user_id":1,"is_deleted":true}
+`,
+			wantVersion: 1,
+		},
+		{
+			name: "current version",
+			log: `{"format":"shortener-urls","version":2}
+{"short_url":"EwH","original_url":"https://pract.ru/url1","user_id":1,"is_deleted":true,"deleted_at":"2024-01-02T03:04:05Z"}
+`,
+			wantVersion: 2,
+		},
+		{
+			name: "newer version",
+			log: `{"format":"shortener-urls","version":3}
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "urls.json")
+			require.NoError(t, os.WriteFile(fileName, []byte(test.log), 0666))
+
+			check, err := VerifyFile(fileName)
+			if test.wantErr {
+				assert.Error(t, err)
+				_, err = NewFileURLs(fileName, "", zaptest.NewLogger(t).Sugar())
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.wantVersion, check.Version)
+			if assert.Len(t, check.URLs, 1) {
+				assert.NotNil(t, check.URLs[0].DeletedAt, "deletion time is set by the migration")
+			}
+
+			repo, err := NewFileURLs(fileName, "", zaptest.NewLogger(t).Sugar())
+			require.NoError(t, err)
+			require.NoError(t, repo.Close())
+			check, err = VerifyFile(fileName)
+			require.NoError(t, err)
+			assert.Equal(t, fileVersion, check.Version, "the file is rewritten in the current version")
+		})
+	}
+}
+
+func TestFileCompression(t *testing.T) {
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "urls.json")
+			ctx := context.Background()
+
+			repo, err := NewFileURLs(fileName, compression, zaptest.NewLogger(t).Sugar())
+			require.NoError(t, err)
+			_, err = repo.AddURL(ctx, "EwH", "https://pract.ru/url1", testUserID)
+			require.NoError(t, err)
+			require.NoError(t, repo.AddClick(ctx, "EwH"))
+			// The storage is not closed, the appended records are read back.
+
+			check, err := VerifyFile(fileName)
+			require.NoError(t, err)
+			assert.Equal(t, compression, check.Compression)
+			assert.Equal(t, 2, check.Records)
+			if assert.Len(t, check.URLs, 1) {
+				assert.Equal(t, int64(1), check.URLs[0].Clicks)
+			}
+
+			// A torn append is skipped.
+			data, err := os.ReadFile(fileName)
+			require.NoError(t, err)
+			_, err = repo.AddURL(ctx, "Ert", "https://pract.ru/url2", testUserID)
+			require.NoError(t, err)
+			full, err := os.ReadFile(fileName)
+			require.NoError(t, err)
+			require.NoError(t, repo.Close())
+			tornName := filepath.Join(t.TempDir(), "torn.json")
+			require.NoError(t, os.WriteFile(tornName, full[:len(data)+(len(full)-len(data))/2], 0666))
+
+			reopened, err := NewFileURLs(tornName, CompressionGzip, zaptest.NewLogger(t).Sugar())
+			require.NoError(t, err)
+			defer reopened.Close()
+			assert.Len(t, reopened.Urls, 1)
+			check, err = VerifyFile(tornName)
+			require.NoError(t, err)
+			assert.Equal(t, CompressionGzip, check.Compression, "the file is compacted with the new compression")
+		})
+	}
+
+	_, err := NewFileURLs(filepath.Join(t.TempDir(), "urls.json"), "lz4", zaptest.NewLogger(t).Sugar())
+	assert.Error(t, err)
+}
+
+func TestFileCorruptBlock(t *testing.T) {
+	lines := []string{
+		`{"format":"shortener-urls","version":2}` + "\n" +
+			`{"short_url":"EwH","original_url":"https://pract.ru/url1","user_id":1,"created_at":"2024-01-02T03:04:05Z"}` + "\n",
+		`{"short_url":"Ert","original_url":"https://pract.ru/url2","user_id":1,"created_at":"2024-01-02T03:04:05Z"}` + "\n",
+		`{"short_url":"Rty","original_url":"https://pract.ru/url3","user_id":1,"created_at":"2024-01-02T03:04:05Z"}` + "\n",
+	}
+	for _, compression := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			var blocks [][]byte
+			for _, v := range lines {
+				block, err := compress(compression, []byte(v))
+				require.NoError(t, err)
+				blocks = append(blocks, block)
+			}
+			// A byte in the middle of the second block is damaged, the third block is intact.
+			data := bytes.Join(blocks, nil)
+			data[len(blocks[0])+len(blocks[1])/2] ^= 0xff
+			fileName := filepath.Join(t.TempDir(), "urls.json")
+			require.NoError(t, os.WriteFile(fileName, data, 0666))
+
+			_, err := VerifyFile(fileName)
+			assert.Error(t, err, "a corrupt block before the end is not a torn tail")
+			_, err = NewFileURLs(fileName, compression, zaptest.NewLogger(t).Sugar())
+			assert.Error(t, err)
+			after, err := os.ReadFile(fileName)
+			require.NoError(t, err)
+			assert.Equal(t, data, after, "the records after the corrupt block are not lost")
+
+			// The same block cut off at the end of the file is torn.
+			require.NoError(t, os.WriteFile(fileName, data[:len(blocks[0])+len(blocks[1])/2], 0666))
+			check, err := VerifyFile(fileName)
+			require.NoError(t, err)
+			assert.True(t, check.TornTail)
+			assert.Len(t, check.URLs, 1)
+		})
+	}
+}
+
+func TestRepairAndCompactFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "urls.json")
+	log := `{"format":"shortener-urls","version":2}
+{"short_url":"EwH","original_url":"https://pract.ru/url1","user_id":1,"created_at":"2024-01-02T03:04:05Z"}
+not json
+{"op":"click","short_url":"EwH"}
+{"original_url":"https://pract.ru/url2","user_id":1}
+{"short_url":"Rty","original_url":"https://pract.ru/url3","user_id":1,"created_at":"2024-01-02T03:04:05Z"}
+`
+	require.NoError(t, os.WriteFile(fileName, []byte(log), 0666))
+
+	check, err := VerifyFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, 3, check.Records)
+	assert.Len(t, check.URLs, 2)
+	if assert.Len(t, check.Corrupt, 2) {
+		assert.Equal(t, 3, check.Corrupt[0].Line)
+		assert.Equal(t, "not json", check.Corrupt[0].Data)
+		assert.Equal(t, 5, check.Corrupt[1].Line)
+	}
+	data, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, log, string(data), "verify does not change the file")
+
+	_, err = CompactFile(fileName, "")
+	assert.Error(t, err, "a corrupt file is not compacted")
+
+	_, err = RepairFile(fileName, CompressionZstd)
+	require.NoError(t, err)
+	quarantined, err := os.ReadFile(fileName + ".corrupt")
+	require.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(quarantined, []byte("\n")))
+
+	check, err = CompactFile(fileName, CompressionNone)
+	require.NoError(t, err)
+	assert.Empty(t, check.Corrupt)
+	assert.Equal(t, CompressionZstd, check.Compression)
+
+	check, err = VerifyFile(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, CompressionNone, check.Compression)
+	assert.Equal(t, 2, check.Records)
+	if assert.Len(t, check.URLs, 2) {
+		assert.Equal(t, int64(1), check.URLs[0].Clicks)
+		assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), check.URLs[0].CreatedAt.UTC())
+	}
+
+	_, err = VerifyFile(filepath.Join(t.TempDir(), "none.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// lockFile does nothing, the storage file is not locked on this system.
+func lockFile(file *os.File) error {
+	return nil
+}
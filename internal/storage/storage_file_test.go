@@ -1,11 +1,17 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 )
 
 var testFileName = "for_tests.json"
@@ -26,9 +32,19 @@ func fillFile() error {
 	return nil
 }
 
+// newTestFileURLs opens the test file storage and closes it after the test,
+// so the next test can lock the file.
+func newTestFileURLs(t *testing.T) (*FileURLs, error) {
+	repo, err := NewFileURLs(testFileName, "", zaptest.NewLogger(t).Sugar())
+	if err == nil {
+		t.Cleanup(func() { repo.Close() })
+	}
+	return repo, err
+}
+
 func TestNewFileURLs(t *testing.T) {
 	t.Run("create file", func(t *testing.T) {
-		file, err := NewFileURLs(testFileName)
+		file, err := newTestFileURLs(t)
 		if assert.NoError(t, err) {
 			assert.NotEmpty(t, file)
 		}
@@ -40,7 +56,7 @@ func TestFileGetURL(t *testing.T) {
 	if err != nil {
 		t.Fatal("Unable to create file:", err)
 	}
-	testRepo, err := NewFileURLs(testFileName)
+	testRepo, err := newTestFileURLs(t)
 	tests := []struct {
 		name     string
 		short    string
@@ -73,7 +89,7 @@ func TestFileGetURL(t *testing.T) {
 }
 
 func TestFileAddURL(t *testing.T) {
-	testRepo, err := NewFileURLs(testFileName)
+	testRepo, err := newTestFileURLs(t)
 	t.Run("add url in file", func(t *testing.T) {
 		if assert.NoError(t, err) {
 			_, err := testRepo.AddURL(context.Background(), "sh", "https://mail.ru", testUserID)
@@ -83,7 +99,7 @@ func TestFileAddURL(t *testing.T) {
 }
 
 func TestFileAddBatch(t *testing.T) {
-	testRepo, errFile := NewFileURLs(testFileName)
+	testRepo, errFile := newTestFileURLs(t)
 	testRequestBatch := []RequestBatch{
 		{
 			CorrelationID: "ind1",
@@ -109,10 +125,20 @@ func TestFileAddBatch(t *testing.T) {
 
 	t.Run("add batch in file", func(t *testing.T) {
 		if assert.NoError(t, errFile) {
-			err := testRepo.AddBatch(context.Background(), testResponseBatch, testRequestBatch, testUserID)
+			_, err := testRepo.AddBatch(context.Background(), testResponseBatch, testRequestBatch, testUserID)
 			assert.NoError(t, err)
 		}
 	})
+	t.Run("add existing batch in file", func(t *testing.T) {
+		if assert.NoError(t, errFile) {
+			results, err := testRepo.AddBatch(context.Background(),
+				[]ResponseBatch{{CorrelationID: "ind3", ShortURL: "aaa"}},
+				[]RequestBatch{{CorrelationID: "ind3", OriginalURL: "https://pract.ru/url2"}},
+				testUserID)
+			assert.NoError(t, err)
+			assert.Equal(t, []BatchResult{{ShortURL: cfg.URL + "rrr", Status: BatchExists}}, results)
+		}
+	})
 }
 
 func TestFileGetAllUserURLs(t *testing.T) {
@@ -120,7 +146,7 @@ func TestFileGetAllUserURLs(t *testing.T) {
 	if err != nil {
 		t.Fatal("Unable to create file:", err)
 	}
-	testRepo, errFile := NewFileURLs(testFileName)
+	testRepo, errFile := newTestFileURLs(t)
 	t.Run("get user urls", func(t *testing.T) {
 		if assert.NoError(t, errFile) {
 			userURLs, err := testRepo.GetAllUserURLs(context.Background(), cfg.URL, 1777238335)
@@ -135,17 +161,87 @@ func TestFileDeleteUserURLs(t *testing.T) {
 	if err != nil {
 		t.Fatal("Unable to create file:", err)
 	}
-	testRepo, errFile := NewFileURLs(testFileName)
+	testRepo, errFile := newTestFileURLs(t)
 	t.Run("mark deleted", func(t *testing.T) {
 		if assert.NoError(t, errFile) {
-			err := testRepo.DeleteUserURLs(context.Background(), []string{"H_O4PA", "-YtNlA"}, 1777238335)
+			deleted, err := testRepo.DeleteUserURLs(context.Background(), []string{"H_O4PA", "-YtNlA", "OGAE8Q"}, 1777238335)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"H_O4PA", "-YtNlA"}, deleted)
+		}
+	})
+}
+
+func TestFileIterateUserURLs(t *testing.T) {
+	err := fillFile()
+	if err != nil {
+		t.Fatal("Unable to create file:", err)
+	}
+	testRepo, errFile := newTestFileURLs(t)
+	t.Run("iterate user urls", func(t *testing.T) {
+		if assert.NoError(t, errFile) {
+			cnt := 0
+			err := testRepo.IterateUserURLs(context.Background(), 1777238335, func(url URLInfo) error {
+				cnt++
+				return nil
+			})
 			assert.NoError(t, err)
+			assert.Equal(t, 4, cnt)
 		}
 	})
 }
 
+func TestFileAddClick(t *testing.T) {
+	err := fillFile()
+	if err != nil {
+		t.Fatal("Unable to create file:", err)
+	}
+	testRepo, errFile := newTestFileURLs(t)
+	t.Run("add click", func(t *testing.T) {
+		if assert.NoError(t, errFile) {
+			assert.NoError(t, testRepo.AddClick(context.Background(), "dfT_vA"))
+			assert.Equal(t, int64(1), testRepo.Urls[4].Clicks)
+		}
+	})
+}
+
+func TestFileRestoreAndPurge(t *testing.T) {
+	err := fillFile()
+	if err != nil {
+		t.Fatal("Unable to create file:", err)
+	}
+	testRepo, errFile := newTestFileURLs(t)
+	if !assert.NoError(t, errFile) {
+		return
+	}
+	_, err = testRepo.DeleteUserURLs(context.Background(), []string{"H_O4PA", "-YtNlA"}, 1777238335)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("restore within period", func(t *testing.T) {
+		restored, err := testRepo.RestoreUserURLs(context.Background(), []string{"H_O4PA", "dfT_vA"}, 1777238335, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"H_O4PA"}, restored)
+		assert.False(t, testRepo.Urls[1].DeletedFlag)
+		assert.Nil(t, testRepo.Urls[1].DeletedAt)
+	})
+
+	t.Run("restore after period", func(t *testing.T) {
+		restored, err := testRepo.RestoreUserURLs(context.Background(), []string{"-YtNlA"}, 1777238335, time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Empty(t, restored)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		purged, err := testRepo.PurgeDeletedURLs(context.Background(), time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		assert.Equal(t, 4, len(testRepo.Urls))
+	})
+}
+
 func TestFilePingStor(t *testing.T) {
-	testRepo, errFile := NewFileURLs(testFileName)
+	testRepo, errFile := newTestFileURLs(t)
 	t.Run("ping", func(t *testing.T) {
 		if assert.NoError(t, errFile) {
 			assert.NoError(t, testRepo.PingStor(context.Background()))
@@ -156,7 +252,7 @@ func TestFilePingStor(t *testing.T) {
 }
 
 func TestFileClose(t *testing.T) {
-	testRepo, errFile := NewFileURLs(testFileName)
+	testRepo, errFile := newTestFileURLs(t)
 	t.Run("close storage", func(t *testing.T) {
 		if assert.NoError(t, errFile) {
 			assert.NoError(t, testRepo.Close())
@@ -169,7 +265,7 @@ func TestFileGetStats(t *testing.T) {
 	if err != nil {
 		t.Fatal("Unable to create file:", err)
 	}
-	testRepo, errFile := NewFileURLs(testFileName)
+	testRepo, errFile := newTestFileURLs(t)
 	t.Run("get stats", func(t *testing.T) {
 		if assert.NoError(t, errFile) {
 			stats, err := testRepo.GetStats(context.Background())
@@ -178,3 +274,171 @@ func TestFileGetStats(t *testing.T) {
 		}
 	})
 }
+
+func TestFileLogReplay(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		want    []string
+		clicks  int64
+		corrupt int
+	}{
+		{
+			name: "changes and torn last line",
+			log: `{"short_url":"EwH","original_url":"https://pract.ru/url1","user_id":1}
+{"short_url":"Ert","original_url":"https://pract.ru/url2","user_id":1}
+{"op":"click","short_url":"EwH"}
+{"op":"click","short_url":"EwH"}
+{"short_url":"Ert","original_url":"https://pract.ru/url2","user_id":1,"is_deleted":true,"deleted_at":"2024-01-02T03:04:05Z"}
+{"op":"purge","short_url":"Ert"}
+{"short_url":"Rty","original_url":"https://pract.ru/url3","user_id":1}
+{"short_url":"Yui","original_url":"https://pr`,
+			want:   []string{"EwH", "Rty"},
+			clicks: 2,
+		},
+		{
+			name: "broken lines in the middle",
+			log: `{"short_url":"EwH","original_url":"https://pract.ru/url1","user_id":1}
+{"short_url":"Ert","orig
+{"op":"rename","short_url":"EwH"}
+{"short_url":"Rty","original_url":"https://pract.ru/url3","user_id":1}
+`,
+			want:    []string{"EwH", "Rty"},
+			corrupt: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "urls.json")
+			require.NoError(t, os.WriteFile(fileName, []byte(test.log), 0666))
+
+			repo, err := NewFileURLs(fileName, "", zaptest.NewLogger(t).Sugar())
+			require.NoError(t, err)
+			defer repo.Close()
+
+			var got []string
+			for _, v := range repo.Urls {
+				got = append(got, v.ShortURL)
+			}
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.clicks, repo.Urls[0].Clicks)
+
+			data, err := os.ReadFile(fileName)
+			require.NoError(t, err)
+			assert.Equal(t, len(test.want)+1, bytes.Count(data, []byte("\n")), "the log is compacted on opening")
+
+			quarantined, err := os.ReadFile(fileName + ".corrupt")
+			if test.corrupt == 0 {
+				assert.True(t, os.IsNotExist(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.corrupt, bytes.Count(quarantined, []byte("\n")))
+		})
+	}
+}
+
+func TestFileChangesSurviveCrash(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "urls.json")
+	ctx := context.Background()
+
+	repo, err := NewFileURLs(fileName, "", zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	_, err = repo.AddURL(ctx, "EwH", "https://pract.ru/url1", testUserID)
+	require.NoError(t, err)
+	_, err = repo.AddURL(ctx, "Ert", "https://pract.ru/url2", testUserID)
+	require.NoError(t, err)
+	require.NoError(t, repo.AddClick(ctx, "EwH"))
+	_, err = repo.DeleteUserURLs(ctx, []string{"Ert"}, testUserID)
+	require.NoError(t, err)
+	// The storage is not closed, as after a crash: only the lock is released with the file.
+	require.NoError(t, repo.file.Close())
+
+	reopened, err := NewFileURLs(fileName, "", zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	_, isDel, ok := reopened.GetURL(ctx, "Ert")
+	assert.True(t, ok)
+	assert.True(t, isDel)
+	if assert.Len(t, reopened.Urls, 2) {
+		assert.Equal(t, int64(1), reopened.Urls[0].Clicks)
+	}
+}
+
+func TestFileLock(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "urls.json")
+	repo, err := NewFileURLs(fileName, "", zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	_, err = repo.AddURL(context.Background(), "EwH", "https://pract.ru/url1", testUserID)
+	require.NoError(t, err)
+
+	_, err = NewFileURLs(fileName, "", zaptest.NewLogger(t).Sugar())
+	assert.ErrorIs(t, err, ErrFileLocked, "the storage is open")
+	_, err = RepairFile(fileName, "")
+	assert.ErrorIs(t, err, ErrFileLocked)
+	_, err = CompactFile(fileName, "")
+	assert.ErrorIs(t, err, ErrFileLocked)
+
+	require.NoError(t, repo.compact())
+	_, err = CompactFile(fileName, "")
+	assert.ErrorIs(t, err, ErrFileLocked, "the compacted log is locked")
+
+	require.NoError(t, repo.Close())
+	check, err := CompactFile(fileName, "")
+	require.NoError(t, err)
+	assert.Len(t, check.URLs, 1)
+	reopened, err := NewFileURLs(fileName, "", zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err, "the lock is released on closing")
+	assert.NoError(t, reopened.Close())
+}
+
+func TestFileStartCompaction(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "urls.json")
+	ctx := context.Background()
+
+	repo, err := NewFileURLs(fileName, "", zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	defer repo.Close()
+
+	_, err = repo.AddURL(ctx, "EwH", "https://pract.ru/url1", testUserID)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.AddClick(ctx, "EwH"))
+	}
+
+	lines := func() int {
+		data, err := os.ReadFile(fileName)
+		require.NoError(t, err)
+		return bytes.Count(data, []byte("\n"))
+	}
+	assert.Equal(t, 7, lines())
+
+	repo.StartCompaction(10 * time.Millisecond)
+	assert.Eventually(t, func() bool { return lines() == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestFileCompactionKeepsConcurrentWrites(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "urls.json")
+	ctx := context.Background()
+
+	repo, err := NewFileURLs(fileName, CompressionGzip, zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	repo.StartCompaction(time.Millisecond)
+
+	const count = 200
+	for i := 0; i < count; i++ {
+		_, err = repo.AddURL(ctx, "short"+strconv.Itoa(i), "https://pract.ru/url"+strconv.Itoa(i), testUserID)
+		require.NoError(t, err)
+	}
+	// The log file is read without closing the storage, as after a crash.
+	check, err := VerifyFile(fileName)
+	require.NoError(t, err)
+	assert.Len(t, check.URLs, count)
+
+	require.NoError(t, repo.Close())
+	assert.NoError(t, repo.Close(), "repeated close")
+	_, err = repo.AddURL(ctx, "closed", "https://pract.ru/closed", testUserID)
+	assert.ErrorIs(t, err, errFileClosed)
+}
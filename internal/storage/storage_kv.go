@@ -0,0 +1,574 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the key-value storage.
+var (
+	// bucketURLs - short URL -> kvURL.
+	bucketURLs = []byte("urls")
+	// bucketUserURLs - user ID + short URL -> empty value, index of the user's URLs.
+	bucketUserURLs = []byte("user_urls")
+	// bucketUserOrigins - user ID + original URL -> short URL, unique index of the user's original URLs.
+	bucketUserOrigins = []byte("user_origins")
+	// bucketDeleted - deletion time + short URL -> empty value, index of deleted URLs for the purge.
+	bucketDeleted = []byte("deleted")
+)
+
+// kvOpenTimeout - how long to wait for the database file lock held by another process.
+const kvOpenTimeout = time.Second
+
+// kvURL stores URL information in the key-value storage.
+type kvURL struct {
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	DeletedFlag bool       `json:"is_deleted"`
+	UserID      int        `json:"user_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Clicks      int64      `json:"clicks"`
+}
+
+// KVURLs stores URLs in an embedded transactional key-value database (bbolt).
+// Every change is committed to disk, the file is never rewritten as a whole.
+type KVURLs struct {
+	db *bolt.DB
+}
+
+// NewKVURLs opens the database file and creates the buckets.
+func NewKVURLs(fileName string) (*KVURLs, error) {
+	db, err := bolt.Open(fileName, 0600, &bolt.Options{Timeout: kvOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketURLs, bucketUserURLs, bucketUserOrigins, bucketDeleted} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &KVURLs{db: db}, nil
+}
+
+// userKey creates an index key: the user ID followed by the value.
+func userKey(userID int, value string) []byte {
+	key := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(key, uint64(userID))
+	return append(key, value...)
+}
+
+// deletedKey creates a key of the deleted URLs index ordered by the deletion time.
+func deletedKey(deletedAt time.Time, shortURL string) []byte {
+	key := make([]byte, 8, 8+len(shortURL))
+	binary.BigEndian.PutUint64(key, uint64(deletedAt.UnixNano()))
+	return append(key, shortURL...)
+}
+
+// getURL reads the URL record, returns false if there is no such short URL.
+func getURL(tx *bolt.Tx, shortURL string) (url kvURL, ok bool, err error) {
+	data := tx.Bucket(bucketURLs).Get([]byte(shortURL))
+	if data == nil {
+		return kvURL{}, false, nil
+	}
+	if err = json.Unmarshal(data, &url); err != nil {
+		return kvURL{}, false, err
+	}
+	return url, true, nil
+}
+
+// putURL saves the URL record.
+func putURL(tx *bolt.Tx, url kvURL) error {
+	data, err := json.Marshal(url)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketURLs).Put([]byte(url.ShortURL), data)
+}
+
+// insertURL saves a new URL record and its index entries.
+func insertURL(tx *bolt.Tx, url kvURL) error {
+	if tx.Bucket(bucketURLs).Get([]byte(url.ShortURL)) != nil {
+		return ErrShortURLExists
+	}
+	if err := putURL(tx, url); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketUserURLs).Put(userKey(url.UserID, url.ShortURL), nil); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketUserOrigins).Put(userKey(url.UserID, url.OriginalURL), []byte(url.ShortURL))
+}
+
+// GetURL gets the original URL matching the short URL.
+func (kv *KVURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
+	if ctx.Err() != nil {
+		return "", false, false
+	}
+
+	var url kvURL
+	err := kv.db.View(func(tx *bolt.Tx) (err error) {
+		url, ok, err = getURL(tx, shortURL)
+		return err
+	})
+	if err != nil || !ok {
+		return "", false, false
+	}
+	return url.OriginalURL, url.DeletedFlag, true
+}
+
+// AddURL adds a new short url.
+func (kv *KVURLs) AddURL(ctx context.Context, shortURL string, originURL string, userID int) (findURL string, err error) {
+	if err = ctx.Err(); err != nil {
+		return "", err
+	}
+
+	err = kv.db.Update(func(tx *bolt.Tx) error {
+		if existing := tx.Bucket(bucketUserOrigins).Get(userKey(userID, originURL)); existing != nil {
+			findURL = string(existing)
+			return ErrConflict
+		}
+		return insertURL(tx, kvURL{
+			ShortURL:    shortURL,
+			OriginalURL: originURL,
+			UserID:      userID,
+			CreatedAt:   time.Now(),
+		})
+	})
+	return findURL, err
+}
+
+// AddBatch adds a batch of new short URLs in one transaction.
+func (kv *KVURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (results []BatchResult, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err = kv.db.Update(func(tx *bolt.Tx) error {
+		origins := tx.Bucket(bucketUserOrigins)
+		existing := make(map[string]string)
+		for _, v := range originURLBatch {
+			if short := origins.Get(userKey(userID, v.OriginalURL)); short != nil {
+				existing[v.OriginalURL] = string(short)
+			}
+		}
+
+		now := time.Now()
+		var added []int
+		results, added = resolveBatch(existing, shortURLBatch, originURLBatch)
+		for _, k := range added {
+			err := insertURL(tx, kvURL{
+				ShortURL:    shortURLBatch[k].ShortURL,
+				OriginalURL: originURLBatch[k].OriginalURL,
+				UserID:      userID,
+				CreatedAt:   now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetAllUserURLs gets all user's short url.
+func (kv *KVURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []UserURL, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err = kv.db.View(func(tx *bolt.Tx) error {
+		prefix := userKey(userID, "")
+		c := tx.Bucket(bucketUserURLs).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			url, ok, err := getURL(tx, string(k[len(prefix):]))
+			if err != nil {
+				return err
+			}
+			if ok {
+				userURLs = append(userURLs, UserURL{
+					ShortURL:    baseURL + url.ShortURL,
+					OriginalURL: url.OriginalURL,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userURLs, nil
+}
+
+// IterateUserURLs calls fn for each user's URL.
+// URLs are read in small pages, so the transaction is not open while fn is running.
+func (kv *KVURLs) IterateUserURLs(ctx context.Context, userID int, fn func(url URLInfo) error) (err error) {
+	prefix := userKey(userID, "")
+	from := prefix
+	page := make([]URLInfo, 0, iteratePageSize)
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		page = page[:0]
+		end := true
+		err = kv.db.View(func(tx *bolt.Tx) error {
+			c := tx.Bucket(bucketUserURLs).Cursor()
+			k, _ := c.Seek(from)
+			if len(from) > len(prefix) && bytes.Equal(k, from) {
+				k, _ = c.Next()
+			}
+			for ; k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				if len(page) == iteratePageSize {
+					end = false
+					return nil
+				}
+				url, ok, err := getURL(tx, string(k[len(prefix):]))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+				page = append(page, URLInfo{
+					ShortURL:    url.ShortURL,
+					OriginalURL: url.OriginalURL,
+					UserID:      url.UserID,
+					DeletedFlag: url.DeletedFlag,
+					CreatedAt:   url.CreatedAt,
+					Clicks:      url.Clicks,
+				})
+				from = append(from[:0:0], k...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, v := range page {
+			if err = fn(v); err != nil {
+				return err
+			}
+		}
+		if end {
+			return nil
+		}
+	}
+}
+
+// IterateURLs calls fn for each URL ordered by the short URL, starting after the short URL after.
+// URLs are read in small pages, so the transaction is not open while fn is running.
+func (kv *KVURLs) IterateURLs(ctx context.Context, after string, fn func(url URLRecord) error) (err error) {
+	from := []byte(after)
+	page := make([]URLRecord, 0, iteratePageSize)
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		page = page[:0]
+		end := true
+		err = kv.db.View(func(tx *bolt.Tx) error {
+			c := tx.Bucket(bucketURLs).Cursor()
+			k, v := c.Seek(from)
+			if bytes.Equal(k, from) {
+				k, v = c.Next()
+			}
+			for ; k != nil; k, v = c.Next() {
+				if len(page) == iteratePageSize {
+					end = false
+					return nil
+				}
+				var url kvURL
+				if err := json.Unmarshal(v, &url); err != nil {
+					return err
+				}
+				page = append(page, URLRecord{
+					URLInfo: URLInfo{
+						ShortURL:    url.ShortURL,
+						OriginalURL: url.OriginalURL,
+						UserID:      url.UserID,
+						DeletedFlag: url.DeletedFlag,
+						CreatedAt:   url.CreatedAt,
+						Clicks:      url.Clicks,
+					},
+					DeletedAt: url.DeletedAt,
+				})
+				from = append(from[:0:0], k...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, v := range page {
+			if err = fn(v); err != nil {
+				return err
+			}
+		}
+		if end {
+			return nil
+		}
+	}
+}
+
+// LoadURLs adds the URLs with all their data in one transaction, existing ones are skipped.
+func (kv *KVURLs) LoadURLs(ctx context.Context, records []URLRecord) (loaded int, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	err = kv.db.Update(func(tx *bolt.Tx) error {
+		loaded = 0
+		for _, v := range records {
+			if tx.Bucket(bucketURLs).Get([]byte(v.ShortURL)) != nil ||
+				tx.Bucket(bucketUserOrigins).Get(userKey(v.UserID, v.OriginalURL)) != nil {
+				continue
+			}
+			url := kvURL{
+				ShortURL:    v.ShortURL,
+				OriginalURL: v.OriginalURL,
+				DeletedFlag: v.DeletedFlag,
+				UserID:      v.UserID,
+				CreatedAt:   v.CreatedAt,
+				DeletedAt:   v.DeletedAt,
+				Clicks:      v.Clicks,
+			}
+			if url.DeletedFlag && url.DeletedAt == nil {
+				url.DeletedAt = &now
+			}
+			if err := insertURL(tx, url); err != nil {
+				return err
+			}
+			if url.DeletedFlag {
+				if err := tx.Bucket(bucketDeleted).Put(deletedKey(*url.DeletedAt, url.ShortURL), nil); err != nil {
+					return err
+				}
+			}
+			loaded++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return loaded, nil
+}
+
+// AddClick increases the number of redirects by the short URL.
+func (kv *KVURLs) AddClick(ctx context.Context, shortURL string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	return kv.db.Update(func(tx *bolt.Tx) error {
+		url, ok, err := getURL(tx, shortURL)
+		if err != nil || !ok {
+			return err
+		}
+		url.Clicks++
+		return putURL(tx, url)
+	})
+}
+
+// AddClicks increases the numbers of redirects by the short URLs in one transaction.
+func (kv *KVURLs) AddClicks(ctx context.Context, clicks map[string]int64) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	return kv.db.Update(func(tx *bolt.Tx) error {
+		for shortURL, n := range clicks {
+			url, ok, err := getURL(tx, shortURL)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			url.Clicks += n
+			if err = putURL(tx, url); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteUserURLs sets the deletion flag to the user URLs sent in the request.
+func (kv *KVURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
+	now := time.Now()
+	err = kv.db.Update(func(tx *bolt.Tx) error {
+		for _, delURL := range delURLs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			url, ok, err := getURL(tx, delURL)
+			if err != nil {
+				return err
+			}
+			if !ok || url.UserID != userID {
+				continue
+			}
+			if !url.DeletedFlag {
+				url.DeletedFlag = true
+				url.DeletedAt = &now
+				if err = putURL(tx, url); err != nil {
+					return err
+				}
+				if err = tx.Bucket(bucketDeleted).Put(deletedKey(now, delURL), nil); err != nil {
+					return err
+				}
+			}
+			deleted = append(deleted, delURL)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// RestoreUserURLs clears the deletion flag of the user URLs deleted after deletedAfter.
+func (kv *KVURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	err = kv.db.Update(func(tx *bolt.Tx) error {
+		for _, shortURL := range shortURLs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			url, ok, err := getURL(tx, shortURL)
+			if err != nil {
+				return err
+			}
+			if !ok || url.UserID != userID || !url.DeletedFlag || url.DeletedAt == nil || !url.DeletedAt.After(deletedAfter) {
+				continue
+			}
+			if err = tx.Bucket(bucketDeleted).Delete(deletedKey(*url.DeletedAt, shortURL)); err != nil {
+				return err
+			}
+			url.DeletedFlag = false
+			url.DeletedAt = nil
+			if err = putURL(tx, url); err != nil {
+				return err
+			}
+			restored = append(restored, shortURL)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// PurgeDeletedURLs permanently removes URLs deleted before deletedBefore.
+func (kv *KVURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	err = kv.db.Update(func(tx *bolt.Tx) error {
+		limit := deletedKey(deletedBefore, "")
+		var keys [][]byte
+		c := tx.Bucket(bucketDeleted).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k[:8], limit) < 0; k, _ = c.Next() {
+			keys = append(keys, k)
+		}
+
+		for _, k := range keys {
+			shortURL := string(k[8:])
+			url, ok, err := getURL(tx, shortURL)
+			if err != nil {
+				return err
+			}
+			if ok {
+				if err = tx.Bucket(bucketURLs).Delete([]byte(shortURL)); err != nil {
+					return err
+				}
+				if err = tx.Bucket(bucketUserURLs).Delete(userKey(url.UserID, shortURL)); err != nil {
+					return err
+				}
+				origins := tx.Bucket(bucketUserOrigins)
+				originKey := userKey(url.UserID, url.OriginalURL)
+				if string(origins.Get(originKey)) == shortURL {
+					if err = origins.Delete(originKey); err != nil {
+						return err
+					}
+				}
+				purged++
+			}
+			if err = tx.Bucket(bucketDeleted).Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// GetStats gets statistics - amount URLs and users.
+func (kv *KVURLs) GetStats(ctx context.Context) (stats ServiceStats, err error) {
+	if err = ctx.Err(); err != nil {
+		return ServiceStats{}, err
+	}
+
+	err = kv.db.View(func(tx *bolt.Tx) error {
+		stats.URLs = tx.Bucket(bucketURLs).Stats().KeyN
+
+		c := tx.Bucket(bucketUserURLs).Cursor()
+		for k, _ := c.First(); k != nil; {
+			stats.Users++
+			next := binary.BigEndian.Uint64(k[:8]) + 1
+			if next == 0 {
+				break
+			}
+			k, _ = c.Seek(userKey(int(next), ""))
+		}
+		return nil
+	})
+	if err != nil {
+		return ServiceStats{}, err
+	}
+	return stats, nil
+}
+
+// PingStor checking access to storage.
+func (kv *KVURLs) PingStor(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return kv.db.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+// Close closes the database file.
+func (kv *KVURLs) Close() error {
+	return kv.db.Close()
+}
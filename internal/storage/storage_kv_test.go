@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKV(t *testing.T) (*KVURLs, string) {
+	fileName := filepath.Join(t.TempDir(), "urls.db")
+	kv, err := NewKVURLs(fileName)
+	require.NoError(t, err)
+	return kv, fileName
+}
+
+func TestKVAddAndGetURL(t *testing.T) {
+	kv, fileName := newTestKV(t)
+	ctx := context.Background()
+
+	t.Run("add url", func(t *testing.T) {
+		_, err := kv.AddURL(ctx, "EwH", "https://practicum.yandex.ru/", testUserID)
+		assert.NoError(t, err)
+	})
+	t.Run("add existing url", func(t *testing.T) {
+		findURL, err := kv.AddURL(ctx, "Ert", "https://practicum.yandex.ru/", testUserID)
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, "EwH", findURL)
+	})
+	t.Run("add existing short url", func(t *testing.T) {
+		_, err := kv.AddURL(ctx, "EwH", "https://mail.ru/", 88)
+		assert.ErrorIs(t, err, ErrShortURLExists)
+	})
+	t.Run("get url", func(t *testing.T) {
+		originURL, isDel, ok := kv.GetURL(ctx, "EwH")
+		assert.True(t, ok)
+		assert.False(t, isDel)
+		assert.Equal(t, "https://practicum.yandex.ru/", originURL)
+
+		_, _, ok = kv.GetURL(ctx, "Ert")
+		assert.False(t, ok)
+	})
+	t.Run("get url after reopening", func(t *testing.T) {
+		require.NoError(t, kv.Close())
+		reopened, err := NewKVURLs(fileName)
+		require.NoError(t, err)
+		defer reopened.Close()
+
+		originURL, _, ok := reopened.GetURL(ctx, "EwH")
+		assert.True(t, ok)
+		assert.Equal(t, "https://practicum.yandex.ru/", originURL)
+	})
+}
+
+func TestKVAddBatch(t *testing.T) {
+	kv, _ := newTestKV(t)
+	defer kv.Close()
+	ctx := context.Background()
+
+	_, err := kv.AddURL(ctx, "EwH", "https://pract.ru/url1", testUserID)
+	require.NoError(t, err)
+
+	results, err := kv.AddBatch(ctx,
+		[]ResponseBatch{{CorrelationID: "ind1", ShortURL: "ggg"}, {CorrelationID: "ind2", ShortURL: "rrr"}, {CorrelationID: "ind3", ShortURL: "ttt"}},
+		[]RequestBatch{{CorrelationID: "ind1", OriginalURL: "https://pract.ru/url1"}, {CorrelationID: "ind2", OriginalURL: "https://pract.ru/url2"}, {CorrelationID: "ind3", OriginalURL: "https://pract.ru/url2"}},
+		testUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, []BatchResult{
+		{ShortURL: "EwH", Status: BatchExists},
+		{ShortURL: "rrr", Status: BatchCreated},
+		{ShortURL: "rrr", Status: BatchExists},
+	}, results)
+
+	urls, err := kv.GetAllUserURLs(ctx, cfg.URL+"/", testUserID)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []UserURL{
+		{ShortURL: cfg.URL + "/EwH", OriginalURL: "https://pract.ru/url1"},
+		{ShortURL: cfg.URL + "/rrr", OriginalURL: "https://pract.ru/url2"},
+	}, urls)
+}
+
+func TestKVIterateUserURLs(t *testing.T) {
+	kv, _ := newTestKV(t)
+	defer kv.Close()
+	ctx := context.Background()
+
+	const count = iteratePageSize*2 + 5
+	for i := 0; i < count; i++ {
+		_, err := kv.AddURL(ctx, fmt.Sprintf("u%03d", i), fmt.Sprintf("https://pract.ru/%d", i), testUserID)
+		require.NoError(t, err)
+	}
+	_, err := kv.AddURL(ctx, "other", "https://mail.ru/", 88)
+	require.NoError(t, err)
+	require.NoError(t, kv.AddClick(ctx, "u000"))
+
+	var urls []URLInfo
+	err = kv.IterateUserURLs(ctx, testUserID, func(url URLInfo) error {
+		urls = append(urls, url)
+		return nil
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, urls, count) {
+		assert.Equal(t, "u000", urls[0].ShortURL)
+		assert.Equal(t, int64(1), urls[0].Clicks)
+		assert.Equal(t, fmt.Sprintf("u%03d", count-1), urls[count-1].ShortURL)
+	}
+
+	stats, err := kv.GetStats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, ServiceStats{URLs: count + 1, Users: 2}, stats)
+}
+
+func TestKVDeleteRestoreAndPurge(t *testing.T) {
+	kv, _ := newTestKV(t)
+	defer kv.Close()
+	ctx := context.Background()
+
+	for _, v := range []string{"EwH", "Ert", "Rty"} {
+		_, err := kv.AddURL(ctx, v, "https://pract.ru/"+v, testUserID)
+		require.NoError(t, err)
+	}
+
+	deleted, err := kv.DeleteUserURLs(ctx, []string{"EwH", "Ert", "Rty", "none"}, testUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"EwH", "Ert", "Rty"}, deleted)
+	deleted, err = kv.DeleteUserURLs(ctx, []string{"EwH"}, 88)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	_, isDel, ok := kv.GetURL(ctx, "EwH")
+	assert.True(t, ok)
+	assert.True(t, isDel)
+
+	restored, err := kv.RestoreUserURLs(ctx, []string{"EwH", "none"}, testUserID, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"EwH"}, restored)
+
+	purged, err := kv.PurgeDeletedURLs(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+
+	_, _, ok = kv.GetURL(ctx, "Ert")
+	assert.False(t, ok)
+	_, isDel, ok = kv.GetURL(ctx, "EwH")
+	assert.True(t, ok)
+	assert.False(t, isDel)
+
+	_, err = kv.AddURL(ctx, "New", "https://pract.ru/Ert", testUserID)
+	assert.NoError(t, err, "purged original URL can be added again")
+	assert.NoError(t, kv.PingStor(ctx))
+}
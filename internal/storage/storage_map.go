@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"slices"
+	"strconv"
 	"sync"
+	"time"
 )
 
 // MemURL stores URL information in memory.
@@ -13,6 +15,9 @@ type MemURL struct {
 	originURL   string
 	deletedFlag bool
 	userID      int
+	createdAt   time.Time
+	deletedAt   time.Time
+	clicks      int64
 }
 
 // MemURLs stores information about all URLs in memory.
@@ -30,6 +35,10 @@ func NewMapURLs() *MemURLs {
 
 // GetURL gets the original URL matching the short URL.
 func (urls *MemURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
+	if ctx.Err() != nil {
+		return "", false, false
+	}
+
 	urls.RLock()
 	defer urls.RUnlock()
 
@@ -42,40 +51,79 @@ func (urls *MemURLs) GetURL(ctx context.Context, shortURL string) (originURL str
 }
 
 // AddURL adds a new short url.
+// If the user has already shortened originURL, returns the existing short URL and ErrConflict.
+// Returns ErrShortURLExists if shortURL is used by another URL.
 func (urls *MemURLs) AddURL(ctx context.Context, shortURL string, originURL string, userID int) (findURL string, err error) {
+	if err = ctx.Err(); err != nil {
+		return "", err
+	}
+
 	urls.Lock()
 	defer urls.Unlock()
 
+	taken := false
+	for _, v := range urls.originalURLs {
+		if v.userID == userID && v.originURL == originURL {
+			return v.shortURL, ErrConflict
+		}
+		taken = taken || v.shortURL == shortURL
+	}
+	if taken {
+		return "", ErrShortURLExists
+	}
 	urls.originalURLs = append(urls.originalURLs, MemURL{
 		userID:      userID,
 		shortURL:    shortURL,
 		originURL:   originURL,
 		deletedFlag: false,
+		createdAt:   time.Now(),
 	})
 	return "", nil
 }
 
 // AddBatch adds a batch of new short URLs.
-func (urls *MemURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (err error) {
-	allUrls := make([]MemURL, len(shortURLBatch))
-	for k, v := range shortURLBatch {
-		allUrls = append(allUrls, MemURL{
+// Returns ErrShortURLExists and adds nothing if one of the short URLs is already used.
+func (urls *MemURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (results []BatchResult, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	urls.Lock()
+	defer urls.Unlock()
+
+	existing := make(map[string]string)
+	used := make(map[string]bool, len(urls.originalURLs))
+	for _, v := range urls.originalURLs {
+		if v.userID == userID {
+			existing[v.originURL] = v.shortURL
+		}
+		used[v.shortURL] = true
+	}
+
+	now := time.Now()
+	results, added := resolveBatch(existing, shortURLBatch, originURLBatch)
+	if err = checkShortURLs(used, shortURLBatch, added); err != nil {
+		return nil, err
+	}
+	for _, k := range added {
+		urls.originalURLs = append(urls.originalURLs, MemURL{
 			userID:      userID,
-			shortURL:    v.ShortURL,
+			shortURL:    shortURLBatch[k].ShortURL,
 			originURL:   originURLBatch[k].OriginalURL,
 			deletedFlag: false,
+			createdAt:   now,
 		})
 	}
 
-	urls.Lock()
-	defer urls.Unlock()
-	urls.originalURLs = append(urls.originalURLs, allUrls...)
-
-	return nil
+	return results, nil
 }
 
 // GetAllUserURLs gets all user's short url.
 func (urls *MemURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []UserURL, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	urls.RLock()
 	defer urls.RUnlock()
 
@@ -91,24 +139,216 @@ func (urls *MemURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID
 	return userURLs, nil
 }
 
+// IterateUserURLs calls fn for each user's URL in the order of the short URLs.
+// URLs are copied in small pages, so the lock is not held while fn is running.
+// The next page starts after the last short URL of the previous one,
+// so URLs added or purged while fn is running do not shift the pages.
+func (urls *MemURLs) IterateUserURLs(ctx context.Context, userID int, fn func(url URLInfo) error) (err error) {
+	page := make([]URLInfo, 0, iteratePageSize)
+	after := ""
+	for {
+		page = page[:0]
+		urls.RLock()
+		for _, v := range urls.originalURLs {
+			if v.userID == userID && v.shortURL > after {
+				page = addToPage(page, URLInfo{
+					ShortURL:    v.shortURL,
+					OriginalURL: v.originURL,
+					UserID:      v.userID,
+					DeletedFlag: v.deletedFlag,
+					CreatedAt:   v.createdAt,
+					Clicks:      v.clicks,
+				})
+			}
+		}
+		urls.RUnlock()
+
+		for _, v := range page {
+			if err = fn(v); err != nil {
+				return err
+			}
+		}
+		if len(page) < iteratePageSize {
+			return nil
+		}
+		after = page[len(page)-1].ShortURL
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// IterateURLs calls fn for each URL ordered by the short URL, starting after the short URL after.
+func (urls *MemURLs) IterateURLs(ctx context.Context, after string, fn func(url URLRecord) error) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	urls.RLock()
+	records := make([]URLRecord, 0, len(urls.originalURLs))
+	for _, v := range urls.originalURLs {
+		rec := URLRecord{URLInfo: URLInfo{
+			ShortURL:    v.shortURL,
+			OriginalURL: v.originURL,
+			UserID:      v.userID,
+			DeletedFlag: v.deletedFlag,
+			CreatedAt:   v.createdAt,
+			Clicks:      v.clicks,
+		}}
+		if v.deletedFlag {
+			deletedAt := v.deletedAt
+			rec.DeletedAt = &deletedAt
+		}
+		records = append(records, rec)
+	}
+	urls.RUnlock()
+
+	return iterateSorted(ctx, records, after, fn)
+}
+
+// LoadURLs adds the URLs with all their data, existing ones are skipped.
+func (urls *MemURLs) LoadURLs(ctx context.Context, records []URLRecord) (loaded int, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	urls.Lock()
+	defer urls.Unlock()
+
+	shortURLs := make(map[string]bool, len(urls.originalURLs))
+	origins := make(map[string]bool, len(urls.originalURLs))
+	for _, v := range urls.originalURLs {
+		shortURLs[v.shortURL] = true
+		origins[strconv.Itoa(v.userID)+" "+v.originURL] = true
+	}
+	canLoad := newLoadFilter(shortURLs, origins)
+
+	for _, v := range records {
+		if !canLoad(v) {
+			continue
+		}
+		url := MemURL{
+			shortURL:    v.ShortURL,
+			originURL:   v.OriginalURL,
+			deletedFlag: v.DeletedFlag,
+			userID:      v.UserID,
+			createdAt:   v.CreatedAt,
+			clicks:      v.Clicks,
+		}
+		if v.DeletedAt != nil {
+			url.deletedAt = *v.DeletedAt
+		}
+		urls.originalURLs = append(urls.originalURLs, url)
+		loaded++
+	}
+	return loaded, nil
+}
+
+// AddClick increases the number of redirects by the short URL.
+func (urls *MemURLs) AddClick(ctx context.Context, shortURL string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	urls.Lock()
+	defer urls.Unlock()
+
+	for k, v := range urls.originalURLs {
+		if v.shortURL == shortURL {
+			urls.originalURLs[k].clicks++
+			return nil
+		}
+	}
+	return nil
+}
+
+// AddClicks increases the numbers of redirects by the short URLs.
+func (urls *MemURLs) AddClicks(ctx context.Context, clicks map[string]int64) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	urls.Lock()
+	defer urls.Unlock()
+
+	for k, v := range urls.originalURLs {
+		urls.originalURLs[k].clicks += clicks[v.shortURL]
+	}
+	return nil
+}
+
 // DeleteUserURLs sets the deletion flag to the user URLs sent in the request.
-func (urls *MemURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (err error) {
+func (urls *MemURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
 	urls.Lock()
 	defer urls.Unlock()
 
+	now := time.Now()
 	for _, delURL := range delURLs {
+		if err = ctx.Err(); err != nil {
+			return deleted, err
+		}
 		for k, curURL := range urls.originalURLs {
 			if (delURL == curURL.shortURL) && (userID == curURL.userID) {
-				urls.originalURLs[k].deletedFlag = true
+				if !curURL.deletedFlag {
+					urls.originalURLs[k].deletedFlag = true
+					urls.originalURLs[k].deletedAt = now
+				}
+				deleted = append(deleted, delURL)
 				break
 			}
 		}
 	}
-	return nil
+	return deleted, nil
+}
+
+// RestoreUserURLs clears the deletion flag of the user URLs deleted after deletedAfter.
+func (urls *MemURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	urls.Lock()
+	defer urls.Unlock()
+
+	for _, shortURL := range shortURLs {
+		if err = ctx.Err(); err != nil {
+			return restored, err
+		}
+		for k, curURL := range urls.originalURLs {
+			if (shortURL == curURL.shortURL) && (userID == curURL.userID) {
+				if curURL.deletedFlag && curURL.deletedAt.After(deletedAfter) {
+					urls.originalURLs[k].deletedFlag = false
+					urls.originalURLs[k].deletedAt = time.Time{}
+					restored = append(restored, shortURL)
+				}
+				break
+			}
+		}
+	}
+	return restored, nil
+}
+
+// PurgeDeletedURLs permanently removes URLs deleted before deletedBefore.
+func (urls *MemURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	urls.Lock()
+	defer urls.Unlock()
+
+	urls.originalURLs = slices.DeleteFunc(urls.originalURLs, func(v MemURL) bool {
+		if v.deletedFlag && v.deletedAt.Before(deletedBefore) {
+			purged++
+			return true
+		}
+		return false
+	})
+	return purged, nil
 }
 
 // GetStats gets statistics - amount URLs and users.
 func (urls *MemURLs) GetStats(ctx context.Context) (stats ServiceStats, err error) {
+	if err = ctx.Err(); err != nil {
+		return ServiceStats{}, err
+	}
+
 	urls.Lock()
 	defer urls.Unlock()
 
@@ -128,6 +368,9 @@ func (urls *MemURLs) GetStats(ctx context.Context) (stats ServiceStats, err erro
 
 // PingStor checking access to storage.
 func (urls *MemURLs) PingStor(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if urls == nil {
 		return errors.New("storage storage does not exist")
 	}
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -91,8 +93,25 @@ func TestAddBatch(t *testing.T) {
 		},
 	}
 	t.Run("add batch url in storage", func(t *testing.T) {
-		err := testRepo.AddBatch(context.Background(), testResponseBatch, testRequestBatch, testUserID)
+		results, err := testRepo.AddBatch(context.Background(), testResponseBatch, testRequestBatch, testUserID)
 		assert.NoError(t, err)
+		assert.Equal(t, []BatchResult{
+			{ShortURL: cfg.URL + "ggg", Status: BatchCreated},
+			{ShortURL: cfg.URL + "rrr", Status: BatchCreated},
+		}, results)
+	})
+	t.Run("add batch with existing urls", func(t *testing.T) {
+		results, err := testRepo.AddBatch(context.Background(),
+			[]ResponseBatch{{CorrelationID: "ind3", ShortURL: "aaa"}, {CorrelationID: "ind4", ShortURL: "bbb"}, {CorrelationID: "ind5", ShortURL: "ccc"}},
+			[]RequestBatch{{CorrelationID: "ind3", OriginalURL: "https://pract.ru/url1"}, {CorrelationID: "ind4", OriginalURL: "https://pract.ru/url3"}, {CorrelationID: "ind5", OriginalURL: "https://pract.ru/url3"}},
+			testUserID)
+		assert.NoError(t, err)
+		assert.Equal(t, []BatchResult{
+			{ShortURL: cfg.URL + "ggg", Status: BatchExists},
+			{ShortURL: "bbb", Status: BatchCreated},
+			{ShortURL: "bbb", Status: BatchExists},
+		}, results)
+		assert.Len(t, testRepo.originalURLs, 3)
 	})
 }
 
@@ -152,8 +171,9 @@ func TestDeleteUserURLs(t *testing.T) {
 
 	t.Run("mark deletet", func(t *testing.T) {
 		del := "EwH"
-		err := testRepo.DeleteUserURLs(context.Background(), []string{del}, testUserID)
+		deleted, err := testRepo.DeleteUserURLs(context.Background(), []string{del}, testUserID)
 		assert.NoError(t, err)
+		assert.Equal(t, []string{del}, deleted)
 		for _, u := range testRepo.originalURLs {
 			if u.shortURL == del {
 				assert.Equal(t, true, u.deletedFlag)
@@ -164,6 +184,84 @@ func TestDeleteUserURLs(t *testing.T) {
 	})
 }
 
+func TestIterateUserURLs(t *testing.T) {
+	testRepo := NewMapURLs()
+	for i := 0; i < iteratePageSize+5; i++ {
+		testRepo.originalURLs = append(testRepo.originalURLs, MemURL{
+			userID:    testUserID,
+			shortURL:  "EwH" + strconv.Itoa(i),
+			originURL: "https://practicum.yandex.ru/",
+		})
+		testRepo.originalURLs = append(testRepo.originalURLs, MemURL{
+			userID:    88,
+			shortURL:  "Ert" + strconv.Itoa(i),
+			originURL: "https://mail.ru/",
+		})
+	}
+
+	t.Run("iterate over pages", func(t *testing.T) {
+		cnt := 0
+		err := testRepo.IterateUserURLs(context.Background(), testUserID, func(url URLInfo) error {
+			assert.Equal(t, testUserID, url.UserID)
+			cnt++
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, iteratePageSize+5, cnt)
+	})
+}
+
+func TestAddClick(t *testing.T) {
+	testRepo := NewMapURLs()
+	testRepo.originalURLs = append(testRepo.originalURLs, MemURL{
+		userID:    testUserID,
+		shortURL:  "EwH",
+		originURL: "https://practicum.yandex.ru/",
+	})
+
+	t.Run("add click", func(t *testing.T) {
+		assert.NoError(t, testRepo.AddClick(context.Background(), "EwH"))
+		assert.NoError(t, testRepo.AddClick(context.Background(), "EwH"))
+		assert.Equal(t, int64(2), testRepo.originalURLs[0].clicks)
+	})
+}
+
+func TestRestoreUserURLs(t *testing.T) {
+	now := time.Now()
+	testRepo := NewMapURLs()
+	testRepo.originalURLs = []MemURL{
+		{userID: testUserID, shortURL: "EwH", originURL: "https://practicum.yandex.ru/", deletedFlag: true, deletedAt: now},
+		{userID: testUserID, shortURL: "Ert", originURL: "https://mail.ru/", deletedFlag: true, deletedAt: now.Add(-2 * time.Hour)},
+		{userID: 88, shortURL: "Euu", originURL: "https://ya.ru/", deletedFlag: true, deletedAt: now},
+	}
+
+	t.Run("restore within period", func(t *testing.T) {
+		restored, err := testRepo.RestoreUserURLs(context.Background(), []string{"EwH", "Ert", "Euu"}, testUserID, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"EwH"}, restored)
+		assert.False(t, testRepo.originalURLs[0].deletedFlag)
+		assert.True(t, testRepo.originalURLs[1].deletedFlag)
+		assert.True(t, testRepo.originalURLs[2].deletedFlag)
+	})
+}
+
+func TestPurgeDeletedURLs(t *testing.T) {
+	now := time.Now()
+	testRepo := NewMapURLs()
+	testRepo.originalURLs = []MemURL{
+		{userID: testUserID, shortURL: "EwH", originURL: "https://practicum.yandex.ru/", deletedFlag: true, deletedAt: now},
+		{userID: testUserID, shortURL: "Ert", originURL: "https://mail.ru/", deletedFlag: true, deletedAt: now.Add(-2 * time.Hour)},
+		{userID: testUserID, shortURL: "Euu", originURL: "https://ya.ru/"},
+	}
+
+	t.Run("purge old", func(t *testing.T) {
+		purged, err := testRepo.PurgeDeletedURLs(context.Background(), now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		assert.Equal(t, 2, len(testRepo.originalURLs))
+	})
+}
+
 func TestPingStor(t *testing.T) {
 	testRepo := NewMapURLs()
 	t.Run("ping", func(t *testing.T) {
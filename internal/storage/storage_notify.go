@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+)
+
+// Operations of the change events.
+const (
+	// ChangeAdd - the short URLs were added.
+	ChangeAdd = "add"
+	// ChangeDelete - the short URLs were marked as deleted.
+	ChangeDelete = "delete"
+	// ChangeRestore - the deletion flag of the short URLs was cleared.
+	ChangeRestore = "restore"
+	// ChangePurge - deleted URLs were removed, the event has no short URLs.
+	ChangePurge = "purge"
+	// ChangeReset - events could have been lost while reconnecting, all cached URLs are outdated.
+	ChangeReset = "reset"
+)
+
+// notifyPayloadSize - the maximum size of one notification payload,
+// PostgreSQL limits it to 8000 bytes.
+const notifyPayloadSize = 7900
+
+// Reconnection delays of the listener.
+const (
+	listenMinBackoff = time.Second
+	listenMaxBackoff = 30 * time.Second
+)
+
+// ChangeEvent describes a change of the URLs made by any instance of the service.
+type ChangeEvent struct {
+	// Op - ChangeAdd, ChangeDelete, ChangeRestore, ChangePurge or ChangeReset.
+	Op string `json:"op"`
+	// ShortURLs - the changed short URLs.
+	ShortURLs []string `json:"short_urls,omitempty"`
+}
+
+// changePayloads encodes the event as notification payloads,
+// the short URLs are split between several payloads if they do not fit in one.
+func changePayloads(ev ChangeEvent) ([]string, error) {
+	var payloads []string
+	part := ChangeEvent{Op: ev.Op}
+	size := len(`{"op":"","short_urls":[]}`) + len(ev.Op)
+	for _, shortURL := range ev.ShortURLs {
+		// The short URL is quoted and separated by a comma.
+		if len(part.ShortURLs) > 0 && size+len(shortURL)+3 > notifyPayloadSize {
+			data, err := json.Marshal(part)
+			if err != nil {
+				return nil, err
+			}
+			payloads = append(payloads, string(data))
+			part.ShortURLs = nil
+			size = len(`{"op":"","short_urls":[]}`) + len(ev.Op)
+		}
+		part.ShortURLs = append(part.ShortURLs, shortURL)
+		size += len(shortURL) + 3
+	}
+	data, err := json.Marshal(part)
+	if err != nil {
+		return nil, err
+	}
+	return append(payloads, string(data)), nil
+}
+
+// publish sends the change event to all instances listening to the notification channel.
+// The change is already saved, so the event is sent even if the request is cancelled,
+// and an error is only logged: other instances keep the outdated cache until it expires.
+func (db *DBURLs) publish(ctx context.Context, op string, shortURLs []string) {
+	if db.notifyChannel == "" || (op != ChangePurge && len(shortURLs) == 0) {
+		return
+	}
+	ctx, cancel := withTimeout(context.WithoutCancel(ctx), db.timeouts.Write)
+	defer cancel()
+	payloads, err := changePayloads(ChangeEvent{Op: op, ShortURLs: shortURLs})
+	if err == nil {
+		for _, payload := range payloads {
+			if _, err = db.pool.Exec(ctx, "SELECT pg_notify($1, $2)", db.notifyChannel, payload); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		logger.FromContext(ctx).Infow("publish URL changes", "op", op, "error", err)
+	}
+}
+
+// listenConn contains the pgx.Conn methods used by the listener.
+type listenConn interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// Listener receives the change events published by all instances and passes them to the subscribers.
+// It keeps a separate database connection and reconnects when it is lost.
+type Listener struct {
+	channel     string
+	connect     func(ctx context.Context) (listenConn, error)
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	subscribers []func(ev ChangeEvent)
+	sync.RWMutex
+}
+
+// NewListener creates a listener of the notification channel of the database.
+func NewListener(dsn string, channel string) *Listener {
+	return &Listener{
+		channel: channel,
+		connect: func(ctx context.Context) (listenConn, error) {
+			return pgx.Connect(ctx, dsn)
+		},
+		minBackoff: listenMinBackoff,
+		maxBackoff: listenMaxBackoff,
+	}
+}
+
+// Subscribe adds the function called with each change event.
+// Events are passed one by one, fn must not block for long.
+func (l *Listener) Subscribe(fn func(ev ChangeEvent)) {
+	l.Lock()
+	defer l.Unlock()
+	l.subscribers = append(l.subscribers, fn)
+}
+
+// dispatch passes the event to the subscribers.
+func (l *Listener) dispatch(ev ChangeEvent) {
+	l.RLock()
+	defer l.RUnlock()
+	for _, fn := range l.subscribers {
+		fn(ev)
+	}
+}
+
+// Run listens to the channel until ctx is done.
+// After a reconnection subscribers get ChangeReset, as events could have been lost.
+func (l *Listener) Run(ctx context.Context) {
+	backoff := l.minBackoff
+	connected := false
+	for {
+		err := l.listen(ctx, func() {
+			if connected {
+				l.dispatch(ChangeEvent{Op: ChangeReset})
+			}
+			connected = true
+			backoff = l.minBackoff
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		logger.FromContext(ctx).Infow("listen URL changes", "channel", l.channel, "error", err, "retry in", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, l.maxBackoff)
+	}
+}
+
+// listen connects, subscribes to the channel, calls ready and dispatches the events until an error.
+func (l *Listener) listen(ctx context.Context, ready func()) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return err
+	}
+	ready()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var ev ChangeEvent
+		if err = json.Unmarshal([]byte(n.Payload), &ev); err != nil {
+			logger.FromContext(ctx).Infow("decode URL change", "payload", n.Payload, "error", err)
+			continue
+		}
+		l.dispatch(ev)
+	}
+}
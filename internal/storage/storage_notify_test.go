@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+)
+
+func TestChangePayloads(t *testing.T) {
+	tests := []struct {
+		name      string
+		count     int
+		wantParts int
+	}{
+		{name: "purge without urls", count: 0, wantParts: 1},
+		{name: "one payload", count: 10, wantParts: 1},
+		{name: "split payloads", count: 1000, wantParts: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ev := ChangeEvent{Op: ChangeDelete}
+			for i := 0; i < test.count; i++ {
+				ev.ShortURLs = append(ev.ShortURLs, fmt.Sprintf("short%04d", i))
+			}
+
+			payloads, err := changePayloads(ev)
+			require.NoError(t, err)
+			assert.Len(t, payloads, test.wantParts)
+			var got []string
+			for _, v := range payloads {
+				assert.LessOrEqual(t, len(v), notifyPayloadSize)
+				assert.True(t, strings.HasPrefix(v, `{"op":"delete"`))
+				var part ChangeEvent
+				require.NoError(t, json.Unmarshal([]byte(v), &part))
+				got = append(got, part.ShortURLs...)
+			}
+			assert.Equal(t, ev.ShortURLs, got)
+		})
+	}
+}
+
+func TestDBPublish(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	testDB := DBURLs{pool: mock, notifyChannel: "urls"}
+	ctx := context.Background()
+
+	mock.ExpectExec("INSERT INTO urls").WithArgs(testUserID, "EwH", "https://pract.ru/url1").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("SELECT pg_notify").WithArgs("urls", `{"op":"add","short_urls":["EwH"]}`).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	_, err = testDB.AddURL(ctx, "EwH", "https://pract.ru/url1", testUserID)
+	assert.NoError(t, err)
+
+	mock.ExpectExec("DELETE FROM urls").WithArgs(pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 2))
+	mock.ExpectExec("SELECT pg_notify").WithArgs("urls", `{"op":"purge"}`).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	_, err = testDB.PurgeDeletedURLs(ctx, time.Now())
+	assert.NoError(t, err)
+
+	mock.ExpectExec("DELETE FROM urls").WithArgs(pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	_, err = testDB.PurgeDeletedURLs(ctx, time.Now())
+	assert.NoError(t, err, "nothing is published without changes")
+
+	mock.ExpectExec("SELECT pg_notify").WithArgs("urls", `{"op":"delete","short_urls":["EwH"]}`).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1)).
+		WillDelayFor(10 * time.Millisecond)
+	core, logs := observer.New(zapcore.InfoLevel)
+	cancelled, cancel := context.WithCancel(logger.NewContext(ctx, zap.New(core).Sugar()))
+	cancel()
+	testDB.publish(cancelled, ChangeDelete, []string{"EwH"})
+	assert.Zero(t, logs.Len(), "the saved change is published after the request is cancelled")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// testListenConn delivers the payloads and fails when they run out.
+type testListenConn struct {
+	payloads chan string
+	listened []string
+}
+
+func (c *testListenConn) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	c.listened = append(c.listened, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *testListenConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case payload, ok := <-c.payloads:
+		if !ok {
+			return nil, errors.New("connection lost")
+		}
+		return &pgconn.Notification{Payload: payload}, nil
+	}
+}
+
+func (c *testListenConn) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestListenerRun(t *testing.T) {
+	first := &testListenConn{payloads: make(chan string, 2)}
+	first.payloads <- `{"op":"add","short_urls":["EwH"]}`
+	first.payloads <- `not json`
+	close(first.payloads)
+	second := &testListenConn{payloads: make(chan string, 1)}
+	second.payloads <- `{"op":"delete","short_urls":["Ert"]}`
+
+	var connects int
+	l := NewListener("", "shortener urls")
+	l.minBackoff = time.Millisecond
+	l.connect = func(ctx context.Context) (listenConn, error) {
+		connects++
+		switch connects {
+		case 1:
+			return nil, errors.New("database is starting")
+		case 2:
+			return first, nil
+		}
+		return second, nil
+	}
+
+	var mu sync.Mutex
+	var events []ChangeEvent
+	l.Subscribe(func(ev ChangeEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, ev)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 3
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, []ChangeEvent{
+		{Op: ChangeAdd, ShortURLs: []string{"EwH"}},
+		{Op: ChangeReset},
+		{Op: ChangeDelete, ShortURLs: []string{"Ert"}},
+	}, events)
+	assert.Equal(t, []string{`LISTEN "shortener urls"`}, first.listened)
+}
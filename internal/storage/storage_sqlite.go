@@ -0,0 +1,458 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+)
+
+// sqliteScheme - the DBDSN prefix selecting the SQLite storage, e.g. sqlite:///var/lib/shortener/urls.db.
+const sqliteScheme = "sqlite://"
+
+// sqliteBusyTimeout - how long a connection waits for the write lock, in milliseconds.
+const sqliteBusyTimeout = 5000
+
+// IsSQLiteDSN reports whether the DSN selects the SQLite storage.
+func IsSQLiteDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, sqliteScheme)
+}
+
+// OpenSQLite opens the SQLite database file from the sqlite:// DSN in WAL mode.
+func OpenSQLite(dsn string) (*sql.DB, error) {
+	fileName := strings.TrimPrefix(dsn, sqliteScheme)
+	if fileName == "" {
+		return nil, errors.New("empty SQLite file name")
+	}
+	return sql.Open("sqlite3", "file:"+fileName+
+		"?_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate&_busy_timeout="+
+		strconv.Itoa(sqliteBusyTimeout))
+}
+
+// SQLiteURLs stores URLs in a SQLite database.
+type SQLiteURLs struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+// NewSQLiteURLs opens the SQLite database and migrates the schema to the latest version.
+func NewSQLiteURLs(flags config.Flags) (*SQLiteURLs, error) {
+	db, err := OpenSQLite(flags.DBDSN)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	mig, err := NewSQLiteMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = mig.Up(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteURLs{db: db, timeouts: NewTimeouts(flags)}, nil
+}
+
+// GetURL gets the original URL matching the short URL.
+func (s *SQLiteURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx,
+		"SELECT original_url, deleted_flag FROM urls WHERE short_url = ?", shortURL)
+	if err := row.Scan(&originURL, &isDel); err != nil {
+		return "", false, false
+	}
+	return originURL, isDel, true
+}
+
+// AddURL adds a new short url.
+func (s *SQLiteURLs) AddURL(ctx context.Context, shortURL string, originURL string, userID int) (findURL string, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO urls (user_id, short_url, original_url, created_at) VALUES (?, ?, ?, ?)",
+		userID, shortURL, originURL, time.Now().UTC())
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique) {
+			// Either the user has already added the URL or the short URL is used by another URL.
+			row := s.db.QueryRowContext(ctx,
+				"SELECT short_url FROM urls WHERE original_url = ? AND user_id = ?", originURL, userID)
+			errScan := row.Scan(&findURL)
+			if errors.Is(errScan, sql.ErrNoRows) {
+				return "", fmt.Errorf("%w: %w", ErrShortURLExists, err)
+			}
+			if errScan != nil {
+				return "", err
+			}
+			return findURL, fmt.Errorf("%w: %w", ErrConflict, err)
+		}
+		return "", err
+	}
+	return "", nil
+}
+
+// AddBatch adds a batch of new short URLs in one transaction with a prepared statement.
+// URLs already added by the user are skipped and their existing short URLs are returned.
+// Returns ErrShortURLExists and adds nothing if one of the short URLs is already used.
+func (s *SQLiteURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) (results []BatchResult, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.PrepareContext(ctx,
+		"INSERT INTO urls (user_id, short_url, original_url, created_at) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING")
+	if err != nil {
+		return nil, err
+	}
+	defer insert.Close()
+	find, err := tx.PrepareContext(ctx,
+		"SELECT short_url FROM urls WHERE user_id = ? AND original_url = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer find.Close()
+
+	now := time.Now().UTC()
+	results = make([]BatchResult, len(shortURLBatch))
+	for k, v := range shortURLBatch {
+		originURL := originURLBatch[k].OriginalURL
+		result, err := insert.ExecContext(ctx, userID, v.ShortURL, originURL, now)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if rows == 1 {
+			results[k] = BatchResult{ShortURL: v.ShortURL, Status: BatchCreated}
+			continue
+		}
+
+		var shortURL string
+		err = find.QueryRowContext(ctx, userID, originURL).Scan(&shortURL)
+		if errors.Is(err, sql.ErrNoRows) {
+			// The URL is neither added nor found, its short URL is used by another URL.
+			return nil, fmt.Errorf("%w: %s", ErrShortURLExists, v.ShortURL)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results[k] = BatchResult{ShortURL: shortURL, Status: BatchExists}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetAllUserURLs gets all user's short url.
+func (s *SQLiteURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []UserURL, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT short_url, original_url FROM urls WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u UserURL
+		if err = rows.Scan(&u.ShortURL, &u.OriginalURL); err != nil {
+			return nil, err
+		}
+		userURLs = append(userURLs, UserURL{
+			ShortURL:    baseURL + u.ShortURL,
+			OriginalURL: u.OriginalURL,
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return userURLs, nil
+}
+
+// IterateUserURLs calls fn for each user's URL while reading rows from the database.
+func (s *SQLiteURLs) IterateUserURLs(ctx context.Context, userID int, fn func(url URLInfo) error) (err error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT short_url, original_url, deleted_flag, created_at, clicks FROM urls WHERE user_id = ? ORDER BY created_at", userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u := URLInfo{UserID: userID}
+		if err = rows.Scan(&u.ShortURL, &u.OriginalURL, &u.DeletedFlag, &u.CreatedAt, &u.Clicks); err != nil {
+			return err
+		}
+		if err = fn(u); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// IterateURLs calls fn for each URL ordered by the short URL, starting after the short URL after.
+// URLs are read in pages, so a query is not open while fn is running.
+func (s *SQLiteURLs) IterateURLs(ctx context.Context, after string, fn func(url URLRecord) error) (err error) {
+	for {
+		page, err := s.urlsPage(ctx, after)
+		if err != nil {
+			return err
+		}
+		for _, v := range page {
+			if err = fn(v); err != nil {
+				return err
+			}
+		}
+		if len(page) < iteratePageSize {
+			return nil
+		}
+		after = page[len(page)-1].ShortURL
+	}
+}
+
+// urlsPage reads the page of URLs after the short URL after.
+func (s *SQLiteURLs) urlsPage(ctx context.Context, after string) (page []URLRecord, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT short_url, original_url, user_id, deleted_flag, created_at, deleted_at, clicks FROM urls WHERE short_url > ? ORDER BY short_url LIMIT ?",
+		after, iteratePageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u URLRecord
+		var deletedAt sql.NullTime
+		if err = rows.Scan(&u.ShortURL, &u.OriginalURL, &u.UserID, &u.DeletedFlag, &u.CreatedAt, &deletedAt, &u.Clicks); err != nil {
+			return nil, err
+		}
+		if deletedAt.Valid {
+			u.DeletedAt = &deletedAt.Time
+		}
+		page = append(page, u)
+	}
+	return page, rows.Err()
+}
+
+// LoadURLs adds the URLs with all their data in one transaction, existing ones are skipped.
+func (s *SQLiteURLs) LoadURLs(ctx context.Context, urls []URLRecord) (loaded int, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.PrepareContext(ctx,
+		"INSERT INTO urls (user_id, short_url, original_url, deleted_flag, created_at, deleted_at, clicks) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING")
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	now := time.Now().UTC()
+	for _, v := range urls {
+		var deletedAt sql.NullTime
+		if v.DeletedAt != nil {
+			deletedAt = sql.NullTime{Time: v.DeletedAt.UTC(), Valid: true}
+		} else if v.DeletedFlag {
+			deletedAt = sql.NullTime{Time: now, Valid: true}
+		}
+		result, err := insert.ExecContext(ctx, v.UserID, v.ShortURL, v.OriginalURL, v.DeletedFlag, v.CreatedAt.UTC(), deletedAt, v.Clicks)
+		if err != nil {
+			return 0, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		loaded += int(rows)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return loaded, nil
+}
+
+// AddClick increases the number of redirects by the short URL.
+func (s *SQLiteURLs) AddClick(ctx context.Context, shortURL string) (err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	_, err = s.db.ExecContext(ctx,
+		"UPDATE urls SET clicks = clicks + 1 WHERE short_url = ?", shortURL)
+	return err
+}
+
+// AddClicks increases the numbers of redirects by the short URLs in one transaction.
+func (s *SQLiteURLs) AddClicks(ctx context.Context, clicks map[string]int64) (err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	update, err := tx.PrepareContext(ctx, "UPDATE urls SET clicks = clicks + ? WHERE short_url = ?")
+	if err != nil {
+		return err
+	}
+	defer update.Close()
+	for shortURL, n := range clicks {
+		if _, err = update.ExecContext(ctx, n, shortURL); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteUserURLs sets the deletion flag to the user URLs sent in the request.
+func (s *SQLiteURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Delete)
+	defer cancel()
+
+	now := time.Now().UTC()
+	err = s.eachURL(ctx,
+		"UPDATE urls SET deleted_flag = true, deleted_at = COALESCE(deleted_at, ?) WHERE user_id = ? AND short_url = ?",
+		delURLs, func(shortURL string) []any {
+			return []any{now, userID, shortURL}
+		}, &deleted)
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// RestoreUserURLs clears the deletion flag of the user URLs deleted after deletedAfter.
+func (s *SQLiteURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	err = s.eachURL(ctx,
+		"UPDATE urls SET deleted_flag = false, deleted_at = NULL WHERE user_id = ? AND short_url = ? AND deleted_flag AND deleted_at > ?",
+		shortURLs, func(shortURL string) []any {
+			return []any{userID, shortURL, deletedAfter.UTC()}
+		}, &restored)
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// eachURL runs the prepared update for each short URL in one transaction
+// and appends the short URLs of the changed rows to changed.
+func (s *SQLiteURLs) eachURL(ctx context.Context, query string, shortURLs []string, args func(shortURL string) []any, changed *[]string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, shortURL := range shortURLs {
+		result, err := stmt.ExecContext(ctx, args(shortURL)...)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows > 0 {
+			*changed = append(*changed, shortURL)
+		}
+	}
+	return tx.Commit()
+}
+
+// PurgeDeletedURLs permanently removes URLs deleted before deletedBefore.
+func (s *SQLiteURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Delete)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM urls WHERE deleted_flag AND deleted_at < ?", deletedBefore.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetStats gets statistics - amount URLs and users.
+func (s *SQLiteURLs) GetStats(ctx context.Context) (stats ServiceStats, err error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(short_url) AS urls, COUNT(DISTINCT user_id) AS users FROM urls")
+	if err = row.Scan(&stats.URLs, &stats.Users); err != nil {
+		return ServiceStats{}, err
+	}
+	return stats, nil
+}
+
+// PingStor checking access to storage.
+func (s *SQLiteURLs) PingStor(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	return s.db.PingContext(ctx)
+}
+
+// PendingMigrations returns the number of schema migrations that are not applied yet.
+func (s *SQLiteURLs) PendingMigrations(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	var version int
+	row := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	if err := row.Scan(&version); err != nil {
+		return 0, err
+	}
+	return pendingMigrations("migrations/sqlite", version)
+}
+
+// Close closes the database.
+func (s *SQLiteURLs) Close() error {
+	return s.db.Close()
+}
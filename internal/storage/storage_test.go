@@ -1,9 +1,19 @@
 package storage
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
 
 	"github.com/Julia-ivv/shortener-url.git/internal/config"
 )
@@ -11,19 +21,334 @@ import (
 var cfg config.Flags
 
 func Init() {
-	cfg = *config.NewConfig()
+	cfg = *config.NewConfig(zap.NewNop().Sugar())
+}
+
+// TestMain runs the tests with a copy of for_tests.json,
+// the file storage rewrites its file when it is opened and closed.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "storage")
+	if err != nil {
+		panic(err)
+	}
+	data, err := os.ReadFile(testFileName)
+	if err != nil {
+		panic(err)
+	}
+	testFileName = filepath.Join(dir, testFileName)
+	if err = os.WriteFile(testFileName, data, 0666); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
 }
 
 func TestNewURLs(t *testing.T) {
 	t.Run("create map repo", func(t *testing.T) {
-		repo, err := NewURLs(cfg)
-		assert.NoError(t, err)
-		assert.NotEmpty(t, repo)
+		repo, err := NewURLs(cfg, zaptest.NewLogger(t).Sugar())
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, repo)
+			assert.NoError(t, repo.Close())
+		}
 	})
 	t.Run("create file repo", func(t *testing.T) {
-		cfg.FileName = "for_tests.json"
-		repo, err := NewURLs(cfg)
-		assert.NoError(t, err)
-		assert.NotEmpty(t, repo)
+		cfg.FileName = testFileName
+		repo, err := NewURLs(cfg, zaptest.NewLogger(t).Sugar())
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, repo)
+			assert.NoError(t, repo.Close())
+		}
+	})
+}
+
+func TestWithTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		hasDeadline bool
+	}{
+		{name: "with timeout", timeout: time.Second, hasDeadline: true},
+		{name: "without timeout", timeout: 0, hasDeadline: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := withTimeout(context.Background(), test.timeout)
+			defer cancel()
+			_, ok := ctx.Deadline()
+			assert.Equal(t, test.hasDeadline, ok)
+		})
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repos := map[string]Repositories{"map": NewMapURLs()}
+	fileRepo, err := newTestFileURLs(t)
+	if assert.NoError(t, err) {
+		repos["file"] = fileRepo
+	}
+	kvRepo, err := NewKVURLs(filepath.Join(t.TempDir(), "urls.db"))
+	if assert.NoError(t, err) {
+		defer kvRepo.Close()
+		repos["kv"] = kvRepo
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			_, err := repo.AddURL(ctx, "EwH", "https://practicum.yandex.ru/", testUserID)
+			assert.ErrorIs(t, err, context.Canceled)
+			_, _, ok := repo.GetURL(ctx, "EwH")
+			assert.False(t, ok)
+			_, err = repo.DeleteUserURLs(ctx, []string{"EwH"}, testUserID)
+			assert.ErrorIs(t, err, context.Canceled)
+			_, err = repo.PurgeDeletedURLs(ctx, time.Now())
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.ErrorIs(t, repo.PingStor(ctx), context.Canceled)
+		})
+	}
+}
+
+// testStorages creates empty instances of the storages that run in the process,
+// each test of the common behavior runs against all of them.
+var testStorages = map[string]func(t *testing.T) Repositories{
+	"mem": func(t *testing.T) Repositories { return NewMapURLs() },
+	"file": func(t *testing.T) Repositories {
+		f, err := NewFileURLs(filepath.Join(t.TempDir(), "urls.json"), "", zaptest.NewLogger(t).Sugar())
+		require.NoError(t, err)
+		t.Cleanup(func() { f.Close() })
+		return f
+	},
+	"kv": func(t *testing.T) Repositories {
+		kv, _ := newTestKV(t)
+		t.Cleanup(func() { kv.Close() })
+		return kv
+	},
+	"sqlite": func(t *testing.T) Repositories { return newTestSQLite(t) },
+}
+
+func TestAddURLConflict(t *testing.T) {
+	for name, newStorage := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			repo := newStorage(t)
+			ctx := context.Background()
+
+			findURL, err := repo.AddURL(ctx, "aaa", "https://pract.ru/url1", 1)
+			require.NoError(t, err)
+			assert.Empty(t, findURL)
+
+			findURL, err = repo.AddURL(ctx, "bbb", "https://pract.ru/url1", 1)
+			assert.ErrorIs(t, err, ErrConflict)
+			assert.Equal(t, "aaa", findURL)
+			_, _, ok := repo.GetURL(ctx, "bbb")
+			assert.False(t, ok, "the repeated URL is not added")
+
+			_, err = repo.AddURL(ctx, "ccc", "https://pract.ru/url1", 2)
+			assert.NoError(t, err, "another user adds the same URL")
+
+			results, err := repo.AddBatch(ctx,
+				[]ResponseBatch{{CorrelationID: "1", ShortURL: "ddd"}, {CorrelationID: "2", ShortURL: "eee"}},
+				[]RequestBatch{{CorrelationID: "1", OriginalURL: "https://pract.ru/url1"}, {CorrelationID: "2", OriginalURL: "https://pract.ru/url2"}}, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []BatchResult{{ShortURL: "aaa", Status: BatchExists}, {ShortURL: "eee", Status: BatchCreated}}, results)
+
+			findURL, err = repo.AddURL(ctx, "fff", "https://pract.ru/url2", 1)
+			assert.ErrorIs(t, err, ErrConflict)
+			assert.Equal(t, "eee", findURL)
+		})
+	}
+}
+
+func TestAddURLShortURLExists(t *testing.T) {
+	for name, newStorage := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			repo := newStorage(t)
+			ctx := context.Background()
+
+			_, err := repo.AddURL(ctx, "aaa", "https://pract.ru/url1", 1)
+			require.NoError(t, err)
+
+			_, err = repo.AddURL(ctx, "aaa", "https://pract.ru/url2", 2)
+			assert.ErrorIs(t, err, ErrShortURLExists)
+			originURL, _, ok := repo.GetURL(ctx, "aaa")
+			assert.True(t, ok)
+			assert.Equal(t, "https://pract.ru/url1", originURL, "the used short URL is not replaced")
+
+			_, err = repo.AddBatch(ctx,
+				[]ResponseBatch{{CorrelationID: "1", ShortURL: "bbb"}, {CorrelationID: "2", ShortURL: "aaa"}},
+				[]RequestBatch{{CorrelationID: "1", OriginalURL: "https://pract.ru/url3"}, {CorrelationID: "2", OriginalURL: "https://pract.ru/url4"}}, 1)
+			assert.ErrorIs(t, err, ErrShortURLExists)
+			_, _, ok = repo.GetURL(ctx, "bbb")
+			assert.False(t, ok, "the batch is not added")
+
+			_, err = repo.AddBatch(ctx,
+				[]ResponseBatch{{CorrelationID: "1", ShortURL: "ccc"}, {CorrelationID: "2", ShortURL: "ccc"}},
+				[]RequestBatch{{CorrelationID: "1", OriginalURL: "https://pract.ru/url3"}, {CorrelationID: "2", OriginalURL: "https://pract.ru/url4"}}, 1)
+			assert.ErrorIs(t, err, ErrShortURLExists, "the short URL is repeated in the batch")
+			_, _, ok = repo.GetURL(ctx, "ccc")
+			assert.False(t, ok, "the batch is not added")
+		})
+	}
+}
+
+func TestIterateUserURLsWithPurge(t *testing.T) {
+	const total = 2*iteratePageSize + 10
+	for name, newStorage := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			repo := newStorage(t)
+			ctx := context.Background()
+
+			var deleted []string
+			for k := 0; k < total; k++ {
+				shortURL := fmt.Sprintf("s%04d", k)
+				_, err := repo.AddURL(ctx, shortURL, "https://pract.ru/url"+strconv.Itoa(k), testUserID)
+				require.NoError(t, err)
+				if k < iteratePageSize/2 {
+					deleted = append(deleted, shortURL)
+				}
+			}
+			_, err := repo.AddURL(ctx, "other", "https://pract.ru/url1", testUserID+1)
+			require.NoError(t, err)
+			_, err = repo.DeleteUserURLs(ctx, deleted, testUserID)
+			require.NoError(t, err)
+
+			var visited []string
+			err = repo.IterateUserURLs(ctx, testUserID, func(url URLInfo) error {
+				visited = append(visited, url.ShortURL)
+				if len(visited) == iteratePageSize {
+					// The deleted URLs of the first page are removed while iterating.
+					_, err := repo.PurgeDeletedURLs(ctx, time.Now().Add(time.Minute))
+					return err
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			require.Len(t, visited, total, "no URL is skipped or repeated")
+			assert.True(t, slices.IsSorted(visited))
+		})
+	}
+}
+
+func TestBulkStorage(t *testing.T) {
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []URLRecord{
+		{URLInfo: URLInfo{ShortURL: "ccc", OriginalURL: "https://pract.ru/url3", UserID: 2, CreatedAt: createdAt, Clicks: 7}},
+		{URLInfo: URLInfo{ShortURL: "aaa", OriginalURL: "https://pract.ru/url1", UserID: 1, CreatedAt: createdAt}},
+		{URLInfo: URLInfo{ShortURL: "bbb", OriginalURL: "https://pract.ru/url2", UserID: 1, CreatedAt: createdAt, DeletedFlag: true}, DeletedAt: &deletedAt},
+		{URLInfo: URLInfo{ShortURL: "aaa", OriginalURL: "https://pract.ru/other", UserID: 3, CreatedAt: createdAt}},
+		{URLInfo: URLInfo{ShortURL: "ddd", OriginalURL: "https://pract.ru/url1", UserID: 1, CreatedAt: createdAt}},
+	}
+
+	for name, newStorage := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			stor := newStorage(t).(BulkStorage)
+			ctx := context.Background()
+
+			loaded, err := stor.LoadURLs(ctx, records)
+			require.NoError(t, err)
+			assert.Equal(t, 3, loaded, "repeated short and original URLs are skipped")
+			loaded, err = stor.LoadURLs(ctx, records)
+			require.NoError(t, err)
+			assert.Equal(t, 0, loaded)
+
+			var got []URLRecord
+			err = stor.IterateURLs(ctx, "", func(url URLRecord) error {
+				got = append(got, url)
+				return nil
+			})
+			require.NoError(t, err)
+			if assert.Len(t, got, 3) {
+				assert.Equal(t, "aaa", got[0].ShortURL)
+				assert.Equal(t, 1, got[0].UserID)
+				assert.Nil(t, got[0].DeletedAt)
+				assert.True(t, got[1].DeletedFlag)
+				if assert.NotNil(t, got[1].DeletedAt) {
+					assert.True(t, deletedAt.Equal(*got[1].DeletedAt))
+				}
+				assert.Equal(t, int64(7), got[2].Clicks)
+				assert.True(t, createdAt.Equal(got[2].CreatedAt))
+			}
+
+			var after []string
+			err = stor.IterateURLs(ctx, "aaa", func(url URLRecord) error {
+				after = append(after, url.ShortURL)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"bbb", "ccc"}, after)
+		})
+	}
+}
+
+// takenShortURLs fails the first adds with ErrShortURLExists.
+type takenShortURLs struct {
+	Repositories
+	taken int
+	calls int
+}
+
+func (s *takenShortURLs) AddURL(ctx context.Context, shortURL string, originURL string, userID int) (string, error) {
+	s.calls++
+	if s.calls <= s.taken {
+		return "", ErrShortURLExists
+	}
+	return s.Repositories.AddURL(ctx, shortURL, originURL, userID)
+}
+
+func (s *takenShortURLs) AddBatch(ctx context.Context, shortURLBatch []ResponseBatch, originURLBatch []RequestBatch, userID int) ([]BatchResult, error) {
+	s.calls++
+	if s.calls <= s.taken {
+		return nil, ErrShortURLExists
+	}
+	return s.Repositories.AddBatch(ctx, shortURLBatch, originURLBatch, userID)
+}
+
+func TestAddNewURL(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		taken   int
+		wantErr error
+	}{
+		{name: "added", taken: 0},
+		{name: "short URL is generated again", taken: shortURLAttempts - 1},
+		{name: "all short URLs are used", taken: shortURLAttempts, wantErr: ErrShortURLExists},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &takenShortURLs{Repositories: NewMapURLs(), taken: test.taken}
+			shortURL, err := AddNewURL(ctx, repo, "https://pract.ru/url1", testUserID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else if assert.NoError(t, err) {
+				originURL, _, ok := repo.GetURL(ctx, shortURL)
+				assert.True(t, ok)
+				assert.Equal(t, "https://pract.ru/url1", originURL)
+			}
+
+			repo = &takenShortURLs{Repositories: NewMapURLs(), taken: test.taken}
+			results, err := AddNewBatch(ctx, repo, []RequestBatch{{CorrelationID: "1", OriginalURL: "https://pract.ru/url1"}}, testUserID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else if assert.NoError(t, err) && assert.Len(t, results, 1) {
+				assert.Equal(t, BatchCreated, results[0].Status)
+				_, _, ok := repo.GetURL(ctx, results[0].ShortURL)
+				assert.True(t, ok)
+			}
+		})
+	}
+
+	t.Run("url already added", func(t *testing.T) {
+		repo := NewMapURLs()
+		shortURL, err := AddNewURL(ctx, repo, "https://pract.ru/url1", testUserID)
+		require.NoError(t, err)
+		findURL, err := AddNewURL(ctx, repo, "https://pract.ru/url1", testUserID)
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, shortURL, findURL)
 	})
 }
@@ -0,0 +1,82 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+)
+
+// acmeDirName - the subdirectory of the data directory with the ACME account key and certificates.
+const acmeDirName = "autocert"
+
+// newACMEManager creates the manager obtaining and renewing the certificates of the ACME domains.
+// The account key and the certificates are cached in DataDir, so they survive restarts.
+func newACMEManager(flags config.Flags) (*autocert.Manager, error) {
+	var domains []string
+	for _, domain := range strings.Split(flags.ACMEDomains, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) == 0 {
+		return nil, errors.New("no ACME domains")
+	}
+
+	client := &acme.Client{DirectoryURL: flags.ACMEDirectory}
+	if flags.ACMECAFile != "" {
+		roots, err := loadRoots(flags.ACMECAFile)
+		if err != nil {
+			return nil, err
+		}
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: roots},
+			},
+		}
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(filepath.Join(flags.DataDir, acmeDirName)),
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Client:     client,
+		Email:      flags.ACMEEmail,
+	}, nil
+}
+
+// loadRoots returns the system roots with the certificates of the PEM file added.
+func loadRoots(fileName string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, errors.New("no certificates in " + fileName)
+	}
+	return roots, nil
+}
+
+// getCertificate returns the ACME certificate for the requested domain.
+// The names ACME can't issue certificates for - empty, localhost and IP addresses - get the file certificate.
+func (c *Certificates) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+	if name == "" || name == "localhost" || strings.HasSuffix(name, ".localhost") || net.ParseIP(name) != nil {
+		return c.files.GetCertificate(hello)
+	}
+	return c.acme.GetCertificate(hello)
+}
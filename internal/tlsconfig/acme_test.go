@@ -0,0 +1,77 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/crypto/acme"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+)
+
+func TestACME(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePair(t, certFile, keyFile)
+	caFile := filepath.Join(dir, "ca.pem")
+	caPEM, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0644))
+
+	certs, err := New(config.Flags{
+		TLSCertFile:   certFile,
+		TLSKeyFile:    keyFile,
+		ACMEDomains:   "short.example.com, www.short.example.com",
+		ACMEDirectory: "https://localhost:14000/dir",
+		ACMECAFile:    caFile,
+		DataDir:       dir,
+	}, zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+
+	cfg := certs.ServerConfig()
+	assert.Contains(t, cfg.NextProtos, acme.ALPNProto, "TLS-ALPN-01 challenges are answered")
+
+	fileCert, err := certs.files.GetCertificate(nil)
+	require.NoError(t, err)
+	tests := []struct {
+		name       string
+		serverName string
+		wantFile   bool
+	}{
+		{name: "without SNI", serverName: "", wantFile: true},
+		{name: "localhost", serverName: "localhost", wantFile: true},
+		{name: "ip", serverName: "127.0.0.1", wantFile: true},
+		{name: "not allowed domain", serverName: "other.example.com", wantFile: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{ServerName: test.serverName})
+			if test.wantFile {
+				require.NoError(t, err)
+				assert.Same(t, fileCert, cert)
+				return
+			}
+			assert.Error(t, err, "the host policy rejects the domain before contacting the ACME server")
+		})
+	}
+
+	t.Run("HTTP-01 handler", func(t *testing.T) {
+		handler := certs.HTTPHandler()
+		require.NotNil(t, handler)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "http://short.example.com/EwH", nil))
+		assert.Equal(t, http.StatusFound, res.Code)
+		assert.Equal(t, "https://short.example.com/EwH", res.Header().Get("Location"), "other requests are redirected to HTTPS")
+	})
+
+	_, err = New(config.Flags{TLSCertFile: certFile, TLSKeyFile: keyFile, ACMEDomains: "short.example.com", ACMECAFile: filepath.Join(dir, "none.pem")},
+		zaptest.NewLogger(t).Sugar())
+	assert.Error(t, err)
+}
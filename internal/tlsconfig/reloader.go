@@ -0,0 +1,101 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// checkInterval - how often the certificate files are checked for changes.
+const checkInterval = 5 * time.Second
+
+// fileState - the modification time and size of a file, a change of them means the file is rewritten.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader serves the certificate loaded from files and reloads it when the files change,
+// so a renewed certificate is used without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	log      *zap.SugaredLogger
+	interval time.Duration
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certStat fileState
+	keyStat  fileState
+	checked  time.Time
+}
+
+// NewReloader loads the certificate and the private key from PEM files.
+func NewReloader(certFile, keyFile string, log *zap.SugaredLogger) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		log:      log,
+		interval: checkInterval,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	r.checked = time.Now()
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, it is used as tls.Config.GetCertificate.
+// The files are checked at most once per interval during handshakes. If the new files can't be loaded,
+// e.g. the key is not written yet, the previous certificate is served and the files are checked again later.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= r.interval {
+		r.checked = time.Now()
+		if err := r.reload(); err != nil {
+			r.log.Errorw("reload TLS certificate", "cert", r.certFile, "key", r.keyFile, "error", err)
+		}
+	}
+	return r.cert, nil
+}
+
+// reload loads the files if they changed since the last load. Must be called under lock.
+func (r *Reloader) reload() error {
+	certStat, err := stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyStat, err := stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil && certStat == r.certStat && keyStat == r.keyStat {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil {
+		r.log.Infow("reloaded TLS certificate", "cert", r.certFile)
+	}
+	r.cert = &cert
+	r.certStat = certStat
+	r.keyStat = keyStat
+	return nil
+}
+
+// stat returns the state of the file.
+func stat(name string) (fileState, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
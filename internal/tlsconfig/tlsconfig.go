@@ -0,0 +1,250 @@
+// Package tlsconfig creates the TLS settings of the servers and keeps their certificate up to date.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/Julia-ivv/shortener-url.git/cmd/certgenerator"
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+)
+
+// Files of the generated certificate.
+const (
+	// dirName - the subdirectory of the data directory with the generated certificate.
+	dirName = "tls"
+	// certName - the certificate file name.
+	certName = "cert.pem"
+	// keyName - the private key file name.
+	keyName = "key.pem"
+)
+
+// keyBits - the length of the generated private key.
+const keyBits = 4096
+
+// renewBefore - how long before the expiration the generated certificate is replaced.
+const renewBefore = 30 * 24 * time.Hour
+
+// Certificates provides the TLS settings of the servers.
+type Certificates struct {
+	minVersion uint16
+	ciphers    []uint16
+	// files serves the certificate from the files or the self-signed one.
+	files *Reloader
+	// acme obtains the certificates of the ACME domains, nil if ACME is disabled.
+	acme *autocert.Manager
+}
+
+// New creates the certificates from the flags.
+// The certificate is loaded from TLSCertFile and TLSKeyFile and reloaded when the files change.
+// If they are not set, a self-signed certificate is generated in DataDir on the first start and reused after restarts.
+// With ACMEDomains the certificates of the domains are obtained via ACME, the file or self-signed certificate
+// is served for the other names, e.g. localhost.
+func New(flags config.Flags, log *zap.SugaredLogger) (*Certificates, error) {
+	minVersion, err := ParseVersion(flags.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	ciphers, err := ParseCiphers(flags.TLSCiphers)
+	if err != nil {
+		return nil, err
+	}
+
+	certFile, keyFile := flags.TLSCertFile, flags.TLSKeyFile
+	switch {
+	case certFile == "" && keyFile == "":
+		certFile, keyFile, err = SelfSigned(filepath.Join(flags.DataDir, dirName), log)
+		if err != nil {
+			return nil, err
+		}
+	case certFile == "" || keyFile == "":
+		return nil, errors.New("both the TLS certificate and the key files must be set")
+	}
+	reloader, err := NewReloader(certFile, keyFile, log)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Certificates{
+		minVersion: minVersion,
+		ciphers:    ciphers,
+		files:      reloader,
+	}
+	if flags.ACMEDomains != "" {
+		c.acme, err = newACMEManager(flags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// ServerConfig returns the TLS settings of a server.
+func (c *Certificates) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     c.minVersion,
+		CipherSuites:   c.ciphers,
+		GetCertificate: c.files.GetCertificate,
+	}
+	if c.acme != nil {
+		cfg.GetCertificate = c.getCertificate
+		// The TLS-ALPN-01 challenges are answered in the handshakes of the server.
+		cfg.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+	}
+	return cfg
+}
+
+// GRPCConfig returns the TLS settings of the gRPC server. With GRPCClientCA the client certificates
+// are requested and verified with the CAs of the bundle, with GRPCClientCertRequired clients without one are rejected.
+func (c *Certificates) GRPCConfig(flags config.Flags) (*tls.Config, error) {
+	cfg := c.ServerConfig()
+	// The ACME challenges are answered by the HTTPS server, gRPC negotiates only h2.
+	cfg.NextProtos = nil
+	if flags.GRPCClientCA == "" {
+		if flags.GRPCClientCertRequired {
+			return nil, errors.New("client certificates are required without a client CA")
+		}
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(flags.GRPCClientCA)
+	if err != nil {
+		return nil, err
+	}
+	cas := x509.NewCertPool()
+	if !cas.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %s", flags.GRPCClientCA)
+	}
+	cfg.ClientCAs = cas
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	if flags.GRPCClientCertRequired {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// HTTPHandler returns the handler answering the ACME HTTP-01 challenges and redirecting
+// other requests to HTTPS, nil if ACME is disabled.
+func (c *Certificates) HTTPHandler() http.Handler {
+	if c.acme == nil {
+		return nil
+	}
+	return c.acme.HTTPHandler(nil)
+}
+
+// ParseVersion returns the TLS version by its number: 1.2 or 1.3, empty - 1.2.
+// Older versions are not supported.
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q, use 1.2 or 1.3", version)
+	}
+}
+
+// ParseCiphers returns the IDs of the comma-separated cipher suites, nil for the empty list.
+// Insecure and unknown suites are rejected.
+func ParseCiphers(names string) ([]uint16, error) {
+	if strings.TrimSpace(names) == "" {
+		return nil, nil
+	}
+	secure := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+	insecure := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+
+	var ids []uint16
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if id, ok := secure[name]; ok {
+			ids = append(ids, id)
+			continue
+		}
+		if insecure[name] {
+			return nil, fmt.Errorf("insecure TLS cipher suite %s", name)
+		}
+		return nil, fmt.Errorf("unknown TLS cipher suite %s", name)
+	}
+	return ids, nil
+}
+
+// SelfSigned returns the files of the self-signed certificate in dir.
+// The certificate is generated if there is none or it expires soon, otherwise it is reused,
+// so clients can pin it.
+func SelfSigned(dir string, log *zap.SugaredLogger) (certFile string, keyFile string, err error) {
+	certFile = filepath.Join(dir, certName)
+	keyFile = filepath.Join(dir, keyName)
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil {
+		var leaf *x509.Certificate
+		leaf, err = x509.ParseCertificate(pair.Certificate[0])
+		if err == nil && time.Now().Add(renewBefore).Before(leaf.NotAfter) {
+			return certFile, keyFile, nil
+		}
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Infow("generate a new TLS certificate", "dir", dir, "error", err)
+	}
+
+	certPEM, keyPEM, err := certgenerator.Generate(keyBits)
+	if err != nil {
+		return "", "", err
+	}
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+	// The key is written first, the certificate is the marker of a complete pair.
+	if err = writeFile(keyFile, keyPEM, 0600); err != nil {
+		return "", "", err
+	}
+	if err = writeFile(certFile, certPEM, 0644); err != nil {
+		return "", "", err
+	}
+	log.Infow("generated a self-signed TLS certificate", "file", certFile)
+	return certFile, keyFile, nil
+}
+
+// writeFile replaces the file with data atomically, a reader gets either the old or the new content.
+func writeFile(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
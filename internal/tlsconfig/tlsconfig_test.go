@@ -0,0 +1,210 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Julia-ivv/shortener-url.git/cmd/certgenerator"
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+)
+
+// writePair writes a new self-signed certificate and its key to the files.
+func writePair(t *testing.T, certFile, keyFile string) {
+	certPEM, keyPEM, err := certgenerator.Generate(1024)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0644))
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    uint16
+		wantErr bool
+	}{
+		{version: "", want: tls.VersionTLS12},
+		{version: "1.2", want: tls.VersionTLS12},
+		{version: "1.3", want: tls.VersionTLS13},
+		{version: "1.1", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			got, err := ParseVersion(test.version)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestParseCiphers(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   string
+		want    []uint16
+		wantErr bool
+	}{
+		{name: "defaults", names: "", want: nil},
+		{
+			name:  "secure suites",
+			names: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+			want:  []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256},
+		},
+		{name: "insecure suite", names: "TLS_RSA_WITH_RC4_128_SHA", wantErr: true},
+		{name: "unknown suite", names: "TLS_NULL", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseCiphers(test.names)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestSelfSigned(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tls")
+	log := zaptest.NewLogger(t).Sugar()
+
+	certFile, keyFile, err := SelfSigned(dir, log)
+	require.NoError(t, err)
+	first, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, _, err = SelfSigned(dir, log)
+	require.NoError(t, err)
+	second, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	assert.Equal(t, first, second, "the certificate is reused after a restart")
+
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0644))
+	_, _, err = SelfSigned(dir, log)
+	require.NoError(t, err)
+	_, err = tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err, "a broken certificate is replaced")
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePair(t, certFile, keyFile)
+
+	r, err := NewReloader(certFile, keyFile, zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	first, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+
+	t.Run("not checked before the interval", func(t *testing.T) {
+		writePair(t, certFile, keyFile)
+		got, err := r.GetCertificate(nil)
+		require.NoError(t, err)
+		assert.Same(t, first, got)
+	})
+
+	r.interval = 0
+	var second *tls.Certificate
+	t.Run("changed files are reloaded", func(t *testing.T) {
+		second, err = r.GetCertificate(nil)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Certificate, second.Certificate)
+	})
+
+	t.Run("broken files keep the previous certificate", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(keyFile, []byte("broken key"), 0600))
+		got, err := r.GetCertificate(nil)
+		require.NoError(t, err)
+		assert.Same(t, second, got)
+	})
+
+	_, err = NewReloader(filepath.Join(dir, "none.pem"), keyFile, zaptest.NewLogger(t).Sugar())
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePair(t, certFile, keyFile)
+
+	tests := []struct {
+		name    string
+		flags   config.Flags
+		wantErr bool
+	}{
+		{name: "certificate files", flags: config.Flags{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSMinVersion: "1.3"}},
+		{name: "key file is missing", flags: config.Flags{TLSCertFile: certFile}, wantErr: true},
+		{name: "bad version", flags: config.Flags{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSMinVersion: "1.0"}, wantErr: true},
+		{name: "bad cipher", flags: config.Flags{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSCiphers: "TLS_NULL"}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			certs, err := New(test.flags, zaptest.NewLogger(t).Sugar())
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Nil(t, certs.HTTPHandler(), "ACME is disabled")
+			cfg := certs.ServerConfig()
+			assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+			cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{ServerName: "localhost"})
+			require.NoError(t, err)
+			assert.NotNil(t, cert)
+		})
+	}
+}
+
+func TestGRPCConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePair(t, certFile, keyFile)
+	badCA := filepath.Join(dir, "bad.pem")
+	require.NoError(t, os.WriteFile(badCA, []byte("not a certificate"), 0644))
+	certs, err := New(config.Flags{TLSCertFile: certFile, TLSKeyFile: keyFile}, zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		flags      config.Flags
+		wantAuth   tls.ClientAuthType
+		wantClient bool
+		wantErr    bool
+	}{
+		{name: "without client CA", flags: config.Flags{}, wantAuth: tls.NoClientCert},
+		{name: "optional client certificates", flags: config.Flags{GRPCClientCA: certFile}, wantAuth: tls.VerifyClientCertIfGiven, wantClient: true},
+		{name: "required client certificates", flags: config.Flags{GRPCClientCA: certFile, GRPCClientCertRequired: true},
+			wantAuth: tls.RequireAndVerifyClientCert, wantClient: true},
+		{name: "required without client CA", flags: config.Flags{GRPCClientCertRequired: true}, wantErr: true},
+		{name: "client CA is missing", flags: config.Flags{GRPCClientCA: filepath.Join(dir, "none.pem")}, wantErr: true},
+		{name: "client CA without certificates", flags: config.Flags{GRPCClientCA: badCA}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := certs.GRPCConfig(test.flags)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.wantAuth, cfg.ClientAuth)
+			assert.Equal(t, test.wantClient, cfg.ClientCAs != nil)
+			assert.Empty(t, cfg.NextProtos)
+			assert.NotNil(t, cfg.GetCertificate)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// Span attributes of storage operations.
+const (
+	attrBackend  = attribute.Key("storage.backend")
+	attrShortURL = attribute.Key("storage.short_url")
+	attrUserID   = attribute.Key("storage.user_id")
+	attrCount    = attribute.Key("storage.count")
+	attrFound    = attribute.Key("storage.found")
+	attrDeleted  = attribute.Key("storage.deleted")
+	attrConflict = attribute.Key("storage.conflict")
+)
+
+// TracedURLs creates a span for each storage operation.
+type TracedURLs struct {
+	storage.Repositories
+	backend string
+	tracer  trace.Tracer
+}
+
+// NewTracedURLs wraps the storage, backend is the storage name in the span attributes.
+func NewTracedURLs(repo storage.Repositories, backend string) *TracedURLs {
+	return &TracedURLs{Repositories: repo, backend: backend, tracer: Tracer()}
+}
+
+// start starts the span of the operation as a child of the span in ctx.
+func (s *TracedURLs) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attrBackend.String(s.backend))
+	return s.tracer.Start(ctx, "storage."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// end records the error of the operation and ends the span.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// GetURL gets the original URL matching the short URL.
+func (s *TracedURLs) GetURL(ctx context.Context, shortURL string) (originURL string, isDel bool, ok bool) {
+	ctx, span := s.start(ctx, "GetURL", attrShortURL.String(shortURL))
+	defer span.End()
+	originURL, isDel, ok = s.Repositories.GetURL(ctx, shortURL)
+	span.SetAttributes(attrFound.Bool(ok), attrDeleted.Bool(isDel))
+	return originURL, isDel, ok
+}
+
+// AddURL adds a new short url.
+func (s *TracedURLs) AddURL(ctx context.Context, shortURL string, originURL string, userID int) (findURL string, err error) {
+	ctx, span := s.start(ctx, "AddURL", attrShortURL.String(shortURL), attrUserID.Int(userID))
+	defer func() {
+		// A conflict is a normal answer of the storage.
+		if errors.Is(err, storage.ErrConflict) {
+			span.SetAttributes(attrConflict.Bool(true))
+			end(span, nil)
+			return
+		}
+		end(span, err)
+	}()
+	return s.Repositories.AddURL(ctx, shortURL, originURL, userID)
+}
+
+// AddBatch adds a batch of new short URLs.
+func (s *TracedURLs) AddBatch(ctx context.Context, shortURLBatch []storage.ResponseBatch, originURLBatch []storage.RequestBatch, userID int) (results []storage.BatchResult, err error) {
+	ctx, span := s.start(ctx, "AddBatch", attrUserID.Int(userID), attrCount.Int(len(shortURLBatch)))
+	defer func() { end(span, err) }()
+	return s.Repositories.AddBatch(ctx, shortURLBatch, originURLBatch, userID)
+}
+
+// GetAllUserURLs gets all user's short url.
+func (s *TracedURLs) GetAllUserURLs(ctx context.Context, baseURL string, userID int) (userURLs []storage.UserURL, err error) {
+	ctx, span := s.start(ctx, "GetAllUserURLs", attrUserID.Int(userID))
+	defer func() { end(span, err) }()
+	return s.Repositories.GetAllUserURLs(ctx, baseURL, userID)
+}
+
+// IterateUserURLs calls fn for each user's URL.
+func (s *TracedURLs) IterateUserURLs(ctx context.Context, userID int, fn func(url storage.URLInfo) error) (err error) {
+	ctx, span := s.start(ctx, "IterateUserURLs", attrUserID.Int(userID))
+	defer func() { end(span, err) }()
+	return s.Repositories.IterateUserURLs(ctx, userID, fn)
+}
+
+// AddClick increases the number of redirects by the short URL.
+func (s *TracedURLs) AddClick(ctx context.Context, shortURL string) (err error) {
+	ctx, span := s.start(ctx, "AddClick", attrShortURL.String(shortURL))
+	defer func() { end(span, err) }()
+	return s.Repositories.AddClick(ctx, shortURL)
+}
+
+// DeleteUserURLs sets the deletion flag to the user URLs.
+func (s *TracedURLs) DeleteUserURLs(ctx context.Context, delURLs []string, userID int) (deleted []string, err error) {
+	ctx, span := s.start(ctx, "DeleteUserURLs", attrUserID.Int(userID), attrCount.Int(len(delURLs)))
+	defer func() { end(span, err) }()
+	return s.Repositories.DeleteUserURLs(ctx, delURLs, userID)
+}
+
+// RestoreUserURLs clears the deletion flag of the user URLs.
+func (s *TracedURLs) RestoreUserURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (restored []string, err error) {
+	ctx, span := s.start(ctx, "RestoreUserURLs", attrUserID.Int(userID), attrCount.Int(len(shortURLs)))
+	defer func() { end(span, err) }()
+	return s.Repositories.RestoreUserURLs(ctx, shortURLs, userID, deletedAfter)
+}
+
+// PurgeDeletedURLs permanently removes URLs deleted before deletedBefore.
+func (s *TracedURLs) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	ctx, span := s.start(ctx, "PurgeDeletedURLs")
+	defer func() {
+		span.SetAttributes(attrCount.Int64(purged))
+		end(span, err)
+	}()
+	return s.Repositories.PurgeDeletedURLs(ctx, deletedBefore)
+}
+
+// GetStats gets the amount of all users and URLs in the service.
+func (s *TracedURLs) GetStats(ctx context.Context) (stats storage.ServiceStats, err error) {
+	ctx, span := s.start(ctx, "GetStats")
+	defer func() { end(span, err) }()
+	return s.Repositories.GetStats(ctx)
+}
+
+// PingStor checking access to storage.
+func (s *TracedURLs) PingStor(ctx context.Context) (err error) {
+	ctx, span := s.start(ctx, "PingStor")
+	defer func() { end(span, err) }()
+	return s.Repositories.PingStor(ctx)
+}
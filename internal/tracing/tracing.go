@@ -0,0 +1,143 @@
+// Package tracing sets up OpenTelemetry tracing of the service
+// and passes the trace context to logs and deletion tasks.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+)
+
+// Span exporters.
+const (
+	// ExporterOTLP - spans are sent to an OpenTelemetry collector over gRPC.
+	ExporterOTLP = "otlp"
+	// ExporterStdout - spans are printed to stdout as JSON.
+	ExporterStdout = "stdout"
+	// ExporterFile - spans are appended to a file as JSON.
+	ExporterFile = "file"
+)
+
+// serviceName - the service name in the spans.
+const serviceName = "shortener"
+
+// instrumentation - the name of the tracer of the service.
+const instrumentation = "github.com/Julia-ivv/shortener-url.git"
+
+// Tracer returns the tracer of the service.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// NewProvider creates the tracer provider with the exporter from the flags and sets it as global.
+// The W3C trace context propagator is set even if tracing is disabled,
+// so the trace of the caller is passed on.
+// Returns a function that sends the remaining spans and stops the exporter.
+func NewProvider(ctx context.Context, flags config.Flags, version string) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if flags.TraceExporter == "" {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, flags)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName), semconv.ServiceVersion(version)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, errors.Join(err, exporter.Shutdown(ctx))
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(flags.TraceSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the span exporter selected by the flags.
+func newExporter(ctx context.Context, flags config.Flags) (sdktrace.SpanExporter, error) {
+	switch flags.TraceExporter {
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(flags.TraceEndpoint)}
+		if flags.TraceInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		return stdouttrace.New()
+	case ExporterFile:
+		file, err := os.OpenFile(flags.TraceFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &fileExporter{SpanExporter: exporter, file: file}, nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", flags.TraceExporter)
+}
+
+// fileExporter closes the file of the stdout exporter on shutdown.
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+// Shutdown stops the exporter and closes the file.
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.SpanExporter.Shutdown(ctx), e.file.Close())
+}
+
+// WithLogger returns a copy of ctx with the logger that adds
+// the trace and span IDs of the current span to the records.
+// ctx is not changed if it has no span.
+func WithLogger(ctx context.Context) context.Context {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ctx
+	}
+	return logger.With(ctx, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+}
+
+// Inject returns the trace context of ctx as a map,
+// so it can be saved with a task that is processed later, nil if ctx has no span.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns a copy of ctx with the trace context saved by Inject.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/Julia-ivv/shortener-url/pkg/logger"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/config"
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// newRecorder sets the global tracer provider that saves the ended spans.
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return sr
+}
+
+func TestTracedURLs(t *testing.T) {
+	sr := newRecorder(t)
+	kv, err := storage.NewKVURLs(t.TempDir() + "/urls.db")
+	require.NoError(t, err)
+	repo := NewTracedURLs(kv, storage.BackendKV)
+	defer repo.Close()
+
+	ctx, parent := Tracer().Start(context.Background(), "request")
+	_, err = repo.AddURL(ctx, "EwH", "https://practicum.yandex.ru/", 1)
+	require.NoError(t, err)
+	_, err = repo.AddURL(ctx, "EwH2", "https://practicum.yandex.ru/", 1)
+	require.ErrorIs(t, err, storage.ErrConflict)
+	_, _, ok := repo.GetURL(ctx, "EwH")
+	require.True(t, ok)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = repo.GetStats(cancelled)
+	require.Error(t, err)
+	parent.End()
+
+	spans := sr.Ended()
+	require.Len(t, spans, 5)
+	tests := []struct {
+		name       string
+		span       sdktrace.ReadOnlySpan
+		wantName   string
+		wantStatus codes.Code
+	}{
+		{name: "add", span: spans[0], wantName: "storage.AddURL", wantStatus: codes.Unset},
+		{name: "conflict is not an error", span: spans[1], wantName: "storage.AddURL", wantStatus: codes.Unset},
+		{name: "get", span: spans[2], wantName: "storage.GetURL", wantStatus: codes.Unset},
+		{name: "error", span: spans[3], wantName: "storage.GetStats", wantStatus: codes.Error},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.wantName, test.span.Name())
+			assert.Equal(t, test.wantStatus, test.span.Status().Code)
+			assert.Equal(t, parent.SpanContext().SpanID(), test.span.Parent().SpanID())
+			assert.Contains(t, test.span.Attributes(), attrBackend.String(storage.BackendKV))
+		})
+	}
+	assert.Contains(t, spans[1].Attributes(), attrConflict.Bool(true))
+	assert.Contains(t, spans[2].Attributes(), attrFound.Bool(true))
+}
+
+func TestInjectExtract(t *testing.T) {
+	newRecorder(t)
+	_, err := NewProvider(context.Background(), config.Flags{}, "test")
+	require.NoError(t, err)
+
+	t.Run("no span", func(t *testing.T) {
+		assert.Nil(t, Inject(context.Background()))
+		assert.Equal(t, context.Background(), Extract(context.Background(), nil))
+	})
+	t.Run("span", func(t *testing.T) {
+		ctx, span := Tracer().Start(context.Background(), "request")
+		defer span.End()
+
+		carrier := Inject(ctx)
+		assert.Contains(t, carrier, "traceparent")
+		got := Extract(context.Background(), carrier)
+		_, child := Tracer().Start(got, "task")
+		defer child.End()
+		assert.Equal(t, span.SpanContext().TraceID(), child.SpanContext().TraceID())
+	})
+}
+
+func TestWithLogger(t *testing.T) {
+	newRecorder(t)
+	log := logger.NewLogger()
+
+	ctx := logger.NewContext(context.Background(), log)
+	assert.Same(t, log, logger.FromContext(WithLogger(ctx)))
+
+	ctx, span := Tracer().Start(ctx, "request")
+	defer span.End()
+	assert.NotSame(t, log, logger.FromContext(WithLogger(ctx)))
+}
+
+func TestNewProvider(t *testing.T) {
+	fileName := t.TempDir() + "/traces.json"
+	tests := []struct {
+		name    string
+		flags   config.Flags
+		wantErr bool
+	}{
+		{name: "disabled", flags: config.Flags{}, wantErr: false},
+		{name: "file", flags: config.Flags{TraceExporter: ExporterFile, TraceFile: fileName, TraceSampleRatio: 1}, wantErr: false},
+		{name: "unknown exporter", flags: config.Flags{TraceExporter: "zipkin"}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shutdown, err := NewProvider(context.Background(), test.flags, "test")
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			_, span := Tracer().Start(context.Background(), "test span")
+			span.End()
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+
+	data, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"test span"`)
+}
@@ -0,0 +1,165 @@
+// Package transfer copies all URLs with their users, deletion flags and counters between storages.
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// defBatchSize - the number of URLs loaded into the target at once by default.
+const defBatchSize = 500
+
+// Source reads all URLs of the storage.
+type Source interface {
+	// IterateURLs calls fn for each URL ordered by the short URL, starting after the short URL after.
+	IterateURLs(ctx context.Context, after string, fn func(url storage.URLRecord) error) (err error)
+}
+
+// Target adds URLs with all their data.
+type Target interface {
+	// LoadURLs adds the URLs skipping existing ones and returns the number of added URLs.
+	LoadURLs(ctx context.Context, urls []storage.URLRecord) (loaded int, err error)
+}
+
+// Progress stores the state of the transfer, it is saved as the checkpoint.
+type Progress struct {
+	// ID - the description of the source and the target, a checkpoint is resumed only with the same ID.
+	ID string `json:"id"`
+	// Read - the number of URLs read from the source.
+	Read int `json:"read"`
+	// Loaded - the number of URLs added to the target, the rest already existed there.
+	Loaded int `json:"loaded"`
+	// Last - the short URL of the last copied URL, the transfer is resumed after it.
+	Last string `json:"last_short_url"`
+}
+
+// Options stores the transfer settings.
+type Options struct {
+	// ID - the description of the source and the target, e.g. "file:/tmp/urls.json -> sqlite://urls.db".
+	ID string
+	// BatchSize - the number of URLs loaded at once, 0 - the default.
+	BatchSize int
+	// Checkpoint - the file where the progress is saved after each batch, empty - no checkpoints.
+	// An existing checkpoint is resumed, it is removed when the transfer is completed.
+	Checkpoint string
+	// DryRun - only read the source, nothing is loaded and no checkpoint is saved.
+	DryRun bool
+	// Report is called with the progress after each batch, may be nil.
+	Report func(p Progress)
+}
+
+// Run copies the URLs from src to dst, dst is not used in the dry run.
+// Returns the progress at the moment of completion or failure.
+func Run(ctx context.Context, src Source, dst Target, opts Options) (Progress, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defBatchSize
+	}
+
+	p := Progress{ID: opts.ID}
+	if opts.Checkpoint != "" && !opts.DryRun {
+		var err error
+		if p, err = loadCheckpoint(opts.Checkpoint, opts.ID); err != nil {
+			return p, err
+		}
+	}
+
+	batch := make([]storage.URLRecord, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if !opts.DryRun {
+			loaded, err := dst.LoadURLs(ctx, batch)
+			if err != nil {
+				return err
+			}
+			p.Loaded += loaded
+		}
+		p.Read += len(batch)
+		p.Last = batch[len(batch)-1].ShortURL
+		batch = batch[:0]
+
+		if opts.Checkpoint != "" && !opts.DryRun {
+			if err := saveCheckpoint(opts.Checkpoint, p); err != nil {
+				return err
+			}
+		}
+		if opts.Report != nil {
+			opts.Report(p)
+		}
+		return nil
+	}
+
+	err := src.IterateURLs(ctx, p.Last, func(url storage.URLRecord) error {
+		batch = append(batch, url)
+		if len(batch) < batchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return p, err
+	}
+
+	if opts.Checkpoint != "" && !opts.DryRun {
+		if err = os.Remove(opts.Checkpoint); err != nil && !os.IsNotExist(err) {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// loadCheckpoint reads the saved progress, returns the initial progress if there is no checkpoint.
+func loadCheckpoint(fileName string, id string) (Progress, error) {
+	p := Progress{ID: id}
+	data, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err = json.Unmarshal(data, &p); err != nil {
+		return Progress{ID: id}, fmt.Errorf("checkpoint %s: %w", fileName, err)
+	}
+	if p.ID != id {
+		return Progress{ID: id}, fmt.Errorf("checkpoint %s belongs to another transfer: %s", fileName, p.ID)
+	}
+	return p, nil
+}
+
+// saveCheckpoint replaces the checkpoint with the progress,
+// a crash leaves either the old or the new checkpoint.
+func saveCheckpoint(fileName string, p Progress) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
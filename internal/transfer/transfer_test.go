@@ -0,0 +1,125 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Julia-ivv/shortener-url.git/internal/storage"
+)
+
+// failingTarget fails after loading failAfter URLs.
+type failingTarget struct {
+	*storage.MemURLs
+	failAfter int
+	loads     int
+}
+
+func (t *failingTarget) LoadURLs(ctx context.Context, urls []storage.URLRecord) (loaded int, err error) {
+	if t.loads+len(urls) > t.failAfter {
+		return 0, errors.New("target is not available")
+	}
+	t.loads += len(urls)
+	return t.MemURLs.LoadURLs(ctx, urls)
+}
+
+func newTestSource(t *testing.T, count int) *storage.MemURLs {
+	src := storage.NewMapURLs()
+	for i := 0; i < count; i++ {
+		_, err := src.AddURL(context.Background(), fmt.Sprintf("u%03d", i), fmt.Sprintf("https://pract.ru/%d", i), i%3)
+		require.NoError(t, err)
+	}
+	_, err := src.DeleteUserURLs(context.Background(), []string{"u001"}, 1)
+	require.NoError(t, err)
+	return src
+}
+
+func countURLs(t *testing.T, stor Source) (count int, deleted int) {
+	err := stor.IterateURLs(context.Background(), "", func(url storage.URLRecord) error {
+		count++
+		if url.DeletedFlag {
+			deleted++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return count, deleted
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		dryRun     bool
+		wantLoaded int
+		wantCopied int
+	}{
+		{
+			name:       "copy",
+			wantLoaded: 25,
+			wantCopied: 25,
+		},
+		{
+			name:       "dry run",
+			dryRun:     true,
+			wantLoaded: 0,
+			wantCopied: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := newTestSource(t, 25)
+			dst := storage.NewMapURLs()
+			var reports int
+
+			p, err := Run(context.Background(), src, dst, Options{
+				ID:        "mem -> mem",
+				BatchSize: 10,
+				DryRun:    test.dryRun,
+				Report:    func(p Progress) { reports++ },
+			})
+			require.NoError(t, err)
+			assert.Equal(t, Progress{ID: "mem -> mem", Read: 25, Loaded: test.wantLoaded, Last: "u024"}, p)
+			assert.Equal(t, 3, reports)
+
+			copied, deleted := countURLs(t, dst)
+			assert.Equal(t, test.wantCopied, copied)
+			if !test.dryRun {
+				assert.Equal(t, 1, deleted)
+			}
+		})
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	src := newTestSource(t, 25)
+	dst := &failingTarget{MemURLs: storage.NewMapURLs(), failAfter: 20}
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	opts := Options{ID: "mem -> mem", BatchSize: 10, Checkpoint: checkpoint}
+
+	p, err := Run(context.Background(), src, dst, opts)
+	assert.Error(t, err)
+	assert.Equal(t, Progress{ID: "mem -> mem", Read: 20, Loaded: 20, Last: "u019"}, p)
+	saved, err := loadCheckpoint(checkpoint, opts.ID)
+	require.NoError(t, err)
+	assert.Equal(t, p, saved)
+
+	_, err = Run(context.Background(), src, dst, Options{ID: "file -> mem", Checkpoint: checkpoint})
+	assert.Error(t, err, "the checkpoint of another transfer is not resumed")
+
+	dst.failAfter = 100
+	p, err = Run(context.Background(), src, dst, opts)
+	require.NoError(t, err)
+	assert.Equal(t, Progress{ID: "mem -> mem", Read: 25, Loaded: 25, Last: "u024"}, p)
+	assert.Equal(t, 25, dst.loads, "copied URLs are not read again")
+
+	_, err = os.Stat(checkpoint)
+	assert.True(t, os.IsNotExist(err), "the checkpoint is removed after the transfer")
+	copied, _ := countURLs(t, dst)
+	assert.Equal(t, 25, copied)
+}
@@ -17,6 +17,15 @@ func (w *gzipWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// FlushError sends the compressed data written so far to the client.
+// It is used by http.ResponseController to flush streaming responses.
+func (w *gzipWriter) FlushError() error {
+	if err := w.Writer.Flush(); err != nil {
+		return err
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
 // NewGzipWriter creates a new instance for the gzip packager.
 func NewGzipWriter(w http.ResponseWriter) *gzipWriter {
 	return &gzipWriter{
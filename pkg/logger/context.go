@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// ctxKey - the context key of the request logger.
+type ctxKey struct{}
+
+// nop discards the records of the contexts without a logger.
+var nop = zap.NewNop().Sugar()
+
+// entry - the logger of the context and the fields of the request it belongs to.
+type entry struct {
+	log    *zap.SugaredLogger
+	fields *fields
+}
+
+// fields - the key-value pairs added to every record of a request.
+// The set is shared by all contexts derived from the request context,
+// so fields added by inner handlers, e.g. the user ID, reach the access log of the outer ones.
+type fields struct {
+	mu   sync.Mutex
+	args []interface{}
+}
+
+// NewContext returns a copy of ctx with the logger.
+// The fields of the request, if any, are kept.
+func NewContext(ctx context.Context, log *zap.SugaredLogger) context.Context {
+	e := &entry{log: log}
+	if parent, ok := ctx.Value(ctxKey{}).(*entry); ok {
+		e.fields = parent.fields
+	}
+	return context.WithValue(ctx, ctxKey{}, e)
+}
+
+// With returns a copy of ctx whose logger adds args to the records,
+// e.g. the trace ID of the request.
+func With(ctx context.Context, args ...interface{}) context.Context {
+	return NewContext(ctx, base(ctx).With(args...))
+}
+
+// NewFields returns a copy of ctx that starts a new set of request fields with args.
+// Loggers taken from the context and from the contexts derived from it add the fields to the records.
+func NewFields(ctx context.Context, args ...interface{}) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &entry{
+		log:    base(ctx),
+		fields: &fields{args: append([]interface{}(nil), args...)},
+	})
+}
+
+// AddFields adds args to the fields of the request of ctx.
+// Loggers taken from the context after the call add them to the records.
+// Does nothing if ctx has no request fields.
+func AddFields(ctx context.Context, args ...interface{}) {
+	e, ok := ctx.Value(ctxKey{}).(*entry)
+	if !ok || e.fields == nil {
+		return
+	}
+	e.fields.mu.Lock()
+	e.fields.args = append(e.fields.args, args...)
+	e.fields.mu.Unlock()
+}
+
+// FromContext returns the logger of the context with the request fields.
+// The records are discarded if the context has no logger, there is no global one to fall back to:
+// the application passes its logger to NewContext.
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	return FromContextOr(ctx, nop)
+}
+
+// FromContextOr returns the logger of the context with the request fields
+// or log if the context has none, e.g. the logger a server is created with.
+func FromContextOr(ctx context.Context, log *zap.SugaredLogger) *zap.SugaredLogger {
+	e, ok := ctx.Value(ctxKey{}).(*entry)
+	if !ok {
+		return log
+	}
+	if e.log != nil {
+		log = e.log
+	}
+	if e.fields == nil {
+		return log
+	}
+	e.fields.mu.Lock()
+	defer e.fields.mu.Unlock()
+	if len(e.fields.args) == 0 {
+		return log
+	}
+	return log.With(e.fields.args...)
+}
+
+// base returns the logger of the context without the request fields.
+func base(ctx context.Context) *zap.SugaredLogger {
+	if e, ok := ctx.Value(ctxKey{}).(*entry); ok && e.log != nil {
+		return e.log
+	}
+	return nop
+}
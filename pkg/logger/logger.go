@@ -2,11 +2,34 @@
 package logger
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Encodings of the log records.
+const (
+	// FormatJSON - one JSON object per record.
+	FormatJSON = "json"
+	// FormatConsole - human-readable records separated by tabs.
+	FormatConsole = "console"
 )
 
-// ZapSugar provides access to the logger.
-var ZapSugar *zap.SugaredLogger
+// Options stores the logger settings.
+type Options struct {
+	// Level - the minimum level of written records: debug, info, warn, error, empty - info.
+	Level string
+	// Format - FormatJSON or FormatConsole, empty - FormatConsole.
+	Format string
+	// Sampling - the number of records with the same level and message written per second,
+	// after that only every Sampling-th of them is written, 0 - no sampling.
+	Sampling int
+	// File - the file the records are appended to, empty - stderr.
+	File string
+}
 
 // NewLogger creates a logger instance.
 func NewLogger() *zap.SugaredLogger {
@@ -20,3 +43,41 @@ func NewLogger() *zap.SugaredLogger {
 
 	return zapSugar
 }
+
+// New creates a logger with the options.
+// The returned level changes the minimum level of the logger at runtime,
+// it is also an HTTP handler: GET returns the level, PUT {"level":"debug"} sets it.
+func New(opts Options) (*zap.SugaredLogger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(opts.Level)
+	if err != nil {
+		return nil, level, err
+	}
+
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	var enc zapcore.Encoder
+	switch opts.Format {
+	case FormatJSON:
+		enc = zapcore.NewJSONEncoder(encCfg)
+	case "", FormatConsole:
+		encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		enc = zapcore.NewConsoleEncoder(encCfg)
+	default:
+		return nil, level, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+
+	out := zapcore.Lock(os.Stderr)
+	if opts.File != "" {
+		out, _, err = zap.Open(opts.File)
+		if err != nil {
+			return nil, level, err
+		}
+	}
+
+	core := zapcore.NewCore(enc, out, level)
+	if opts.Sampling > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, opts.Sampling, opts.Sampling)
+	}
+	log := zap.New(core, zap.AddCaller(), zap.ErrorOutput(zapcore.Lock(os.Stderr)))
+	return log.Sugar(), level, nil
+}
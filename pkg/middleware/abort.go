@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// abortKey is the context key of the abort state of the request.
+type abortKey struct{}
+
+// abortState stores the error the response was aborted with.
+type abortState struct {
+	err error
+}
+
+// HandlerWithAbort lets the handlers abort a started response with Abort.
+// It must be the outermost middleware: the inner ones log and count the aborted request
+// before the connection is closed with http.ErrAbortHandler.
+func HandlerWithAbort(h http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(res http.ResponseWriter, req *http.Request) {
+			state := &abortState{}
+			h.ServeHTTP(res, req.WithContext(context.WithValue(req.Context(), abortKey{}, state)))
+			if state.err != nil {
+				panic(http.ErrAbortHandler)
+			}
+		})
+}
+
+// Abort marks the started response as failed with err, the handler returns after it.
+// The connection is closed once the response passes HandlerWithAbort,
+// so the client gets an incomplete response instead of a truncated one.
+// Without HandlerWithAbort the connection is closed at once.
+func Abort(req *http.Request, err error) {
+	state, ok := req.Context().Value(abortKey{}).(*abortState)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	state.err = err
+}
+
+// AbortError returns the error the response was aborted with, nil if it is not aborted.
+func AbortError(ctx context.Context) error {
+	if state, ok := ctx.Value(abortKey{}).(*abortState); ok {
+		return state.err
+	}
+	return nil
+}
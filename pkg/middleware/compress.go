@@ -8,8 +8,10 @@ import (
 )
 
 // HandlerWithGzipCompression adds the use of the gzip compression to the handler.
+// The response is compressed if the client accepts gzip and the Content-Type
+// or the Accept header of the request contains a compressible type.
 func HandlerWithGzipCompression(h http.Handler) http.Handler {
-	contentTypeForCompression := [2]string{"application/json", "text/html"}
+	contentTypeForCompression := [4]string{"application/json", "text/html", "text/csv", "application/x-ndjson"}
 	return http.HandlerFunc(
 		func(res http.ResponseWriter, req *http.Request) {
 			newRes := res
@@ -21,9 +23,10 @@ func HandlerWithGzipCompression(h http.Handler) http.Handler {
 				}
 			}
 			contentType := req.Header.Get("Content-Type")
+			accept := req.Header.Get("Accept")
 			needsCompressing := false
 			for _, v := range contentTypeForCompression {
-				if strings.Contains(contentType, v) {
+				if strings.Contains(contentType, v) || strings.Contains(accept, v) {
 					needsCompressing = true
 					break
 				}
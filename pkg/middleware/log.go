@@ -32,6 +32,11 @@ func (res *logResponseWriter) WriteHeader(statusCode int) {
 	res.responseInfo.status = statusCode
 }
 
+// Unwrap returns the original ResponseWriter, so http.ResponseController can reach it.
+func (res *logResponseWriter) Unwrap() http.ResponseWriter {
+	return res.ResponseWriter
+}
+
 // HandlerWithLogging adds logging to the handler.
 func HandlerWithLogging(h http.Handler) http.Handler {
 	return http.HandlerFunc(
@@ -51,12 +56,16 @@ func HandlerWithLogging(h http.Handler) http.Handler {
 			h.ServeHTTP(&logRespWriter, req)
 			duration := time.Since(start)
 
-			logger.ZapSugar.Infoln(
+			fields := []interface{}{
 				"uri", uri,
 				"method", method,
 				"status", responseInfo.status,
 				"size", responseInfo.size,
 				"duration", duration,
-			)
+			}
+			if err := AbortError(req.Context()); err != nil {
+				fields = append(fields, "aborted", err)
+			}
+			logger.FromContext(req.Context()).Infoln(fields...)
 		})
 }